@@ -6,7 +6,11 @@ import (
 	"github.com/jslay88/zladxhd-installer/internal/cli"
 )
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
+	cli.Version = version
 	if err := cli.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,62 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CloneFile copies src to dst, preserving mode and modification time.
+// A copy-on-write reflink is used where the filesystem supports it, so
+// large files are cloned instantly without using extra space; otherwise
+// the contents are copied. Returns true if a reflink was used.
+//
+// Hardlinks are deliberately not used: a program that rewrites a file in
+// place would change both copies.
+func CloneFile(src, dst string) (bool, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat source file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return false, fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	tmpPath := dst + ".tmp"
+	out, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return false, fmt.Errorf("failed to create destination file: %w", err)
+	}
+
+	cloned := reflink(out, in) == nil
+	if !cloned {
+		if _, err := io.Copy(out, in); err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmpPath)
+			return false, fmt.Errorf("failed to copy file: %w", err)
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return false, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	_ = os.Chmod(tmpPath, info.Mode().Perm())
+	_ = os.Chtimes(tmpPath, info.ModTime(), info.ModTime())
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		_ = os.Remove(tmpPath)
+		return false, fmt.Errorf("failed to finalize file: %w", err)
+	}
+
+	return cloned, nil
+}
@@ -0,0 +1,13 @@
+package archive
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflink clones src into dst using the FICLONE ioctl.
+// Only filesystems with copy-on-write support (btrfs, XFS, bcachefs) accept it.
+func reflink(dst, src *os.File) error {
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+}
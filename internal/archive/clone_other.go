@@ -0,0 +1,13 @@
+//go:build !linux
+
+package archive
+
+import (
+	"errors"
+	"os"
+)
+
+// reflink is not supported on this platform.
+func reflink(dst, src *os.File) error {
+	return errors.New("reflinks not supported")
+}
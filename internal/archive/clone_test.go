@@ -0,0 +1,65 @@
+package archive_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+)
+
+var _ = Describe("CloneFile", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "archive-clone-test-*")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("should copy contents, mode and modification time", func() {
+		src := filepath.Join(tmpDir, "src.bin")
+		dst := filepath.Join(tmpDir, "nested", "dst.bin")
+		Expect(os.WriteFile(src, []byte("content"), 0755)).To(Succeed())
+		mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		Expect(os.Chtimes(src, mtime, mtime)).To(Succeed())
+
+		_, err := archive.CloneFile(src, dst)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := os.ReadFile(dst)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("content"))
+
+		info, err := os.Stat(dst)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+		Expect(info.ModTime().Equal(mtime)).To(BeTrue())
+	})
+
+	It("should replace an existing destination", func() {
+		src := filepath.Join(tmpDir, "src.bin")
+		dst := filepath.Join(tmpDir, "dst.bin")
+		Expect(os.WriteFile(src, []byte("new"), 0644)).To(Succeed())
+		Expect(os.WriteFile(dst, []byte("old content"), 0644)).To(Succeed())
+
+		_, err := archive.CloneFile(src, dst)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := os.ReadFile(dst)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("new"))
+	})
+
+	It("should return error for missing source", func() {
+		_, err := archive.CloneFile(filepath.Join(tmpDir, "missing"), filepath.Join(tmpDir, "dst"))
+		Expect(err).To(HaveOccurred())
+	})
+})
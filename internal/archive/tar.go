@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractTarGz extracts a .tar.gz archive into destDir. Entries may not
+// escape destDir, either by path, by writing through a symlink, or as a hard
+// link to a file outside it. Symlinks are created as-is, since they are
+// never followed during extraction. Device files and FIFOs are skipped.
+func ExtractTarGz(path, destDir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	gzReader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer func() { _ = gzReader.Close() }()
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		target, err := SafeJoin(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(header.Mode).Perm()|0700); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return fmt.Errorf("failed to create symlink: %w", err)
+			}
+		case tar.TypeLink:
+			source, err := SafeJoin(destDir, header.Linkname)
+			if err != nil {
+				return err
+			}
+			if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+				return fmt.Errorf("invalid hard link in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			if err := os.Link(source, target); err != nil {
+				return fmt.Errorf("failed to create hard link: %w", err)
+			}
+		case tar.TypeReg:
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return fmt.Errorf("failed to create file: %w", err)
+			}
+			if _, err := io.Copy(out, tarReader); err != nil {
+				_ = out.Close()
+				return fmt.Errorf("failed to extract file: %w", err)
+			}
+			if err := out.Close(); err != nil {
+				return fmt.Errorf("failed to extract file: %w", err)
+			}
+			_ = os.Chtimes(target, header.ModTime, header.ModTime)
+		}
+	}
+}
+
+// CleanPath cleans a slash-separated path from an archive, rejecting
+// absolute paths and paths that leave the directory they are extracted to.
+func CleanPath(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+	return clean, nil
+}
+
+// SafeJoin joins name to root, rejecting names that leave root or that
+// pass through a symlink. Missing parent directories are created.
+func SafeJoin(root, name string) (string, error) {
+	clean, err := CleanPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	dir := root
+	parts := strings.Split(filepath.Dir(clean), string(filepath.Separator))
+	for _, part := range parts {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return "", fmt.Errorf("failed to create directory: %w", err)
+				}
+				continue
+			}
+			return "", err
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("invalid path in archive: %s passes through %s", name, part)
+		}
+	}
+
+	return filepath.Join(root, clean), nil
+}
@@ -0,0 +1,111 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+)
+
+var _ = Describe("ExtractTarGz", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "tar-test-*")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	// createTarGz writes a .tar.gz with the given headers; regular files get
+	// their name as content.
+	createTarGz := func(headers ...*tar.Header) string {
+		path := filepath.Join(tmpDir, "test.tar.gz")
+		f, err := os.Create(path)
+		Expect(err).NotTo(HaveOccurred())
+		gz := gzip.NewWriter(f)
+		tw := tar.NewWriter(gz)
+		for _, h := range headers {
+			if h.Typeflag == tar.TypeReg {
+				h.Size = int64(len(h.Name))
+			}
+			Expect(tw.WriteHeader(h)).To(Succeed())
+			if h.Typeflag == tar.TypeReg {
+				_, err := tw.Write([]byte(h.Name))
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}
+		Expect(tw.Close()).To(Succeed())
+		Expect(gz.Close()).To(Succeed())
+		Expect(f.Close()).To(Succeed())
+		return path
+	}
+
+	It("should extract directories, files, symlinks and hard links", func() {
+		path := createTarGz(
+			&tar.Header{Name: "tool/", Typeflag: tar.TypeDir, Mode: 0755},
+			&tar.Header{Name: "tool/proton", Typeflag: tar.TypeReg, Mode: 0755},
+			&tar.Header{Name: "tool/lib/link", Typeflag: tar.TypeSymlink, Linkname: "../proton"},
+			&tar.Header{Name: "tool/hard", Typeflag: tar.TypeLink, Linkname: "tool/proton"},
+		)
+		destDir := filepath.Join(tmpDir, "out")
+
+		Expect(archive.ExtractTarGz(path, destDir)).To(Succeed())
+
+		data, err := os.ReadFile(filepath.Join(destDir, "tool", "proton"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("tool/proton"))
+		info, err := os.Stat(filepath.Join(destDir, "tool", "proton"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+		target, err := os.Readlink(filepath.Join(destDir, "tool", "lib", "link"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(target).To(Equal("../proton"))
+
+		data, err = os.ReadFile(filepath.Join(destDir, "tool", "hard"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("tool/proton"))
+	})
+
+	It("should reject paths outside the destination", func() {
+		path := createTarGz(&tar.Header{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644})
+
+		err := archive.ExtractTarGz(path, filepath.Join(tmpDir, "out"))
+		Expect(err).To(MatchError(ContainSubstring("invalid path")))
+		Expect(filepath.Join(tmpDir, "evil")).NotTo(BeAnExistingFile())
+	})
+
+	It("should reject writes through symlinks", func() {
+		outside := filepath.Join(tmpDir, "outside")
+		Expect(os.MkdirAll(outside, 0755)).To(Succeed())
+		path := createTarGz(
+			&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+			&tar.Header{Name: "link/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		)
+
+		Expect(archive.ExtractTarGz(path, filepath.Join(tmpDir, "out"))).NotTo(Succeed())
+		Expect(filepath.Join(outside, "evil")).NotTo(BeAnExistingFile())
+	})
+
+	It("should reject hard links to files outside the destination", func() {
+		secret := filepath.Join(tmpDir, "secret")
+		Expect(os.WriteFile(secret, []byte("secret"), 0600)).To(Succeed())
+		path := createTarGz(&tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "../secret"})
+
+		Expect(archive.ExtractTarGz(path, filepath.Join(tmpDir, "out"))).NotTo(Succeed())
+		Expect(filepath.Join(tmpDir, "out", "hard")).NotTo(BeAnExistingFile())
+	})
+
+	It("should return error for non-existent archive", func() {
+		Expect(archive.ExtractTarGz(filepath.Join(tmpDir, "missing.tar.gz"), tmpDir)).NotTo(Succeed())
+	})
+})
@@ -2,10 +2,13 @@ package archive
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"strings"
 )
 
 // ExpectedChecksum is the SHA256 checksum of the expected game archive.
@@ -27,13 +30,31 @@ func VerifyChecksum(path string, expected string) error {
 
 // CalculateChecksum calculates the SHA256 checksum of a file.
 func CalculateChecksum(path string) (string, error) {
+	return hashFile(path, sha256.New())
+}
+
+// VerifySHA512 verifies a file's SHA512 checksum, ignoring case.
+func VerifySHA512(path string, expected string) error {
+	actual, err := hashFile(path, sha512.New())
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	}
+
+	return nil
+}
+
+// hashFile returns the hex-encoded hash of a file's contents.
+func hashFile(path string, h hash.Hash) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %w", err)
 	}
 	defer func() { _ = f.Close() }()
 
-	h := sha256.New()
 	if _, err := io.Copy(h, f); err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
@@ -80,6 +80,28 @@ var _ = Describe("Verify", func() {
 		})
 	})
 
+	Describe("VerifySHA512", func() {
+		It("should succeed with correct checksum in any case", func() {
+			testFile := filepath.Join(tmpDir, "test.txt")
+			err := os.WriteFile(testFile, []byte("hello world"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			correctChecksum := "309ECC489C12D6EB4CC40F50C902F2B4D0ED77EE511A7C7A9BCD3CA86D4CD86F989DD35BC5FF499670DA34255B45B0CFD830E81F605DCF7DC5542E93AE9CD76F"
+			err = archive.VerifySHA512(testFile, correctChecksum)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should fail with incorrect checksum", func() {
+			testFile := filepath.Join(tmpDir, "test.txt")
+			err := os.WriteFile(testFile, []byte("hello world"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = archive.VerifySHA512(testFile, "00")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("checksum mismatch"))
+		})
+	})
+
 	Describe("FileExists", func() {
 		It("should return true for existing file", func() {
 			testFile := filepath.Join(tmpDir, "exists.txt")
@@ -4,6 +4,7 @@ package backup
 import (
 	"archive/tar"
 	"compress/gzip"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -24,6 +25,10 @@ type Options struct {
 	ExcludeDirs []string
 	// OnProgress is called with progress updates.
 	OnProgress func(current, total int64, currentFile string)
+	// Reason describes what the backup is taken before, e.g. "install".
+	Reason string
+	// InstallerVersion is recorded in the backup's metadata.
+	InstallerVersion string
 }
 
 // DefaultOptions returns the default backup options.
@@ -46,6 +51,21 @@ type Result struct {
 	FileCount int
 	// Duration is how long the backup took.
 	Duration time.Duration
+	// MetadataPath is the path to the backup's JSON sidecar.
+	MetadataPath string
+}
+
+// Metadata describes a backup. It is stored next to the backup as
+// "Steam-backup-<timestamp>.json".
+type Metadata struct {
+	SteamPath        string    `json:"steam_path"`
+	ExcludeDirs      []string  `json:"exclude_dirs"`
+	FileCount        int       `json:"file_count"`
+	Size             int64     `json:"size"`
+	DurationSeconds  float64   `json:"duration_seconds"`
+	InstallerVersion string    `json:"installer_version,omitempty"`
+	Reason           string    `json:"reason,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 // Create creates a backup of the Steam directory.
@@ -53,10 +73,14 @@ func Create(opts Options) (*Result, error) {
 	start := time.Now()
 
 	// Generate backup filename with timestamp
-	timestamp := time.Now().Format("20060102-150405")
-	filename := fmt.Sprintf("Steam-backup-%s.tar.gz", timestamp)
+	timestamp := start.Format(timeFormat)
+	filename := fmt.Sprintf("%s%s%s", filePrefix, timestamp, fileSuffix)
 	backupPath := filepath.Join(opts.OutputDir, filename)
 
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
 	// Create the backup file
 	file, err := os.Create(backupPath)
 	if err != nil {
@@ -172,18 +196,53 @@ func Create(opts Options) (*Result, error) {
 		return nil, fmt.Errorf("failed to create backup: %w", err)
 	}
 
+	// Flush the archive before measuring it
+	if err := tarWriter.Close(); err != nil {
+		_ = os.Remove(backupPath)
+		return nil, fmt.Errorf("failed to finish backup: %w", err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		_ = os.Remove(backupPath)
+		return nil, fmt.Errorf("failed to finish backup: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(backupPath)
+		return nil, fmt.Errorf("failed to finish backup: %w", err)
+	}
+
 	// Get final file size
 	fileInfo, err := os.Stat(backupPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat backup file: %w", err)
 	}
 
-	return &Result{
-		Path:      backupPath,
-		Size:      fileInfo.Size(),
-		FileCount: fileCount,
-		Duration:  time.Since(start),
-	}, nil
+	result := &Result{
+		Path:         backupPath,
+		Size:         fileInfo.Size(),
+		FileCount:    fileCount,
+		Duration:     time.Since(start),
+		MetadataPath: metadataPath(backupPath),
+	}
+
+	meta := Metadata{
+		SteamPath:        opts.SteamPath,
+		ExcludeDirs:      opts.ExcludeDirs,
+		FileCount:        result.FileCount,
+		Size:             result.Size,
+		DurationSeconds:  result.Duration.Seconds(),
+		InstallerVersion: opts.InstallerVersion,
+		Reason:           opts.Reason,
+		CreatedAt:        start,
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal backup metadata: %w", err)
+	}
+	if err := os.WriteFile(result.MetadataPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write backup metadata: %w", err)
+	}
+
+	return result, nil
 }
 
 // FormatSize returns a human-readable file size.
@@ -0,0 +1,375 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+)
+
+const (
+	fileBackupManifest = "backup.json"
+	fileBackupFilesDir = "files"
+)
+
+// FileBackup is a minimal backup of the individual Steam files a run is about
+// to modify, such as shortcuts.vdf and config.vdf, as opposed to the tarball
+// of the whole Steam directory made by Create.
+type FileBackup struct {
+	// ID uniquely identifies the backup (creation timestamp).
+	ID string `json:"id"`
+	// SteamPath is the Steam directory the files belong to.
+	SteamPath string `json:"steam_path"`
+	// Reason describes what the backup was taken before.
+	Reason string `json:"reason,omitempty"`
+	// CreatedAt is when the backup was taken.
+	CreatedAt time.Time `json:"created_at"`
+	// Files lists the backed up files.
+	Files []BackedUpFile `json:"files"`
+	// Dirs lists the directories backed up whole, relative to SteamPath.
+	// Restoring removes files added to them since.
+	Dirs []string `json:"dirs,omitempty"`
+	// Renames lists the renames done after the backup was taken, such as
+	// moving the compatdata of a migrated AppID. Restoring undoes them.
+	Renames []Rename `json:"renames,omitempty"`
+
+	dir string
+}
+
+// Rename records a path renamed inside the Steam directory. Both paths are
+// relative to the Steam directory.
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// BackedUpFile records a file captured in a FileBackup.
+type BackedUpFile struct {
+	// Path is relative to the Steam directory.
+	Path string `json:"path"`
+	// Existed is false for a file that didn't exist yet; restoring the
+	// backup removes it.
+	Existed bool        `json:"existed"`
+	Size    int64       `json:"size,omitempty"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+}
+
+// Dir returns the directory where the backup is stored.
+func (b *FileBackup) Dir() string {
+	return b.dir
+}
+
+// Size returns the total size of the backed up files.
+func (b *FileBackup) Size() int64 {
+	var size int64
+	for _, f := range b.Files {
+		size += f.Size
+	}
+	return size
+}
+
+// FileStore manages minimal backups of Steam files.
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore creates a file backup store rooted at dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+// Create backs up paths, which must be inside steamPath. A directory is
+// backed up with all of its files; a missing path is recorded so that
+// restoring removes it.
+func (s *FileStore) Create(steamPath, reason string, paths []string) (*FileBackup, error) {
+	now := time.Now()
+	id := now.Format("20060102-150405.000")
+	for n := 1; archive.FileExists(filepath.Join(s.Dir, id)); n++ {
+		id = fmt.Sprintf("%s-%d", now.Format("20060102-150405.000"), n)
+	}
+
+	b := &FileBackup{
+		ID:        id,
+		SteamPath: steamPath,
+		Reason:    reason,
+		CreatedAt: now,
+		dir:       filepath.Join(s.Dir, id),
+	}
+
+	files, dirs, err := collectFiles(steamPath, paths)
+	if err != nil {
+		return nil, err
+	}
+	b.Dirs = dirs
+	for _, f := range files {
+		if f.Existed {
+			src := filepath.Join(steamPath, f.Path)
+			if _, err := archive.CloneFile(src, filepath.Join(b.dir, fileBackupFilesDir, f.Path)); err != nil {
+				_ = os.RemoveAll(b.dir)
+				return nil, fmt.Errorf("failed to back up %s: %w", f.Path, err)
+			}
+		}
+		b.Files = append(b.Files, f)
+	}
+
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := b.writeManifest(); err != nil {
+		_ = os.RemoveAll(b.dir)
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// writeManifest writes the backup manifest.
+func (b *FileBackup) writeManifest() error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(b.dir, fileBackupManifest), data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	return nil
+}
+
+// RecordRename records that from was renamed to to after the backup was
+// taken, so restoring the backup renames it back. Both must be inside the
+// backup's Steam directory.
+func (b *FileBackup) RecordRename(from, to string) error {
+	var r Rename
+	for _, p := range []struct {
+		path string
+		rel  *string
+	}{{from, &r.From}, {to, &r.To}} {
+		rel, err := filepath.Rel(b.SteamPath, p.path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s is not inside the Steam directory %s", p.path, b.SteamPath)
+		}
+		*p.rel = rel
+	}
+
+	b.Renames = append(b.Renames, r)
+	return b.writeManifest()
+}
+
+// List returns the backups in the store, newest first.
+func (s *FileStore) List() ([]FileBackup, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []FileBackup
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.Dir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, fileBackupManifest))
+		if err != nil {
+			continue
+		}
+		var b FileBackup
+		if err := json.Unmarshal(data, &b); err != nil {
+			continue
+		}
+		b.dir = dir
+		backups = append(backups, b)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// Latest returns the most recent backup.
+func (s *FileStore) Latest() (*FileBackup, error) {
+	backups, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no backups found in %s", s.Dir)
+	}
+	return &backups[0], nil
+}
+
+// Restore undoes the recorded renames, puts the backed up files back in
+// place, and removes the files that didn't exist when the backup was taken,
+// including files added to backed up directories. Steam must not be running.
+func (s *FileStore) Restore(b *FileBackup) error {
+	for i := len(b.Renames) - 1; i >= 0; i-- {
+		if err := undoRename(b.SteamPath, b.Renames[i]); err != nil {
+			return err
+		}
+	}
+
+	backedUp := make(map[string]bool, len(b.Files))
+	for _, f := range b.Files {
+		backedUp[f.Path] = true
+		dst, err := backupTarget(b.SteamPath, f.Path)
+		if err != nil {
+			return err
+		}
+		if !f.Existed {
+			if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", f.Path, err)
+			}
+			continue
+		}
+		if _, err := archive.CloneFile(filepath.Join(b.dir, fileBackupFilesDir, f.Path), dst); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", f.Path, err)
+		}
+	}
+
+	for _, dir := range b.Dirs {
+		if err := removeAddedFiles(b.SteamPath, dir, backedUp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undoRename renames r.To back to r.From. A rename that was already undone
+// is skipped.
+func undoRename(steamPath string, r Rename) error {
+	from, err := backupTarget(steamPath, r.From)
+	if err != nil {
+		return err
+	}
+	to, err := backupTarget(steamPath, r.To)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(to); os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Lstat(from); err == nil {
+		return fmt.Errorf("can't move %s back to %s: it already exists", r.To, r.From)
+	}
+	if err := os.Rename(to, from); err != nil {
+		return fmt.Errorf("failed to move %s back to %s: %w", r.To, r.From, err)
+	}
+	return nil
+}
+
+// removeAddedFiles removes the regular files in the backed up directory dir
+// that aren't in the backup.
+func removeAddedFiles(steamPath, dir string, backedUp map[string]bool) error {
+	root, err := backupTarget(steamPath, dir)
+	if err != nil {
+		return err
+	}
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(steamPath, path)
+		if err != nil || backedUp[rel] {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", rel, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clean up %s: %w", dir, err)
+	}
+	return nil
+}
+
+// collectFiles lists the files to back up for paths, relative to steamPath,
+// and the directories among paths.
+func collectFiles(steamPath string, paths []string) ([]BackedUpFile, []string, error) {
+	var files []BackedUpFile
+	var dirs []string
+	seen := make(map[string]bool)
+	relPath := func(path string) (string, error) {
+		rel, err := filepath.Rel(steamPath, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("%s is not inside the Steam directory %s", path, steamPath)
+		}
+		return rel, nil
+	}
+	add := func(path string, info os.FileInfo) error {
+		rel, err := relPath(path)
+		if err != nil {
+			return err
+		}
+		if seen[rel] {
+			return nil
+		}
+		seen[rel] = true
+		f := BackedUpFile{Path: rel}
+		if info != nil {
+			f.Existed = true
+			f.Size = info.Size()
+			f.Mode = info.Mode().Perm()
+		}
+		files = append(files, f)
+		return nil
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			if err := add(path, nil); err != nil {
+				return nil, nil, err
+			}
+		case err != nil:
+			return nil, nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		case info.IsDir():
+			rel, err := relPath(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			dirs = append(dirs, rel)
+			err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.Mode().IsRegular() {
+					return nil
+				}
+				return add(p, info)
+			})
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to scan %s: %w", path, err)
+			}
+		default:
+			if err := add(path, info); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return files, dirs, nil
+}
+
+// backupTarget returns where a backed up file is restored to, making sure a
+// tampered manifest can't point outside the Steam directory.
+func backupTarget(steamPath, rel string) (string, error) {
+	return archive.SafeJoin(steamPath, rel)
+}
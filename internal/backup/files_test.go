@@ -0,0 +1,152 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/backup"
+)
+
+var _ = Describe("FileStore", func() {
+	var (
+		tmpDir   string
+		steamDir string
+		store    *backup.FileStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "filestore-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		steamDir = filepath.Join(tmpDir, "Steam")
+		Expect(os.MkdirAll(filepath.Join(steamDir, "config"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(steamDir, "userdata", "12345", "config", "grid"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(steamDir, "config", "config.vdf"), []byte("config"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(steamDir, "userdata", "12345", "config", "grid", "1p.png"), []byte("art"), 0644)).To(Succeed())
+
+		store = backup.NewFileStore(filepath.Join(tmpDir, "backups"))
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	paths := func() []string {
+		return []string{
+			filepath.Join(steamDir, "config", "config.vdf"),
+			filepath.Join(steamDir, "userdata", "12345", "config", "shortcuts.vdf"),
+			filepath.Join(steamDir, "userdata", "12345", "config", "grid"),
+		}
+	}
+
+	It("should back up only the given files", func() {
+		b, err := store.Create(steamDir, "install", paths())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(b.Files).To(ConsistOf(
+			backup.BackedUpFile{Path: filepath.Join("config", "config.vdf"), Existed: true, Size: 6, Mode: 0644},
+			backup.BackedUpFile{Path: filepath.Join("userdata", "12345", "config", "shortcuts.vdf")},
+			backup.BackedUpFile{Path: filepath.Join("userdata", "12345", "config", "grid", "1p.png"), Existed: true, Size: 3, Mode: 0644},
+		))
+		Expect(b.Dirs).To(ConsistOf(filepath.Join("userdata", "12345", "config", "grid")))
+		Expect(filepath.Join(b.Dir(), "files", "config", "config.vdf")).To(BeARegularFile())
+		Expect(b.Size()).To(Equal(int64(9)))
+	})
+
+	It("should refuse paths outside the Steam directory", func() {
+		_, err := store.Create(steamDir, "install", []string{filepath.Join(tmpDir, "other.vdf")})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("not inside the Steam directory"))
+	})
+
+	It("should list backups newest first", func() {
+		first, err := store.Create(steamDir, "install", paths())
+		Expect(err).NotTo(HaveOccurred())
+		second, err := store.Create(steamDir, "install", paths())
+		Expect(err).NotTo(HaveOccurred())
+
+		backups, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backups).To(HaveLen(2))
+		Expect(backups[0].ID).To(Equal(second.ID))
+		Expect(backups[1].ID).To(Equal(first.ID))
+
+		latest, err := store.Latest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(latest.ID).To(Equal(second.ID))
+		Expect(latest.Reason).To(Equal("install"))
+	})
+
+	It("should return an error when there are no backups", func() {
+		_, err := store.Latest()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should restore modified files and remove created ones", func() {
+		b, err := store.Create(steamDir, "install", paths())
+		Expect(err).NotTo(HaveOccurred())
+
+		shortcutsPath := filepath.Join(steamDir, "userdata", "12345", "config", "shortcuts.vdf")
+		Expect(os.WriteFile(filepath.Join(steamDir, "config", "config.vdf"), []byte("changed"), 0644)).To(Succeed())
+		Expect(os.WriteFile(shortcutsPath, []byte("shortcuts"), 0644)).To(Succeed())
+		Expect(os.Rename(
+			filepath.Join(steamDir, "userdata", "12345", "config", "grid", "1p.png"),
+			filepath.Join(steamDir, "userdata", "12345", "config", "grid", "2p.png"),
+		)).To(Succeed())
+
+		Expect(store.Restore(b)).To(Succeed())
+
+		Expect(os.ReadFile(filepath.Join(steamDir, "config", "config.vdf"))).To(Equal([]byte("config")))
+		Expect(shortcutsPath).NotTo(BeAnExistingFile())
+		Expect(os.ReadFile(filepath.Join(steamDir, "userdata", "12345", "config", "grid", "1p.png"))).To(Equal([]byte("art")))
+		Expect(filepath.Join(steamDir, "userdata", "12345", "config", "grid", "2p.png")).NotTo(BeAnExistingFile())
+	})
+
+	It("should undo recorded renames", func() {
+		b, err := store.Create(steamDir, "install", paths())
+		Expect(err).NotTo(HaveOccurred())
+
+		oldPrefix := filepath.Join(steamDir, "steamapps", "compatdata", "1")
+		newPrefix := filepath.Join(steamDir, "steamapps", "compatdata", "2")
+		Expect(os.MkdirAll(filepath.Join(oldPrefix, "pfx"), 0755)).To(Succeed())
+		Expect(os.Rename(oldPrefix, newPrefix)).To(Succeed())
+		Expect(b.RecordRename(oldPrefix, newPrefix)).To(Succeed())
+
+		latest, err := store.Latest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(latest.Renames).To(ConsistOf(backup.Rename{
+			From: filepath.Join("steamapps", "compatdata", "1"),
+			To:   filepath.Join("steamapps", "compatdata", "2"),
+		}))
+
+		Expect(store.Restore(latest)).To(Succeed())
+		Expect(filepath.Join(oldPrefix, "pfx")).To(BeADirectory())
+		Expect(newPrefix).NotTo(BeAnExistingFile())
+	})
+
+	It("should refuse to undo a rename over an existing path", func() {
+		b, err := store.Create(steamDir, "install", paths())
+		Expect(err).NotTo(HaveOccurred())
+
+		oldPrefix := filepath.Join(steamDir, "steamapps", "compatdata", "1")
+		newPrefix := filepath.Join(steamDir, "steamapps", "compatdata", "2")
+		Expect(os.MkdirAll(oldPrefix, 0755)).To(Succeed())
+		Expect(os.MkdirAll(newPrefix, 0755)).To(Succeed())
+		Expect(b.RecordRename(oldPrefix, newPrefix)).To(Succeed())
+
+		err = store.Restore(b)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("already exists"))
+	})
+
+	It("should refuse renames outside the Steam directory", func() {
+		b, err := store.Create(steamDir, "install", paths())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(b.RecordRename(filepath.Join(tmpDir, "a"), filepath.Join(steamDir, "b"))).NotTo(Succeed())
+	})
+})
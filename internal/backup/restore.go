@@ -0,0 +1,221 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+)
+
+// RestoreOptions configures restoring a backup made by Create.
+type RestoreOptions struct {
+	// SteamPath is the Steam directory to restore into.
+	SteamPath string
+	// Paths selects what to restore, relative to the Steam directory, e.g.
+	// "userdata/<id>/config" or "config/config.vdf". A directory selects
+	// everything below it. Empty restores the whole backup.
+	Paths []string
+}
+
+// ChangeKind describes what restoring does to a file.
+type ChangeKind string
+
+const (
+	// ChangeCreate restores a file that doesn't exist anymore.
+	ChangeCreate ChangeKind = "create"
+	// ChangeOverwrite replaces a file that differs from the backup.
+	ChangeOverwrite ChangeKind = "overwrite"
+	// ChangeUnchanged is a file that already matches the backup.
+	ChangeUnchanged ChangeKind = "unchanged"
+)
+
+// Change is a file a restore would write.
+type Change struct {
+	// Path is relative to the Steam directory.
+	Path string
+	Kind ChangeKind
+	// Size is the size of the file in the backup.
+	Size int64
+	// CurrentSize is the size of the file in the Steam directory.
+	CurrentSize int64
+}
+
+// PlanRestore compares the files selected by opts with the Steam directory,
+// without changing anything. Only regular files are restored; directories
+// and symlinks in the backup are skipped.
+func PlanRestore(backupPath string, opts RestoreOptions) ([]Change, error) {
+	var changes []Change
+	err := walkBackup(backupPath, opts, func(rel string, header *tar.Header, r io.Reader) error {
+		change := Change{Path: rel, Kind: ChangeCreate, Size: header.Size}
+
+		current, err := os.ReadFile(filepath.Join(opts.SteamPath, rel))
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		default:
+			change.Kind = ChangeOverwrite
+			change.CurrentSize = int64(len(current))
+			if change.CurrentSize == header.Size {
+				h := sha256.New()
+				if _, err := io.Copy(h, r); err != nil {
+					return fmt.Errorf("failed to read %s from backup: %w", rel, err)
+				}
+				currentHash := sha256.Sum256(current)
+				if bytes.Equal(h.Sum(nil), currentHash[:]) {
+					change.Kind = ChangeUnchanged
+				}
+			}
+		}
+
+		changes = append(changes, change)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Restore writes the files selected by opts from a backup made by Create
+// into the Steam directory, skipping files that already match. Paths in the
+// backup are sanitized like archive.ExtractTarGz does, and each file is
+// replaced atomically. Steam must not be running.
+func Restore(backupPath string, opts RestoreOptions) (int, error) {
+	restored := 0
+	err := walkBackup(backupPath, opts, func(rel string, header *tar.Header, r io.Reader) error {
+		target, err := archive.SafeJoin(opts.SteamPath, rel)
+		if err != nil {
+			return err
+		}
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from backup: %w", rel, err)
+		}
+		if current, err := os.ReadFile(target); err == nil && bytes.Equal(current, data) {
+			return nil
+		}
+
+		if err := writeFile(target, data, os.FileMode(header.Mode).Perm()); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", rel, err)
+		}
+		_ = os.Chtimes(target, header.ModTime, header.ModTime)
+		restored++
+		return nil
+	})
+	return restored, err
+}
+
+// walkBackup calls fn for each regular file in a backup selected by opts,
+// with its path relative to the Steam directory.
+func walkBackup(backupPath string, opts RestoreOptions, fn func(rel string, header *tar.Header, r io.Reader) error) error {
+	selected := make([]string, 0, len(opts.Paths))
+	for _, p := range opts.Paths {
+		clean, err := archive.CleanPath(p)
+		if err != nil || clean == "." {
+			return fmt.Errorf("invalid path to restore: %s", p)
+		}
+		selected = append(selected, clean)
+	}
+	matched := make([]bool, len(selected))
+
+	file, err := os.Open(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	gzReader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip: %w", err)
+	}
+	defer func() { _ = gzReader.Close() }()
+
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Entries are stored below a top-level "Steam" directory
+		name, err := archive.CleanPath(header.Name)
+		if err != nil {
+			return err
+		}
+		_, rel, ok := strings.Cut(name, string(filepath.Separator))
+		if !ok {
+			continue
+		}
+
+		if len(selected) > 0 {
+			found := false
+			for i, sel := range selected {
+				if rel == sel || strings.HasPrefix(rel, sel+string(filepath.Separator)) {
+					matched[i] = true
+					found = true
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+
+		if err := fn(rel, header, tarReader); err != nil {
+			return err
+		}
+	}
+
+	for i, sel := range selected {
+		if !matched[i] {
+			return fmt.Errorf("nothing in the backup matches %s", sel)
+		}
+	}
+	return nil
+}
+
+// writeFile replaces path with data through a temporary file, so that an
+// interrupted restore never leaves a truncated file behind. Missing parent
+// directories are created.
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,150 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/backup"
+)
+
+var _ = Describe("Restore", func() {
+	var (
+		tmpDir     string
+		steamDir   string
+		backupPath string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "restore-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		steamDir = filepath.Join(tmpDir, "Steam")
+		Expect(os.MkdirAll(filepath.Join(steamDir, "config"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(steamDir, "userdata", "12345", "config"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(steamDir, "config", "config.vdf"), []byte("config"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(steamDir, "config", "loginusers.vdf"), []byte("users"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(steamDir, "userdata", "12345", "config", "shortcuts.vdf"), []byte("shortcuts"), 0644)).To(Succeed())
+
+		result, err := backup.Create(backup.Options{SteamPath: steamDir, OutputDir: tmpDir})
+		Expect(err).NotTo(HaveOccurred())
+		backupPath = result.Path
+
+		// Change the Steam directory after the backup
+		Expect(os.WriteFile(filepath.Join(steamDir, "config", "config.vdf"), []byte("changed config"), 0644)).To(Succeed())
+		Expect(os.Remove(filepath.Join(steamDir, "userdata", "12345", "config", "shortcuts.vdf"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("PlanRestore", func() {
+		It("should compare every file in the backup", func() {
+			changes, err := backup.PlanRestore(backupPath, backup.RestoreOptions{SteamPath: steamDir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(ConsistOf(
+				backup.Change{Path: filepath.Join("config", "config.vdf"), Kind: backup.ChangeOverwrite, Size: 6, CurrentSize: 14},
+				backup.Change{Path: filepath.Join("config", "loginusers.vdf"), Kind: backup.ChangeUnchanged, Size: 5, CurrentSize: 5},
+				backup.Change{Path: filepath.Join("userdata", "12345", "config", "shortcuts.vdf"), Kind: backup.ChangeCreate, Size: 9},
+			))
+		})
+
+		It("should only include the selected paths", func() {
+			changes, err := backup.PlanRestore(backupPath, backup.RestoreOptions{
+				SteamPath: steamDir,
+				Paths:     []string{"userdata/12345/config"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].Path).To(Equal(filepath.Join("userdata", "12345", "config", "shortcuts.vdf")))
+		})
+
+		It("should reject a selected path that isn't in the backup", func() {
+			_, err := backup.PlanRestore(backupPath, backup.RestoreOptions{SteamPath: steamDir, Paths: []string{"userdata/999"}})
+			Expect(err).To(MatchError(ContainSubstring("nothing in the backup matches")))
+		})
+
+		It("should reject selected paths leaving the Steam directory", func() {
+			_, err := backup.PlanRestore(backupPath, backup.RestoreOptions{SteamPath: steamDir, Paths: []string{"../etc"}})
+			Expect(err).To(MatchError(ContainSubstring("invalid path")))
+		})
+	})
+
+	Describe("Restore", func() {
+		It("should restore the whole backup", func() {
+			restored, err := backup.Restore(backupPath, backup.RestoreOptions{SteamPath: steamDir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored).To(Equal(2))
+
+			Expect(os.ReadFile(filepath.Join(steamDir, "config", "config.vdf"))).To(Equal([]byte("config")))
+			Expect(os.ReadFile(filepath.Join(steamDir, "userdata", "12345", "config", "shortcuts.vdf"))).To(Equal([]byte("shortcuts")))
+		})
+
+		It("should restore only the selected file", func() {
+			restored, err := backup.Restore(backupPath, backup.RestoreOptions{
+				SteamPath: steamDir,
+				Paths:     []string{"config/config.vdf"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored).To(Equal(1))
+
+			Expect(os.ReadFile(filepath.Join(steamDir, "config", "config.vdf"))).To(Equal([]byte("config")))
+			Expect(filepath.Join(steamDir, "userdata", "12345", "config", "shortcuts.vdf")).NotTo(BeAnExistingFile())
+		})
+
+		It("should re-create removed directories", func() {
+			Expect(os.RemoveAll(filepath.Join(steamDir, "userdata", "12345"))).To(Succeed())
+
+			changes, err := backup.PlanRestore(backupPath, backup.RestoreOptions{SteamPath: steamDir, Paths: []string{"userdata"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].Kind).To(Equal(backup.ChangeCreate))
+
+			restored, err := backup.Restore(backupPath, backup.RestoreOptions{SteamPath: steamDir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored).To(Equal(2))
+			Expect(os.ReadFile(filepath.Join(steamDir, "userdata", "12345", "config", "shortcuts.vdf"))).To(Equal([]byte("shortcuts")))
+		})
+
+		It("should not write through symlinks", func() {
+			outside := filepath.Join(tmpDir, "outside")
+			Expect(os.MkdirAll(outside, 0755)).To(Succeed())
+			Expect(os.RemoveAll(filepath.Join(steamDir, "userdata"))).To(Succeed())
+			Expect(os.Symlink(outside, filepath.Join(steamDir, "userdata"))).To(Succeed())
+
+			_, err := backup.Restore(backupPath, backup.RestoreOptions{SteamPath: steamDir, Paths: []string{"userdata"}})
+			Expect(err).To(HaveOccurred())
+			Expect(filepath.Join(outside, "12345")).NotTo(BeAnExistingFile())
+		})
+
+		It("should reject entries leaving the Steam directory", func() {
+			evilPath := filepath.Join(tmpDir, "evil.tar.gz")
+			writeTarGz(evilPath, "Steam/../../evil.txt", "evil")
+
+			_, err := backup.Restore(evilPath, backup.RestoreOptions{SteamPath: steamDir})
+			Expect(err).To(MatchError(ContainSubstring("invalid path in archive")))
+			Expect(filepath.Join(tmpDir, "evil.txt")).NotTo(BeAnExistingFile())
+		})
+	})
+})
+
+// writeTarGz writes a .tar.gz archive holding a single file.
+func writeTarGz(path, name, content string) {
+	file, err := os.Create(path)
+	Expect(err).NotTo(HaveOccurred())
+	defer func() { _ = file.Close() }()
+
+	gzWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzWriter)
+	Expect(tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})).To(Succeed())
+	_, err = tarWriter.Write([]byte(content))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(tarWriter.Close()).To(Succeed())
+	Expect(gzWriter.Close()).To(Succeed())
+}
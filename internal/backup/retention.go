@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	filePrefix = "Steam-backup-"
+	fileSuffix = ".tar.gz"
+	timeFormat = "20060102-150405"
+)
+
+// Backup is a backup made by Create.
+type Backup struct {
+	// Path is the path to the backup file.
+	Path string
+	Metadata
+}
+
+// metadataPath returns the path of a backup's JSON sidecar.
+func metadataPath(backupPath string) string {
+	return strings.TrimSuffix(backupPath, fileSuffix) + ".json"
+}
+
+// List returns the backups in dirs, newest first. Backups without metadata,
+// such as those made by older versions, get their time from their name and
+// their size from the file. Missing directories are skipped.
+func List(dirs ...string) ([]Backup, error) {
+	var backups []Backup
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		found, err := listDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, found...)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// listDir returns the backups in dir, in no particular order.
+func listDir(dir string) ([]Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+
+		b := Backup{Path: filepath.Join(dir, name)}
+		if data, err := os.ReadFile(metadataPath(b.Path)); err == nil {
+			_ = json.Unmarshal(data, &b.Metadata)
+		}
+		if b.CreatedAt.IsZero() || b.Size == 0 {
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			b.Size = info.Size()
+			timestamp := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix)
+			if t, err := time.ParseInLocation(timeFormat, timestamp, time.Local); err == nil {
+				b.CreatedAt = t
+			} else {
+				b.CreatedAt = info.ModTime()
+			}
+		}
+		backups = append(backups, b)
+	}
+	return backups, nil
+}
+
+// PruneOptions selects the backups Prune removes.
+type PruneOptions struct {
+	// Keep is how many of the newest backups to keep; 0 keeps any number.
+	Keep int
+	// MaxAge removes backups older than this; 0 keeps backups of any age.
+	MaxAge time.Duration
+}
+
+// expired checks if the index-th newest backup, created at createdAt, is to
+// be removed.
+func (o PruneOptions) expired(index int, createdAt time.Time) bool {
+	if o.Keep > 0 && index >= o.Keep {
+		return true
+	}
+	return o.MaxAge > 0 && time.Since(createdAt) > o.MaxAge
+}
+
+// Prune removes the backups in dirs, and their metadata, that opts doesn't
+// keep. The backups of all dirs count towards opts.Keep together. Returns the
+// removed backups.
+func Prune(dirs []string, opts PruneOptions) ([]Backup, error) {
+	backups, err := List(dirs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Backup
+	for i, b := range backups {
+		if !opts.expired(i, b.CreatedAt) {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", filepath.Base(b.Path), err)
+		}
+		_ = os.Remove(metadataPath(b.Path))
+		removed = append(removed, b)
+	}
+	return removed, nil
+}
+
+// Prune removes the backups in the store that opts doesn't keep. Returns the
+// removed backups.
+func (s *FileStore) Prune(opts PruneOptions) ([]FileBackup, error) {
+	backups, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []FileBackup
+	for i, b := range backups {
+		if !opts.expired(i, b.CreatedAt) {
+			continue
+		}
+		if err := os.RemoveAll(b.dir); err != nil {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", b.ID, err)
+		}
+		removed = append(removed, b)
+	}
+	return removed, nil
+}
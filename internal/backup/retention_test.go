@@ -0,0 +1,185 @@
+package backup_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/backup"
+)
+
+var _ = Describe("Retention", func() {
+	var (
+		tmpDir    string
+		steamDir  string
+		backupDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "retention-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		steamDir = filepath.Join(tmpDir, "Steam")
+		Expect(os.MkdirAll(filepath.Join(steamDir, "config"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(steamDir, "config", "config.vdf"), []byte("config"), 0644)).To(Succeed())
+		backupDir = filepath.Join(tmpDir, "backups")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	// writeBackup writes a backup made at t, with metadata unless it is legacy.
+	writeBackup := func(t time.Time, legacy bool) string {
+		Expect(os.MkdirAll(backupDir, 0755)).To(Succeed())
+		path := filepath.Join(backupDir, "Steam-backup-"+t.Format("20060102-150405")+".tar.gz")
+		Expect(os.WriteFile(path, []byte("tarball"), 0644)).To(Succeed())
+		if !legacy {
+			data, err := json.Marshal(backup.Metadata{SteamPath: steamDir, Size: 7, CreatedAt: t, Reason: "install"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(backupDir, "Steam-backup-"+t.Format("20060102-150405")+".json"), data, 0644)).To(Succeed())
+		}
+		return path
+	}
+
+	Describe("Create", func() {
+		It("should write a metadata sidecar", func() {
+			result, err := backup.Create(backup.Options{
+				SteamPath:        steamDir,
+				OutputDir:        backupDir,
+				ExcludeDirs:      []string{"steamapps"},
+				Reason:           "install",
+				InstallerVersion: "v1.2.3",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.MetadataPath).To(Equal(strings.TrimSuffix(result.Path, ".tar.gz") + ".json"))
+
+			data, err := os.ReadFile(result.MetadataPath)
+			Expect(err).NotTo(HaveOccurred())
+			var meta backup.Metadata
+			Expect(json.Unmarshal(data, &meta)).To(Succeed())
+			Expect(meta.SteamPath).To(Equal(steamDir))
+			Expect(meta.ExcludeDirs).To(Equal([]string{"steamapps"}))
+			Expect(meta.FileCount).To(Equal(1))
+			Expect(meta.Size).To(Equal(result.Size))
+			Expect(meta.InstallerVersion).To(Equal("v1.2.3"))
+			Expect(meta.Reason).To(Equal("install"))
+
+			info, err := os.Stat(result.Path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Size).To(Equal(info.Size()))
+		})
+	})
+
+	Describe("List", func() {
+		It("should list backups newest first, with or without metadata", func() {
+			now := time.Now().Truncate(time.Second)
+			older := writeBackup(now.Add(-48*time.Hour), true)
+			newer := writeBackup(now.Add(-time.Hour), false)
+
+			backups, err := backup.List(backupDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backups).To(HaveLen(2))
+
+			Expect(backups[0].Path).To(Equal(newer))
+			Expect(backups[0].Reason).To(Equal("install"))
+			Expect(backups[1].Path).To(Equal(older))
+			Expect(backups[1].CreatedAt).To(BeTemporally("==", now.Add(-48*time.Hour)))
+			Expect(backups[1].Size).To(Equal(int64(7)))
+		})
+
+		It("should list backups across directories once", func() {
+			now := time.Now().Truncate(time.Second)
+			newer := writeBackup(now.Add(-time.Hour), true)
+			homeDir := filepath.Join(tmpDir, "home")
+			Expect(os.MkdirAll(homeDir, 0755)).To(Succeed())
+			older := filepath.Join(homeDir, "Steam-backup-"+now.Add(-48*time.Hour).Format("20060102-150405")+".tar.gz")
+			Expect(os.WriteFile(older, []byte("tarball"), 0644)).To(Succeed())
+
+			backups, err := backup.List(backupDir, homeDir, backupDir+"/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backups).To(HaveLen(2))
+			Expect(backups[0].Path).To(Equal(newer))
+			Expect(backups[1].Path).To(Equal(older))
+
+			removed, err := backup.Prune([]string{backupDir, homeDir}, backup.PruneOptions{Keep: 1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(HaveLen(1))
+			Expect(older).NotTo(BeAnExistingFile())
+		})
+
+		It("should return nothing for a missing directory", func() {
+			backups, err := backup.List(filepath.Join(tmpDir, "missing"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backups).To(BeEmpty())
+		})
+	})
+
+	Describe("Prune", func() {
+		var paths []string
+
+		BeforeEach(func() {
+			now := time.Now()
+			paths = []string{
+				writeBackup(now.Add(-time.Hour), false),
+				writeBackup(now.Add(-2*time.Hour), false),
+				writeBackup(now.Add(-72*time.Hour), false),
+			}
+		})
+
+		It("should keep the newest backups", func() {
+			removed, err := backup.Prune([]string{backupDir}, backup.PruneOptions{Keep: 2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(HaveLen(1))
+			Expect(removed[0].Path).To(Equal(paths[2]))
+
+			Expect(paths[2]).NotTo(BeAnExistingFile())
+			Expect(strings.TrimSuffix(paths[2], ".tar.gz") + ".json").NotTo(BeAnExistingFile())
+			Expect(paths[0]).To(BeARegularFile())
+		})
+
+		It("should remove backups older than the maximum age", func() {
+			removed, err := backup.Prune([]string{backupDir}, backup.PruneOptions{MaxAge: 90 * time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(HaveLen(2))
+
+			backups, err := backup.List(backupDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backups).To(HaveLen(1))
+			Expect(backups[0].Path).To(Equal(paths[0]))
+		})
+
+		It("should keep everything without a policy", func() {
+			removed, err := backup.Prune([]string{backupDir}, backup.PruneOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(BeEmpty())
+		})
+	})
+
+	Describe("FileStore.Prune", func() {
+		It("should keep the newest backups", func() {
+			store := backup.NewFileStore(filepath.Join(tmpDir, "steam-backups"))
+			var ids []string
+			for range 3 {
+				b, err := store.Create(steamDir, "install", []string{filepath.Join(steamDir, "config", "config.vdf")})
+				Expect(err).NotTo(HaveOccurred())
+				ids = append(ids, b.ID)
+			}
+
+			removed, err := store.Prune(backup.PruneOptions{Keep: 1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(HaveLen(2))
+
+			backups, err := store.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backups).To(HaveLen(1))
+			Expect(backups[0].ID).To(Equal(ids[2]))
+		})
+	})
+})
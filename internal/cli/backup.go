@@ -0,0 +1,426 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+	"github.com/jslay88/zladxhd-installer/internal/backup"
+	"github.com/jslay88/zladxhd-installer/internal/state"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "List, prune and restore Steam backups",
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List full backups and backups of the files the installer changed",
+	Args:  cobra.NoArgs,
+	RunE:  runBackupList,
+}
+
+var backupPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old backups (default: by the retention policy in the config)",
+	Args:  cobra.NoArgs,
+	RunE:  runBackupPrune,
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <file> [path...]",
+	Short: "Restore a Steam backup, or only the given paths of it",
+	Long: `Restore a Steam backup made with --backup-mode full into the Steam
+directory. Paths relative to the Steam directory, such as
+userdata/<id>/config or config/config.vdf, restore only those files.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runBackupRestore,
+}
+
+var backupRestoreLastCmd = &cobra.Command{
+	Use:   "restore-last",
+	Short: "Undo the last run's changes to Steam's files",
+	Args:  cobra.NoArgs,
+	RunE:  runBackupRestoreLast,
+}
+
+// defaultBackupKeep is how many backups of each kind are kept when the config
+// doesn't say.
+const defaultBackupKeep = 5
+
+var (
+	pruneKeep   int
+	pruneMaxAge string
+)
+
+func init() {
+	backupPruneCmd.Flags().IntVar(&pruneKeep, "keep", 0, "Number of the newest backups of each kind to keep (0: the default of 5, negative: all)")
+	backupPruneCmd.Flags().StringVar(&pruneMaxAge, "max-age", "", "Remove backups older than this, e.g. 30d or 72h")
+	backupRestoreCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask for confirmation")
+	backupRestoreLastCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask for confirmation")
+
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupPruneCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+	backupCmd.AddCommand(backupRestoreLastCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+// maxListedChanges limits how many files a restore lists before asking.
+const maxListedChanges = 50
+
+func runBackupList(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	dir := backupDirectory(stateMgr)
+	backups, err := backup.List(fullBackupDirs(stateMgr)...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Full backups in %s:\n", dir)
+	if len(backups) == 0 {
+		fmt.Println("   none")
+	}
+	for _, b := range backups {
+		name := filepath.Base(b.Path)
+		if filepath.Dir(b.Path) != filepath.Clean(dir) {
+			// Made by an older version
+			name = b.Path
+		}
+		fmt.Printf("   %-36s %s  %9s", name, b.CreatedAt.Format("2006-01-02 15:04"), backup.FormatSize(b.Size))
+		if b.FileCount > 0 {
+			fmt.Printf("  %d files", b.FileCount)
+		}
+		if b.Reason != "" {
+			fmt.Printf("  before %s", b.Reason)
+		}
+		if b.InstallerVersion != "" {
+			fmt.Printf("  (installer %s)", b.InstallerVersion)
+		}
+		fmt.Println()
+		if b.SteamPath != "" {
+			fmt.Printf("     of %s\n", b.SteamPath)
+		}
+	}
+	fmt.Println()
+
+	store := backup.NewFileStore(stateMgr.SteamBackupDir())
+	fileBackups, err := store.List()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backups of changed files in %s:\n", store.Dir)
+	if len(fileBackups) == 0 {
+		fmt.Println("   none")
+	}
+	for _, b := range fileBackups {
+		fmt.Printf("   %-36s %s  %9s  %d files  before %s\n", b.ID, b.CreatedAt.Format("2006-01-02 15:04"), backup.FormatSize(b.Size()), len(b.Files), b.Reason)
+	}
+	return nil
+}
+
+func runBackupPrune(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	opts, err := retentionPolicy(stateMgr)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("keep") || cmd.Flags().Changed("max-age") {
+		opts = backup.PruneOptions{Keep: keepCount(pruneKeep)}
+		if pruneMaxAge != "" {
+			if opts.MaxAge, err = parseAge(pruneMaxAge); err != nil {
+				return fmt.Errorf("invalid --max-age: %w", err)
+			}
+		}
+	}
+	if opts.Keep <= 0 && opts.MaxAge <= 0 {
+		return fmt.Errorf("nothing to prune by; pass a positive --keep or --max-age")
+	}
+
+	removed, err := backup.Prune(fullBackupDirs(stateMgr), opts)
+	for _, b := range removed {
+		fmt.Printf("✓ Removed %s (%s)\n", b.Path, backup.FormatSize(b.Size))
+	}
+	if err != nil {
+		return err
+	}
+
+	removedFiles, err := backup.NewFileStore(stateMgr.SteamBackupDir()).Prune(opts)
+	for _, b := range removedFiles {
+		fmt.Printf("✓ Removed backup of changed files %s\n", b.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(removed)+len(removedFiles) == 0 {
+		fmt.Println("✓ Nothing to prune")
+	}
+	return nil
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	s, err := selectSteam(stateMgr, false)
+	if err != nil {
+		return err
+	}
+	if s.IsRunning() {
+		return fmt.Errorf("Steam is running; close it before restoring files into %s", s.Path)
+	}
+
+	backupPath := args[0]
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) && !strings.ContainsRune(backupPath, filepath.Separator) {
+		// A name from `backup list`
+		for _, dir := range fullBackupDirs(stateMgr) {
+			if path := filepath.Join(dir, backupPath); archive.FileExists(path) {
+				backupPath = path
+				break
+			}
+		}
+	}
+	opts := backup.RestoreOptions{SteamPath: s.Path, Paths: args[1:]}
+	changes, err := backup.PlanRestore(backupPath, opts)
+	if err != nil {
+		return err
+	}
+
+	var toWrite []string
+	unchanged := 0
+	fmt.Printf("Restoring %s into %s:\n", filepath.Base(backupPath), s.Path)
+	for _, c := range changes {
+		if c.Kind == backup.ChangeUnchanged {
+			unchanged++
+			continue
+		}
+		if len(toWrite) < maxListedChanges {
+			if c.Kind == backup.ChangeCreate {
+				fmt.Printf("   + %s (%s)\n", c.Path, backup.FormatSize(c.Size))
+			} else {
+				fmt.Printf("   ~ %s (%s → %s)\n", c.Path, backup.FormatSize(c.CurrentSize), backup.FormatSize(c.Size))
+			}
+		}
+		toWrite = append(toWrite, filepath.Join(s.Path, c.Path))
+	}
+	if len(toWrite) > maxListedChanges {
+		fmt.Printf("   ... and %d more\n", len(toWrite)-maxListedChanges)
+	}
+	if unchanged > 0 {
+		fmt.Printf("   %d files already match the backup\n", unchanged)
+	}
+	if len(toWrite) == 0 {
+		fmt.Println("✓ Nothing to restore")
+		return nil
+	}
+
+	if !confirm(fmt.Sprintf("Restore %d files?", len(toWrite)),
+		"The current files are backed up first; `backup restore-last` undoes the restore.") {
+		return fmt.Errorf("restore cancelled")
+	}
+
+	store := backup.NewFileStore(stateMgr.SteamBackupDir())
+	if _, err := store.Create(s.Path, "restore", toWrite); err != nil {
+		return fmt.Errorf("failed to back up the current files: %w", err)
+	}
+
+	restored, err := backup.Restore(backupPath, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Restored %d files\n", restored)
+	pruneBackups(stateMgr)
+	return nil
+}
+
+func runBackupRestoreLast(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	store := backup.NewFileStore(stateMgr.SteamBackupDir())
+	b, err := store.Latest()
+	if err != nil {
+		return err
+	}
+
+	if err := checkSteamStopped(b.SteamPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Backup %s (before %s) of %s:\n", b.ID, b.Reason, b.SteamPath)
+	for i := len(b.Renames) - 1; i >= 0; i-- {
+		fmt.Printf("   move     %s back to %s\n", b.Renames[i].To, b.Renames[i].From)
+	}
+	for _, dir := range b.Dirs {
+		fmt.Printf("   clean    %s (files added since the backup)\n", dir)
+	}
+	for _, f := range b.Files {
+		if f.Existed {
+			fmt.Printf("   restore  %s (%s)\n", f.Path, backup.FormatSize(f.Size))
+		} else {
+			fmt.Printf("   remove   %s\n", f.Path)
+		}
+	}
+	if !confirm(fmt.Sprintf("Restore %d files from backup %s?", len(b.Files), b.ID),
+		"The current files are backed up first, so running restore-last again undoes this.") {
+		return fmt.Errorf("restore cancelled")
+	}
+
+	// Back up what's there now, so the restore can be undone as well
+	paths := make([]string, 0, len(b.Dirs)+len(b.Files))
+	for _, dir := range b.Dirs {
+		paths = append(paths, filepath.Join(b.SteamPath, dir))
+	}
+	for _, f := range b.Files {
+		paths = append(paths, filepath.Join(b.SteamPath, f.Path))
+	}
+	current, err := store.Create(b.SteamPath, "restore-last", paths)
+	if err != nil {
+		return fmt.Errorf("failed to back up the current files: %w", err)
+	}
+	for i := len(b.Renames) - 1; i >= 0; i-- {
+		r := b.Renames[i]
+		if err := current.RecordRename(filepath.Join(b.SteamPath, r.To), filepath.Join(b.SteamPath, r.From)); err != nil {
+			return fmt.Errorf("failed to back up the current files: %w", err)
+		}
+	}
+
+	if err := store.Restore(b); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Restored %d files from backup %s\n", len(b.Files), b.ID)
+	pruneBackups(stateMgr)
+	return nil
+}
+
+// checkSteamStopped returns an error if the Steam installation at path is
+// running, as it would overwrite restored files when it exits.
+func checkSteamStopped(path string) error {
+	installs, err := steam.DiscoverAll()
+	if err != nil {
+		return err
+	}
+	s, err := steam.FindInstall(installs, path)
+	if err != nil {
+		return err
+	}
+	if s.IsRunning() {
+		return fmt.Errorf("Steam is running; close it before restoring files into %s", s.Path)
+	}
+	return nil
+}
+
+// backupDirectory returns where full backups go: --backup-dir, the config's
+// backup_dir, or the state directory's backups directory.
+func backupDirectory(stateMgr *state.Manager) string {
+	if backupDir != "" {
+		return backupDir
+	}
+	if dir := stateMgr.Config().BackupDir; dir != "" {
+		return dir
+	}
+	return stateMgr.BackupDir()
+}
+
+// fullBackupDirs returns the directories full backups are listed and pruned
+// in: backupDirectory and the home directory, where older versions wrote them.
+func fullBackupDirs(stateMgr *state.Manager) []string {
+	dirs := []string{backupDirectory(stateMgr)}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+	return dirs
+}
+
+// keepCount turns a number of backups to keep, as given in the config or with
+// --keep, into PruneOptions.Keep: 0 means defaultBackupKeep and a negative
+// number keeps all of them.
+func keepCount(n int) int {
+	switch {
+	case n == 0:
+		return defaultBackupKeep
+	case n < 0:
+		return 0
+	}
+	return n
+}
+
+// retentionPolicy returns the backup retention policy from the config.
+func retentionPolicy(stateMgr *state.Manager) (backup.PruneOptions, error) {
+	cfg := stateMgr.Config()
+	opts := backup.PruneOptions{Keep: keepCount(cfg.BackupKeep)}
+	if cfg.BackupMaxAge != "" {
+		maxAge, err := parseAge(cfg.BackupMaxAge)
+		if err != nil {
+			return opts, fmt.Errorf("invalid backup_max_age in config: %w", err)
+		}
+		opts.MaxAge = maxAge
+	}
+	return opts, nil
+}
+
+// pruneBackups applies the retention policy after a backup was made. Failures
+// are only reported, as the backup itself succeeded.
+func pruneBackups(stateMgr *state.Manager) {
+	opts, err := retentionPolicy(stateMgr)
+	if err != nil {
+		fmt.Printf("   ⚠ Not removing old backups: %v\n", err)
+		return
+	}
+	if opts.Keep <= 0 && opts.MaxAge <= 0 {
+		return
+	}
+
+	removed, err := backup.Prune(fullBackupDirs(stateMgr), opts)
+	count := len(removed)
+	if err == nil {
+		var removedFiles []backup.FileBackup
+		removedFiles, err = backup.NewFileStore(stateMgr.SteamBackupDir()).Prune(opts)
+		count += len(removedFiles)
+	}
+	if err != nil {
+		fmt.Printf("   ⚠ Failed to remove old backups: %v\n", err)
+	}
+	if count > 0 {
+		fmt.Printf("   ✓ Removed %d old backups\n", count)
+	}
+}
+
+// parseAge parses a duration like time.ParseDuration, also accepting days
+// ("30d") and weeks ("2w").
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if value, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,329 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jslay88/zladxhd-installer/internal/patcher"
+	"github.com/jslay88/zladxhd-installer/internal/runner"
+	"github.com/jslay88/zladxhd-installer/internal/state"
+)
+
+var patchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "Inspect and manage the HD patch",
+}
+
+var patchStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the installed game is patched and to which version",
+	RunE:  runPatchStatus,
+}
+
+var patchRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restore the game directory to its state before the last patch",
+	RunE:  runPatchRollback,
+}
+
+var patchReapplyCmd = &cobra.Command{
+	Use:   "reapply",
+	Short: "Restore the unpatched game and apply a specific patcher version",
+	RunE:  runPatchReapply,
+}
+
+var reapplyVersion string
+
+func init() {
+	patchReapplyCmd.Flags().StringVar(&reapplyVersion, "version", "", "Patcher release tag to apply (required)")
+	_ = patchReapplyCmd.MarkFlagRequired("version")
+
+	patchCmd.AddCommand(patchStatusCmd)
+	patchCmd.AddCommand(patchRollbackCmd)
+	patchCmd.AddCommand(patchReapplyCmd)
+	rootCmd.AddCommand(patchCmd)
+}
+
+func runPatchStatus(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	gameDir, err := resolveGameDir(stateMgr)
+	if err != nil {
+		return err
+	}
+
+	version, err := patcher.DetectVersion(gameDir)
+	if err != nil {
+		return fmt.Errorf("failed to detect game version: %w", err)
+	}
+
+	fmt.Printf("Game directory: %s\n", gameDir)
+	fmt.Printf("Executable:     %s\n", filepath.Base(version.ExePath))
+	fmt.Printf("Version:        %s\n", version)
+
+	if st := stateMgr.State(); st != nil && st.PatcherVersion != "" && st.InstallDir == gameDir {
+		fmt.Printf("Patcher:        %s\n", st.PatcherVersion)
+		if st.PatchedAt != nil {
+			fmt.Printf("Patched at:     %s\n", st.PatchedAt.Local().Format(time.RFC1123))
+		}
+	}
+
+	return nil
+}
+
+func runPatchRollback(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	gameDir, err := resolveGameDir(stateMgr)
+	if err != nil {
+		return err
+	}
+
+	store := patcher.NewSnapshotStore(stateMgr.SnapshotDir())
+	snap, err := store.Latest(gameDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("⏪ Restoring snapshot %s", snap.ID)
+	if snap.GameVersion != "" {
+		fmt.Printf(" (game version %s)", snap.GameVersion)
+	}
+	fmt.Println("...")
+
+	if err := store.Restore(snap); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+
+	recordGameVersion(gameDir, "", stateMgr)
+	fmt.Printf("   ✓ Restored %s\n", gameDir)
+	return nil
+}
+
+func runPatchReapply(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	gameDir, err := resolveGameDir(stateMgr)
+	if err != nil {
+		return err
+	}
+
+	appID := stateMgr.Config().LastAppID
+	if appID == 0 {
+		return fmt.Errorf("no installed AppID known; run the installer first")
+	}
+
+	// Return to the unpatched game before applying the requested version
+	store := patcher.NewSnapshotStore(stateMgr.SnapshotDir())
+	snap, err := store.Original(gameDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("⏪ Restoring unpatched snapshot %s...\n", snap.ID)
+	if err := store.Restore(snap); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	fmt.Println("   ✓ Restored")
+	fmt.Println()
+
+	wineRunner, err := newInstalledRunner(appID, stateMgr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("⬇️  Downloading HD patcher %s...\n", reapplyVersion)
+	p, err := newPatcher(gameDir, stateMgr)
+	if err != nil {
+		return err
+	}
+	if err := p.DownloadVersion(reapplyVersion, true); err != nil {
+		return fmt.Errorf("failed to download patcher: %w", err)
+	}
+	fmt.Printf("   ✓ Patcher ready: %s\n", filepath.Base(p.PatcherPath))
+	fmt.Println()
+
+	return applyPatch(p, wineRunner, stateMgr)
+}
+
+// newInstalledRunner creates a runner for the prefix of the last installation.
+// The Proton version recorded in the config is used for non-protontricks backends.
+func newInstalledRunner(appID uint32, stateMgr *state.Manager) (runner.Runner, error) {
+	kind, ptInstall, err := selectRunner()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := runner.Prefix{AppID: appID}
+	if kind != runner.KindProtontricks {
+		s, err := selectSteam(stateMgr, false)
+		if err != nil {
+			return nil, err
+		}
+		protonName := stateMgr.Config().LastProton
+		if protonName == "" {
+			return nil, fmt.Errorf("no Proton version known; run the installer first or use --runner protontricks")
+		}
+		protonPath, err := s.GetProtonPath(protonName)
+		if err != nil {
+			return nil, err
+		}
+		prefix.SteamPath = s.Path
+		prefix.CompatDataPath = filepath.Join(s.CompatPath, fmt.Sprintf("%d", appID))
+		prefix.ProtonPath = protonPath
+	}
+
+	return runner.New(kind, prefix, ptInstall)
+}
+
+// applyPatch snapshots the game directory, runs the patcher and reports the result.
+// A game already patched by the same patcher version is left as is.
+// A failed snapshot is reported but does not prevent patching. Returns an
+// error if the game could not be verified as patched.
+func applyPatch(p *patcher.Patcher, r runner.Runner, stateMgr *state.Manager) error {
+	if p.AlreadyApplied() {
+		fmt.Printf("✓ Game already patched with patcher %s\n", p.Version)
+		recordGameVersion(p.GameDir, p.Version, stateMgr)
+		fmt.Println()
+		return nil
+	}
+
+	fmt.Println("📸 Snapshotting game directory...")
+	store := patcher.NewSnapshotStore(stateMgr.SnapshotDir())
+	snap, err := store.Create(p.GameDir)
+	if err != nil {
+		fmt.Printf("   ⚠ Failed to snapshot game directory: %v\n", err)
+		fmt.Println("   Rollback will not be available for this patch.")
+	} else {
+		method := "copied"
+		if snap.Reflinked {
+			method = "reflinked"
+		}
+		fmt.Printf("   ✓ Snapshot %s (%d files, %s)\n", snap.ID, len(snap.Files), method)
+	}
+	fmt.Println()
+
+	fmt.Println("🔧 Running HD patcher...")
+	patcherErr := runPatcher(p, r)
+	if snap != nil {
+		if err := store.RecordCreated(snap); err != nil {
+			fmt.Printf("   ⚠ Failed to record the files the patcher created: %v\n", err)
+		}
+		// Keep the unpatched original plus the two most recent snapshots
+		_ = store.Prune(p.GameDir, 2)
+	}
+
+	err = reportPatchResult(p, patcherErr, stateMgr)
+	fmt.Println()
+	return err
+}
+
+// runPatcher applies the patch with the engine selected by --patch-engine.
+// In auto mode the native engine is tried first and the Wine patcher is used
+// when the release has no patch data or native patching fails.
+func runPatcher(p *patcher.Patcher, r runner.Runner) error {
+	if patchEngine != "wine" {
+		err := runWithSpinner("   Applying patch natively", func() error {
+			return p.ApplyNative(false)
+		})
+		switch {
+		case err == nil:
+			fmt.Println("   ✓ Applied patch data without Wine")
+			return nil
+		case patchEngine == "native":
+			return err
+		case errors.Is(err, patcher.ErrNoPatchData):
+			fmt.Println("   No native patch data in this release, using the Wine patcher")
+		default:
+			fmt.Printf("   ⚠ Native patching failed: %v\n", err)
+			fmt.Println("   Falling back to the Wine patcher")
+		}
+	}
+
+	return runWithSpinner("   Running patcher", func() error {
+		return p.Run(r, true)
+	})
+}
+
+// recordGameVersion detects the game version and stores it in the installation
+// state. The patcher version is cleared when the game is no longer patched.
+func recordGameVersion(gameDir string, patcherVersion string, stateMgr *state.Manager) {
+	version, err := patcher.DetectVersion(gameDir)
+	if err != nil {
+		return
+	}
+
+	st := stateMgr.EnsureState()
+	st.InstallDir = gameDir
+	st.GameVersion = version.Version
+	if !version.Patched {
+		st.PatcherVersion = ""
+		st.PatchedAt = nil
+	} else if patcherVersion != "" {
+		now := time.Now()
+		st.PatcherVersion = patcherVersion
+		st.PatchedAt = &now
+	}
+	_ = stateMgr.SaveState()
+}
+
+// resolveGameDir returns the game directory from --install-dir or the last install.
+func resolveGameDir(stateMgr *state.Manager) (string, error) {
+	dir := installDir
+	if dir == "" {
+		dir = stateMgr.Config().LastInstallDir
+	}
+	if dir == "" {
+		return "", fmt.Errorf("no install directory known; pass --install-dir")
+	}
+
+	if dir[0] == '~' {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, dir[1:])
+	}
+
+	return dir, nil
+}
+
+// reportPatchResult verifies the patched game, prints the outcome and
+// records the detected version in the installation state. Returns an error
+// if the patcher failed or the patch could not be verified.
+func reportPatchResult(p *patcher.Patcher, runErr error, stateMgr *state.Manager) error {
+	version, verifyErr := p.Verify()
+
+	switch {
+	case runErr == nil && verifyErr == nil:
+		fmt.Printf("   ✓ Game patched to version %s (patcher %s)\n", version.Version, p.Version)
+	case version != nil:
+		fmt.Printf("   ✗ Patching failed: game is at version %s without a verified patch\n", version.Version)
+	default:
+		fmt.Printf("   ✗ Could not verify patch: %v\n", verifyErr)
+	}
+	if runErr != nil {
+		fmt.Printf("   Patcher exited with error: %v\n", runErr)
+	}
+
+	recordGameVersion(p.GameDir, p.Version, stateMgr)
+
+	if runErr != nil {
+		return fmt.Errorf("patching failed: %w", runErr)
+	}
+	if verifyErr != nil {
+		return fmt.Errorf("patching failed: %w", verifyErr)
+	}
+	return nil
+}
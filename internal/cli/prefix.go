@@ -0,0 +1,364 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+
+	"github.com/jslay88/zladxhd-installer/internal/backup"
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/runner"
+	"github.com/jslay88/zladxhd-installer/internal/state"
+)
+
+var prefixCmd = &cobra.Command{
+	Use:   "prefix",
+	Short: "Inspect and repair the game's Wine prefix",
+}
+
+var prefixInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show the prefix's Proton version, size, health and installed verbs",
+	Args:  cobra.NoArgs,
+	RunE:  runPrefixInfo,
+}
+
+var prefixResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete and re-initialize the prefix, keeping saves",
+	Args:  cobra.NoArgs,
+	RunE:  runPrefixReset,
+}
+
+var prefixSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save a compressed snapshot of the prefix",
+	Args:  cobra.NoArgs,
+	RunE:  runPrefixSnapshot,
+}
+
+var prefixRestoreCmd = &cobra.Command{
+	Use:   "restore [snapshot]",
+	Short: "Replace the prefix with a snapshot (default: the latest)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runPrefixRestore,
+}
+
+var prefixCloneCmd = &cobra.Command{
+	Use:   "clone <appid>",
+	Short: "Copy the prefix to another AppID",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPrefixClone,
+}
+
+var (
+	prefixAppID uint32
+	assumeYes   bool
+)
+
+func init() {
+	prefixCmd.PersistentFlags().Uint32Var(&prefixAppID, "app-id", 0, "AppID of the prefix (default: the installed game)")
+	prefixResetCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask for confirmation")
+	prefixRestoreCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask for confirmation")
+
+	prefixCmd.AddCommand(prefixInfoCmd)
+	prefixCmd.AddCommand(prefixResetCmd)
+	prefixCmd.AddCommand(prefixSnapshotCmd)
+	prefixCmd.AddCommand(prefixRestoreCmd)
+	prefixCmd.AddCommand(prefixCloneCmd)
+	rootCmd.AddCommand(prefixCmd)
+}
+
+func runPrefixInfo(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	cfg, err := installedPrefix(stateMgr, false)
+	if err != nil {
+		return err
+	}
+	if !cfg.HasCompatData() {
+		return fmt.Errorf("no prefix found at %s", cfg.CompatDataPath())
+	}
+
+	fmt.Printf("AppID:          %d\n", cfg.AppID)
+	fmt.Printf("Prefix:         %s\n", cfg.PrefixPath())
+
+	version, err := cfg.ProtonVersion()
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		version = "unknown"
+	}
+	fmt.Printf("Proton version: %s\n", version)
+
+	size, err := cfg.PrefixSize()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Size:           %s\n", backup.FormatSize(size))
+
+	if err := cfg.CheckPrefix(); err != nil {
+		fmt.Println("Health:         ⚠ problems found")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Printf("                - %s\n", line)
+		}
+	} else {
+		fmt.Println("Health:         ✓ ok")
+	}
+
+	verbs, err := cfg.InstalledVerbs()
+	if err != nil {
+		return err
+	}
+	if len(verbs) == 0 {
+		fmt.Println("Verbs:          none")
+	} else {
+		fmt.Printf("Verbs:          %s\n", strings.Join(verbs, ", "))
+	}
+
+	snapshots, err := proton.ListSnapshots(stateMgr.PrefixSnapshotDir(), cfg.AppID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Snapshots:      %d\n", len(snapshots))
+	for _, snap := range snapshots {
+		fmt.Printf("                - %s (%s)\n", filepath.Base(snap.Path), backup.FormatSize(snap.Size))
+	}
+
+	return nil
+}
+
+func runPrefixReset(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	recipe, err := prefixRecipe(stateMgr)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := installedPrefix(stateMgr, true)
+	if err != nil {
+		return err
+	}
+
+	if err := checkPrefixUnused(cfg); err != nil {
+		return err
+	}
+
+	if !confirm(fmt.Sprintf("Delete and re-create the prefix at %s?", cfg.CompatDataPath()),
+		"Saves in AppData, Documents and Saved Games are kept.") {
+		return fmt.Errorf("reset cancelled")
+	}
+
+	runnerKind, ptInstall, err := selectRunner()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("🍷 Re-creating Wine prefix...")
+	err = runWithSpinner("   Initializing", func() error {
+		return cfg.Reset(true)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset prefix: %w", err)
+	}
+	fmt.Printf("   ✓ Prefix initialized at: %s\n", cfg.PrefixPath())
+	recordProton(cfg, stateMgr)
+	fmt.Println()
+
+	wineRunner, err := runner.New(runnerKind, runnerPrefix(cfg), ptInstall)
+	if err != nil {
+		return err
+	}
+	if err := setupPrefix(wineRunner, cfg, recipe, stateMgr.CacheDir()); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Prefix reset complete!")
+	return nil
+}
+
+func runPrefixSnapshot(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	cfg, err := installedPrefix(stateMgr, false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("📸 Snapshotting Wine prefix...")
+	var snap *proton.PrefixSnapshot
+	err = runWithSpinner("   Compressing", func() error {
+		var err error
+		snap, err = cfg.Snapshot(stateMgr.PrefixSnapshotDir())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to snapshot prefix: %w", err)
+	}
+	fmt.Printf("   ✓ Snapshot saved: %s (%s)\n", snap.Path, backup.FormatSize(snap.Size))
+	return nil
+}
+
+func runPrefixRestore(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	cfg, err := installedPrefix(stateMgr, false)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := proton.ListSnapshots(stateMgr.PrefixSnapshotDir(), cfg.AppID)
+	if err != nil {
+		return err
+	}
+
+	var snapshotPath string
+	switch {
+	case len(args) == 1 && strings.ContainsRune(args[0], filepath.Separator):
+		snapshotPath = args[0]
+	case len(args) == 1:
+		for _, snap := range snapshots {
+			if filepath.Base(snap.Path) == args[0] || strings.TrimSuffix(filepath.Base(snap.Path), ".tar.gz") == args[0] {
+				snapshotPath = snap.Path
+			}
+		}
+		if snapshotPath == "" {
+			return fmt.Errorf("snapshot %q not found for AppID %d", args[0], cfg.AppID)
+		}
+	case len(snapshots) == 0:
+		return fmt.Errorf("no prefix snapshots found for AppID %d", cfg.AppID)
+	default:
+		snapshotPath = snapshots[0].Path
+	}
+
+	if err := checkPrefixUnused(cfg); err != nil {
+		return err
+	}
+
+	if !confirm(fmt.Sprintf("Replace the prefix at %s?", cfg.CompatDataPath()),
+		"The current prefix is replaced by "+filepath.Base(snapshotPath)+".") {
+		return fmt.Errorf("restore cancelled")
+	}
+
+	fmt.Printf("⏪ Restoring %s...\n", filepath.Base(snapshotPath))
+	err = runWithSpinner("   Extracting", func() error {
+		return cfg.Restore(snapshotPath)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore prefix: %w", err)
+	}
+	fmt.Printf("   ✓ Restored %s\n", cfg.CompatDataPath())
+	return nil
+}
+
+func runPrefixClone(cmd *cobra.Command, args []string) error {
+	targetID, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil || targetID == 0 {
+		return fmt.Errorf("invalid AppID %q", args[0])
+	}
+
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	cfg, err := installedPrefix(stateMgr, false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📋 Cloning prefix %d to %d...\n", cfg.AppID, targetID)
+	var target *proton.Config
+	err = runWithSpinner("   Copying", func() error {
+		var err error
+		target, err = cfg.Clone(uint32(targetID))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clone prefix: %w", err)
+	}
+	fmt.Printf("   ✓ Cloned to: %s\n", target.CompatDataPath())
+	return nil
+}
+
+// checkPrefixUnused refuses to replace the prefix while Steam is running, as
+// Steam or the game could write to it meanwhile.
+func checkPrefixUnused(cfg *proton.Config) error {
+	if cfg.Steam.IsRunning() {
+		return fmt.Errorf("Steam is running; close it before replacing the prefix at %s", cfg.CompatDataPath())
+	}
+	return nil
+}
+
+// installedPrefix returns the Proton configuration for the prefix selected
+// with --app-id, defaulting to the installed game. The Proton installation
+// recorded in the config is only required if needProton is set.
+func installedPrefix(stateMgr *state.Manager, needProton bool) (*proton.Config, error) {
+	appID := prefixAppID
+	if appID == 0 {
+		appID = stateMgr.Config().LastAppID
+	}
+	if appID == 0 {
+		return nil, fmt.Errorf("no installed AppID known; run the installer first or pass --app-id")
+	}
+
+	s, err := selectSteam(stateMgr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &proton.Config{
+		Steam:      s,
+		AppID:      appID,
+		ProtonName: stateMgr.Config().LastProton,
+	}
+	if needProton {
+		if cfg.ProtonName == "" {
+			return nil, fmt.Errorf("no Proton version known; run the installer first")
+		}
+		if cfg.ProtonPath, err = s.GetProtonPath(cfg.ProtonName); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// confirm asks a yes/no question unless --yes was given.
+func confirm(title string, description string) bool {
+	if assumeYes {
+		return true
+	}
+
+	var ok bool
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(title).
+				Description(description).
+				Value(&ok),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return false
+	}
+	return ok
+}
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/release"
+	"github.com/jslay88/zladxhd-installer/internal/state"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var protonCmd = &cobra.Command{
+	Use:   "proton",
+	Short: "List, install and remove Proton versions",
+}
+
+var protonListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed Proton versions and the recommended one",
+	Args:  cobra.NoArgs,
+	RunE:  runProtonList,
+}
+
+var protonInstallCmd = &cobra.Command{
+	Use:   "install [tag]",
+	Short: "Download and install a GE-Proton release (default: the latest)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runProtonInstall,
+}
+
+var protonRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a custom compatibility tool from compatibilitytools.d",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProtonRemove,
+}
+
+var protonListAvailable bool
+
+func init() {
+	protonListCmd.Flags().BoolVar(&protonListAvailable, "available", false, "Also list GE-Proton releases that can be installed")
+	protonRemoveCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask for confirmation")
+
+	protonCmd.AddCommand(protonListCmd)
+	protonCmd.AddCommand(protonInstallCmd)
+	protonCmd.AddCommand(protonRemoveCmd)
+	rootCmd.AddCommand(protonCmd)
+}
+
+func runProtonList(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	s, err := selectSteam(stateMgr, false)
+	if err != nil {
+		return err
+	}
+
+	versions, err := proton.ListProtonVersions(s)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) == 0 {
+		fmt.Println("No Proton versions installed. Run `zladxhd-installer proton install` to get GE-Proton.")
+	} else {
+		recommendation, err := proton.Recommend(versions)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Installed Proton versions:")
+		for _, v := range versions {
+			marker := "  "
+			if v.Name == recommendation.Version.Name {
+				marker = "★ "
+			}
+			fmt.Printf("%s%-28s %s\n", marker, v.Name, v.Path)
+
+			cfg := &proton.Config{Steam: s, ProtonName: v.Name, ProtonPath: v.Path}
+			users, err := proton.CompatToolUsers(s, cfg.GetCompatToolName())
+			if err == nil && len(users) > 0 {
+				fmt.Printf("    used by AppIDs: %s\n", strings.Join(users, ", "))
+			}
+		}
+		fmt.Println()
+		fmt.Printf("Recommended: %s, because %s\n", recommendation.Version.Name, recommendation.Reason)
+	}
+
+	if !protonListAvailable {
+		return nil
+	}
+
+	src, err := protonReleaseSource(stateMgr.CacheDir())
+	if err != nil {
+		return err
+	}
+	releases, err := src.List()
+	if err != nil {
+		return fmt.Errorf("failed to list GE-Proton releases: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("Available GE-Proton releases:")
+	for _, rel := range releases {
+		status := ""
+		if tool, err := s.FindCompatTool(rel.TagName); err == nil && tool != nil {
+			status = " (installed)"
+		}
+		fmt.Printf("  %s%s\n", rel.TagName, status)
+	}
+	return nil
+}
+
+func runProtonInstall(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	s, err := selectSteam(stateMgr, false)
+	if err != nil {
+		return err
+	}
+
+	tag := ""
+	if len(args) == 1 {
+		tag = args[0]
+	}
+	if _, err := installGEProton(s, tag, stateMgr.CacheDir()); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Restart Steam to see it in the compatibility tool list.")
+	return nil
+}
+
+func runProtonRemove(cmd *cobra.Command, args []string) error {
+	stateMgr, err := state.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	s, err := selectSteam(stateMgr, false)
+	if err != nil {
+		return err
+	}
+
+	tool, err := s.FindCompatTool(args[0])
+	if err != nil {
+		return err
+	}
+	if tool == nil {
+		return fmt.Errorf("%s is not a custom compatibility tool; official Proton versions are uninstalled through Steam", args[0])
+	}
+
+	if !confirm(fmt.Sprintf("Remove %s?", tool.Label()), "Deletes "+filepath.Dir(tool.ManifestPath)+".") {
+		return fmt.Errorf("remove cancelled")
+	}
+
+	if err := proton.RemoveCompatTool(s, tool); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Removed %s\n", tool.Label())
+	return nil
+}
+
+// protonReleaseSource returns the configured GE-Proton release source.
+func protonReleaseSource(cacheDir string) (release.Source, error) {
+	src, err := release.Open(protonSource, protonRepo, release.CacheDir(cacheDir, proton.ReleaseCacheName))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Proton source: %w", err)
+	}
+	return src, nil
+}
+
+// installGEProton installs a GE-Proton release, or the latest if tag is
+// empty, into Steam's compatibilitytools.d. Returns the tool's path.
+func installGEProton(s *steam.Steam, tag string, cacheDir string) (string, error) {
+	src, err := protonReleaseSource(cacheDir)
+	if err != nil {
+		return "", err
+	}
+
+	var rel *release.Release
+	if tag == "" {
+		rel, err = src.Latest()
+	} else {
+		rel, err = src.Get(tag)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find GE-Proton release: %w", err)
+	}
+
+	fmt.Printf("📥 Installing %s...\n", rel.TagName)
+	toolPath, err := proton.InstallCompatTool(rel, s.UserCompatToolsPath(), filepath.Join(cacheDir, "proton"), true)
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", rel.TagName, err)
+	}
+	fmt.Printf("   ✓ Checksum verified, installed to: %s\n", toolPath)
+	return toolPath, nil
+}
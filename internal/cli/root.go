@@ -1,9 +1,14 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/huh"
@@ -12,19 +17,44 @@ import (
 
 	"github.com/jslay88/zladxhd-installer/internal/archive"
 	"github.com/jslay88/zladxhd-installer/internal/backup"
+	"github.com/jslay88/zladxhd-installer/internal/dotnet"
 	"github.com/jslay88/zladxhd-installer/internal/patcher"
 	"github.com/jslay88/zladxhd-installer/internal/proton"
 	"github.com/jslay88/zladxhd-installer/internal/protontricks"
+	"github.com/jslay88/zladxhd-installer/internal/release"
+	"github.com/jslay88/zladxhd-installer/internal/runner"
 	"github.com/jslay88/zladxhd-installer/internal/state"
 	"github.com/jslay88/zladxhd-installer/internal/steam"
 )
 
+// shortcutName is the name of the game's shortcut in Steam.
+const shortcutName = "Zelda: Link's Awakening DX HD"
+
+// Version is the installer's version, set by main.
+var Version = "dev"
+
 var (
 	archivePath string
 	installDir  string
+	libraryName string
+	steamName   string
 	protonName  string
 	noBackup    bool
 	forceBackup bool
+	backupMode  string
+	backupDir   string
+
+	restartSteam bool
+	openGame     string
+
+	patcherSource string
+	patcherRepo   string
+	protonSource  string
+	protonRepo    string
+	patchEngine   string
+	runnerName    string
+	dotnetMethod  string
+	forceVerbs    bool
 )
 
 var rootCmd = &cobra.Command{
@@ -41,17 +71,62 @@ This tool will:
 - Install required .NET runtime
 - Download and run the HD patcher`,
 	RunE: runInstall,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if libraryName != "" && installDir != "" {
+			return fmt.Errorf("--library and --install-dir can't be used together")
+		}
+		switch backupMode {
+		case "minimal", "full":
+		default:
+			return fmt.Errorf("invalid --backup-mode %q: must be minimal or full", backupMode)
+		}
+		switch openGame {
+		case "", "library", "play":
+		default:
+			return fmt.Errorf("invalid --open %q: must be library or play", openGame)
+		}
+		if openGame != "" && !restartSteam {
+			return fmt.Errorf("--open requires --restart-steam")
+		}
+		switch patchEngine {
+		case "auto", "native", "wine":
+		default:
+			return fmt.Errorf("invalid --patch-engine %q: must be auto, native, or wine", patchEngine)
+		}
+		switch dotnetMethod {
+		case "auto", "native", "winetricks":
+		default:
+			return fmt.Errorf("invalid --dotnet %q: must be auto, native, or winetricks", dotnetMethod)
+		}
+		_, err := runner.ParseKind(runnerName)
+		return err
+	},
 }
 
 func init() {
 	rootCmd.Flags().StringVarP(&archivePath, "archive", "a", "", "Path or URL to game archive (uses cache if not provided)")
-	rootCmd.Flags().StringVarP(&installDir, "install-dir", "d", "", "Installation directory (default: ~/.local/share/Steam/steamapps/common/ZLADXHD)")
-	rootCmd.Flags().StringVarP(&protonName, "proton", "p", "Proton 10.0", "Proton version to use")
-	rootCmd.Flags().BoolVar(&noBackup, "no-backup", false, "Skip Steam backup prompt")
-	rootCmd.Flags().BoolVar(&forceBackup, "backup", false, "Force Steam backup without prompt")
+	rootCmd.PersistentFlags().StringVarP(&installDir, "install-dir", "d", "", "Installation directory (default: ~/.local/share/Steam/steamapps/common/ZLADXHD)")
+	rootCmd.PersistentFlags().StringVar(&steamName, "steam", "", "Steam installation to use: native, flatpak, snap or its path (default: ask if there are several)")
+	rootCmd.Flags().StringVar(&libraryName, "library", "", "Steam library to install the game to: path, label or index (default: ask if there are several)")
+	rootCmd.Flags().StringVarP(&protonName, "proton", "p", "", "Proton version to use (default: the recommended version)")
+	rootCmd.Flags().BoolVar(&noBackup, "no-backup", false, "Don't back up anything in the Steam directory")
+	rootCmd.Flags().BoolVar(&forceBackup, "backup", false, "Same as --backup-mode full")
+	rootCmd.Flags().StringVar(&backupMode, "backup-mode", "minimal", "Steam backup: minimal (just the files the installer changes) or full (also a tarball of the Steam directory)")
+	rootCmd.PersistentFlags().StringVar(&backupDir, "backup-dir", "", "Directory for full Steam backups (default: ~/.local/share/zladxhd-installer/backups)")
+	rootCmd.Flags().BoolVar(&restartSteam, "restart-steam", false, "Start Steam again after installing, in the mode it was running in")
+	rootCmd.Flags().StringVar(&openGame, "open", "", "With --restart-steam, open the game: library (its library page) or play (launch it)")
+	rootCmd.PersistentFlags().StringVar(&patcherSource, "patcher-source", "", "Patcher release source: GitHub-compatible API URL, releases JSON, or mirror directory (default: GitHub)")
+	rootCmd.PersistentFlags().StringVar(&patcherRepo, "patcher-repo", patcher.GitHubRepo, "Repository to fetch patcher releases from")
+	rootCmd.PersistentFlags().StringVar(&protonSource, "proton-source", "", "GE-Proton release source: GitHub-compatible API URL, releases JSON, or mirror directory (default: GitHub)")
+	rootCmd.PersistentFlags().StringVar(&protonRepo, "proton-repo", proton.GEProtonRepo, "Repository to fetch GE-Proton releases from")
+	rootCmd.PersistentFlags().StringVar(&patchEngine, "patch-engine", "auto", "Patch engine: auto (native with Wine fallback), native, or wine")
+	rootCmd.PersistentFlags().StringVar(&runnerName, "runner", "auto", "Wine runner: auto, protontricks, proton, or umu")
+	rootCmd.PersistentFlags().BoolVar(&forceVerbs, "force-verbs", false, "Reinstall winetricks verbs and runtimes even if already installed")
+	rootCmd.Flags().StringVar(&dotnetMethod, "dotnet", "auto", ".NET install method: auto (official installer with winetricks fallback), native, or winetricks")
 }
 
 func Execute() error {
+	rootCmd.Version = Version
 	return rootCmd.Execute()
 }
 
@@ -66,14 +141,16 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to initialize state manager: %w", err)
 	}
 
-	// Step 1: Check/Install protontricks
-	fmt.Println("📦 Checking protontricks...")
-	ptInstall, err := ensureProtontricks()
+	recipe, err := prefixRecipe(stateMgr)
+	if err != nil {
+		return err
+	}
+
+	// Step 1: Select the Wine runner (installing protontricks if needed)
+	runnerKind, ptInstall, err := selectRunner()
 	if err != nil {
 		return err
 	}
-	fmt.Printf("   ✓ protontricks %s (%s)\n", ptInstall.Version, ptInstall.Method)
-	fmt.Println()
 
 	// Step 2: Get the game archive
 	fmt.Println("📁 Getting game archive...")
@@ -86,11 +163,11 @@ func runInstall(cmd *cobra.Command, args []string) error {
 
 	// Step 3: Discover Steam
 	fmt.Println("🔍 Discovering Steam installation...")
-	steamInstall, err := steam.Discover()
+	steamInstall, err := selectSteam(stateMgr, true)
 	if err != nil {
-		return fmt.Errorf("failed to find Steam: %w", err)
+		return err
 	}
-	fmt.Printf("   ✓ Found Steam at: %s\n", steamInstall.Path)
+	fmt.Printf("   ✓ Found Steam at: %s\n", steamInstall.Label())
 	fmt.Println()
 
 	// Step 4: Select Steam user
@@ -102,16 +179,17 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	fmt.Printf("   ✓ Selected user: %s\n", user.DisplayName())
 	fmt.Println()
 
-	// Step 5: Backup Steam (optional)
-	if err := handleBackup(steamInstall); err != nil {
+	// Step 5: Back up the Steam directory (with --backup-mode full)
+	if err := handleFullBackup(steamInstall, stateMgr); err != nil {
 		return err
 	}
 
 	// Step 6: Kill Steam if running
 	fmt.Println("🛑 Checking Steam process...")
-	if steam.IsRunning() {
-		fmt.Println("   Steam is running. Shutting down...")
-		if err := steam.Kill(); err != nil {
+	steamModeArgs := steamInstall.RunningModeArgs()
+	if steamInstall.IsRunning() {
+		fmt.Println("   Steam is running. Shutting down and waiting for its processes to exit...")
+		if err := steamInstall.Kill(); err != nil {
 			return fmt.Errorf("failed to stop Steam: %w", err)
 		}
 		fmt.Println("   ✓ Steam stopped")
@@ -120,9 +198,24 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
+	// Back up the files the next steps modify, now that Steam won't rewrite
+	// them anymore
+	steamBackup, err := backupSteamFiles(steamInstall, user, stateMgr)
+	if err != nil {
+		return err
+	}
+
 	// Step 7: Extract game
 	fmt.Println("📦 Extracting game archive...")
-	gameDir, err := extractGame(archiveFile, steamInstall, installDir)
+	destDir := installDir
+	if destDir == "" {
+		library, err := selectLibrary(steamInstall)
+		if err != nil {
+			return err
+		}
+		destDir = filepath.Join(library.CommonPath(), "ZLADXHD")
+	}
+	gameDir, err := extractGame(archiveFile, destDir)
 	if err != nil {
 		return err
 	}
@@ -130,14 +223,22 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Step 8: Find game executable
-	exePath, err := findGameExecutable(gameDir)
+	exePath, err := patcher.FindGameExecutable(gameDir)
 	if err != nil {
 		return err
 	}
 
 	// Step 9: Add non-Steam game
 	fmt.Println("🎮 Adding game to Steam...")
-	shortcut := steam.NewShortcut("Zelda: Link's Awakening DX HD", exePath)
+	if steamInstall.NeedsFilesystemAccess(gameDir) {
+		if err := steamInstall.GrantFilesystemAccess(gameDir); err != nil {
+			return err
+		}
+		fmt.Printf("   ✓ Gave %s Steam access to: %s\n", steamInstall.Type, gameDir)
+	}
+	// The shortcut is launched from inside Steam's sandbox, if any
+	shortcut := steam.NewShortcut(shortcutName, steamInstall.SandboxPath(exePath))
+	shortcut.LaunchOptions = recipe.LaunchOptions(shortcut.LaunchOptions)
 	appID, isNew, err := steam.AddShortcut(user, shortcut)
 	if err != nil {
 		return fmt.Errorf("failed to add shortcut: %w", err)
@@ -146,12 +247,21 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		fmt.Printf("   ✓ Added with AppID: %d\n", appID)
 	} else {
 		fmt.Printf("   ✓ Already exists with AppID: %d\n", appID)
+		if appID, err = migrateShortcutAppID(steamInstall, user, steamBackup, shortcut.AppName, appID); err != nil {
+			return err
+		}
+		if err := updateLaunchOptions(user, shortcut.AppName, recipe); err != nil {
+			fmt.Printf("   ⚠ Failed to update launch options: %v\n", err)
+		}
+	}
+	if len(recipe.Env) > 0 {
+		fmt.Printf("   ✓ Launch options: %s\n", recipe.LaunchOptions(""))
 	}
 	fmt.Println()
 
 	// Step 10: Configure Proton
 	fmt.Println("⚙️  Configuring Proton...")
-	protonCfg, err := configureProton(steamInstall, user, appID, protonName)
+	protonCfg, err := configureProton(steamInstall, user, appID, protonName, stateMgr.CacheDir())
 	if err != nil {
 		return err
 	}
@@ -160,6 +270,7 @@ func runInstall(cmd *cobra.Command, args []string) error {
 
 	// Step 11: Initialize Wine prefix
 	fmt.Println("🍷 Initializing Wine prefix...")
+	checkPrefixVersion(protonCfg, stateMgr)
 	fmt.Println("   This may take a minute on first run...")
 
 	// Show spinner while initializing (suppress Wine debug output)
@@ -183,6 +294,7 @@ func runInstall(cmd *cobra.Command, args []string) error {
 				return fmt.Errorf("failed to initialize Wine prefix: %w", err)
 			}
 			fmt.Printf("   ✓ Prefix initialized at: %s\n", protonCfg.PrefixPath())
+			recordProton(protonCfg, stateMgr)
 			goto prefixComplete
 		default:
 			_ = prefixSpinner.Add(1)
@@ -192,97 +304,56 @@ func runInstall(cmd *cobra.Command, args []string) error {
 prefixComplete:
 	fmt.Println()
 
-	// Step 12: Install .NET runtime
-	fmt.Println("📦 Installing .NET Desktop Runtime 6...")
-	fmt.Println("   This may take a few minutes...")
-	ptRunner := protontricks.NewRunner(ptInstall)
-
-	// Show spinner while installing .NET (suppress Wine debug output)
-	dotnetSpinner := progressbar.NewOptions(-1,
-		progressbar.OptionSetDescription("   Installing"),
-		progressbar.OptionSetWriter(os.Stderr),
-		progressbar.OptionSpinnerType(14),
-	)
-	dotnetDone := make(chan error, 1)
-	go func() {
-		dotnetDone <- ptRunner.InstallDotNetDesktop6(appID, true)
-	}()
+	// Step 12: Install verbs and apply prefix settings from the recipe
+	wineRunner, err := runner.New(runnerKind, runnerPrefix(protonCfg), ptInstall)
+	if err != nil {
+		return err
+	}
 
-	// Animate spinner while waiting
-	for {
-		select {
-		case err := <-dotnetDone:
-			_ = dotnetSpinner.Finish()
-			fmt.Fprint(os.Stderr, "\r\033[K") // Clear spinner line
-			if err != nil {
-				return fmt.Errorf("failed to install .NET: %w", err)
-			}
-			fmt.Println("   ✓ .NET Desktop Runtime 6 installed")
-			goto dotnetComplete
-		default:
-			_ = dotnetSpinner.Add(1)
-			time.Sleep(100 * time.Millisecond)
-		}
+	if err := setupPrefix(wineRunner, protonCfg, recipe, stateMgr.CacheDir()); err != nil {
+		return err
 	}
-dotnetComplete:
-	fmt.Println()
 
 	// Step 13: Download patcher
 	fmt.Println("⬇️  Downloading HD patcher...")
-	p := patcher.NewPatcher(gameDir, stateMgr.CacheDir())
+	p, err := newPatcher(gameDir, stateMgr)
+	if err != nil {
+		return err
+	}
 	if err := p.Download(true); err != nil {
 		return fmt.Errorf("failed to download patcher: %w", err)
 	}
 	fmt.Printf("   ✓ Patcher ready: %s\n", filepath.Base(p.PatcherPath))
 	fmt.Println()
 
-	// Step 14: Run patcher
-	fmt.Println("🔧 Running HD patcher...")
-
-	// Show spinner while patcher runs (suppress Wine debug output)
-	patcherSpinner := progressbar.NewOptions(-1,
-		progressbar.OptionSetDescription("   Running patcher"),
-		progressbar.OptionSetWriter(os.Stderr),
-		progressbar.OptionSpinnerType(14),
-	)
-	patcherDone := make(chan error, 1)
-	go func() {
-		patcherDone <- p.Run(ptRunner, appID, true)
-	}()
-
-	// Animate spinner while waiting
-	var patcherErr error
-	for {
-		select {
-		case err := <-patcherDone:
-			_ = patcherSpinner.Finish()
-			fmt.Fprint(os.Stderr, "\r\033[K") // Clear spinner line
-			patcherErr = err
-			goto patcherComplete
-		default:
-			_ = patcherSpinner.Add(1)
-			time.Sleep(100 * time.Millisecond)
-		}
-	}
-patcherComplete:
-	if patcherErr != nil {
-		fmt.Printf("   ⚠ Patcher may have exited with error: %v\n", patcherErr)
-		fmt.Println("   You can try running it manually later.")
-	} else {
-		fmt.Println("   ✓ Patcher completed")
-	}
-	fmt.Println()
-
-	// Save config for next time
+	// Save config for next time, so `patch reapply` works even if patching fails
 	_ = stateMgr.UpdateConfig(func(cfg *state.Config) {
 		cfg.LastInstallDir = gameDir
 		cfg.LastProton = protonCfg.ProtonName
 		cfg.LastSteamUser = user.ID
+		cfg.LastSteamPath = steamInstall.Path
+		cfg.LastAppID = appID
 	})
 
+	// Step 14: Snapshot the game and run the patcher
+	if err := applyPatch(p, wineRunner, stateMgr); err != nil {
+		fmt.Println("❌ Installation incomplete: the game is set up in Steam but isn't patched.")
+		fmt.Println("   Retry with `zladxhd-installer patch reapply --version <tag>`.")
+		return err
+	}
+
 	// Done!
 	fmt.Println("✅ Installation complete!")
 	fmt.Println()
+
+	if restartSteam {
+		if err := relaunchSteam(steamInstall, steamModeArgs, appID); err != nil {
+			fmt.Printf("   ⚠ %v\n", err)
+		} else {
+			return nil
+		}
+	}
+
 	fmt.Println("You can now:")
 	fmt.Println("  1. Start Steam")
 	fmt.Println("  2. Find 'Zelda: Link's Awakening DX HD' in your library")
@@ -292,6 +363,287 @@ patcherComplete:
 	return nil
 }
 
+// relaunchSteam starts Steam with the mode args it was running with and,
+// with --open, the URL opening the game's library page or launching it.
+func relaunchSteam(s *steam.Steam, modeArgs []string, appID uint32) error {
+	args := append([]string{}, modeArgs...)
+	gameID := steam.ShortcutGameID(appID)
+	switch openGame {
+	case "library":
+		args = append(args, steam.LibraryURL(gameID))
+	case "play":
+		args = append(args, steam.RunGameURL(gameID))
+	}
+
+	fmt.Println("🚀 Starting Steam...")
+	if err := s.Launch(args...); err != nil {
+		return err
+	}
+	fmt.Printf("   ✓ Started %s Steam", s.Type)
+	if len(modeArgs) > 0 {
+		fmt.Printf(" with %s", strings.Join(modeArgs, " "))
+	}
+	fmt.Println()
+	fmt.Println()
+	return nil
+}
+
+// newPatcher creates a patcher using the configured release source.
+func newPatcher(gameDir string, stateMgr *state.Manager) (*patcher.Patcher, error) {
+	p := patcher.NewPatcher(gameDir, stateMgr.CacheDir())
+
+	src, err := release.Open(patcherSource, patcherRepo, release.CacheDir(stateMgr.CacheDir(), patcher.ReleaseCacheName))
+	if err != nil {
+		return nil, fmt.Errorf("invalid patcher source: %w", err)
+	}
+	p.Source = src
+
+	return p, nil
+}
+
+// prefixRecipe returns the game's prefix recipe with the user's config overrides applied.
+func prefixRecipe(stateMgr *state.Manager) (*proton.Recipe, error) {
+	recipe := proton.DefaultRecipe().Merge(stateMgr.Config().Prefix)
+	if err := recipe.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid prefix overrides in config: %w", err)
+	}
+	return recipe, nil
+}
+
+// migrateShortcutAppID offers to move a shortcut added with a random AppID by
+// an older version to the AppID Steam derives for it. Returns the shortcut's
+// AppID afterwards. A failed move is undone by proton.MigrateAppID and
+// returned as an error, since later steps would otherwise use the wrong AppID.
+// The prefix move is recorded in b, if set, so restore-last can undo it.
+func migrateShortcutAppID(s *steam.Steam, user *steam.User, b *backup.FileBackup, appName string, appID uint32) (uint32, error) {
+	existing, err := steam.FindShortcutByName(user, appName)
+	if err != nil || existing == nil || existing.HasSteamAppID() {
+		return appID, nil
+	}
+
+	newID := steam.ShortcutAppID(existing.Exe, existing.AppName)
+	if !confirm(fmt.Sprintf("Change the shortcut's AppID to Steam's %d?", newID),
+		"It has a random AppID from an older installer. Artwork and steam:// links expect Steam's; the Wine prefix and Proton setting are moved along.") {
+		return appID, nil
+	}
+
+	if err := proton.MigrateAppID(s, user, appID, newID); err != nil {
+		return appID, fmt.Errorf("failed to change AppID from %d to %d: %w", appID, newID, err)
+	}
+	fmt.Printf("   ✓ Changed AppID from %d to %d\n", appID, newID)
+
+	// The prefix was moved rather than backed up, so record the move for
+	// restore-last to undo
+	oldCompatData := filepath.Join(s.CompatPath, strconv.FormatUint(uint64(appID), 10))
+	newCompatData := filepath.Join(s.CompatPath, strconv.FormatUint(uint64(newID), 10))
+	if b != nil && archive.FileExists(newCompatData) && !archive.FileExists(oldCompatData) {
+		if err := b.RecordRename(oldCompatData, newCompatData); err != nil {
+			fmt.Printf("   ⚠️  Couldn't record the prefix move in the backup: %v\n", err)
+		}
+	}
+	return newID, nil
+}
+
+// updateLaunchOptions sets the recipe's env vars on an existing shortcut,
+// keeping any launch options the user added.
+func updateLaunchOptions(user *steam.User, appName string, recipe *proton.Recipe) error {
+	existing, err := steam.FindShortcutByName(user, appName)
+	if err != nil || existing == nil {
+		return err
+	}
+
+	launchOptions := recipe.LaunchOptions(existing.LaunchOptions)
+	if launchOptions == existing.LaunchOptions {
+		return nil
+	}
+	existing.LaunchOptions = launchOptions
+	return steam.UpdateShortcut(user, existing)
+}
+
+// setupPrefix installs the recipe's verbs through r, then waits for the
+// prefix's wineserver to exit and writes the recipe's Windows version, DLL
+// overrides and registry tweaks to the prefix.
+func setupPrefix(r runner.Runner, cfg *proton.Config, recipe *proton.Recipe, cacheDir string) error {
+	for _, verb := range recipe.Verbs {
+		if verb == "dotnetdesktop6" {
+			fmt.Println("📦 Installing .NET Desktop Runtime 6...")
+			fmt.Println("   This may take a few minutes...")
+			if err := installDotNet(r, cfg.PrefixPath(), cacheDir); err != nil {
+				return fmt.Errorf("failed to install .NET: %w", err)
+			}
+			fmt.Println()
+			continue
+		}
+
+		fmt.Printf("📦 Installing %s...\n", verb)
+		if !forceVerbs && cfg.VerbInstalled(verb) {
+			fmt.Printf("   ✓ %s already installed\n", verb)
+			fmt.Println()
+			continue
+		}
+		err := runWithSpinner("   Installing", func() error {
+			return r.InstallVerb(verb, runner.VerbOptions{Quiet: true, SuppressOutput: true, Force: forceVerbs})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to install %s: %w", verb, err)
+		}
+		fmt.Printf("   ✓ %s installed\n", verb)
+		fmt.Println()
+	}
+
+	if recipe.WindowsVersion == "" && len(recipe.DLLOverrides) == 0 && len(recipe.Registry) == 0 {
+		return nil
+	}
+
+	fmt.Println("🔧 Applying prefix settings...")
+	// A wineserver still running from the steps above would write its
+	// registry over the edits when it exits
+	if err := r.WaitForWineserver(); err != nil {
+		return err
+	}
+	if err := cfg.ApplyRecipe(recipe); err != nil {
+		return fmt.Errorf("failed to apply prefix settings: %w", err)
+	}
+	if recipe.WindowsVersion != "" {
+		fmt.Printf("   ✓ Windows version: %s\n", recipe.WindowsVersion)
+	}
+	for _, dll := range slices.Sorted(maps.Keys(recipe.DLLOverrides)) {
+		fmt.Printf("   ✓ DLL override: %s=%s\n", dll, recipe.DLLOverrides[dll])
+	}
+	if len(recipe.Registry) > 0 {
+		fmt.Printf("   ✓ %d registry tweak(s) applied\n", len(recipe.Registry))
+	}
+	fmt.Println()
+	return nil
+}
+
+// installDotNet installs the .NET Desktop Runtime 6 with the method selected
+// by --dotnet. In auto mode the official installer is tried first and the
+// winetricks verb is used if it fails.
+func installDotNet(r runner.Runner, prefixPath string, cacheDir string) error {
+	if !forceVerbs && proton.VerbInstalled(prefixPath, "dotnetdesktop6") {
+		fmt.Println("   ✓ .NET Desktop Runtime 6 already installed")
+		return nil
+	}
+
+	if dotnetMethod != "winetricks" {
+		installer := dotnet.NewInstaller(cacheDir)
+		installer.Force = forceVerbs
+		_, err := installer.Download(true)
+		if err == nil {
+			err = runWithSpinner("   Installing", func() error {
+				return installer.Install(r, prefixPath, false, true)
+			})
+		}
+		if err == nil {
+			fmt.Printf("   ✓ .NET Desktop Runtime %s installed\n", installer.Version)
+			return nil
+		}
+		// Metadata disagreeing with the pinned checksum may have been
+		// tampered with, so don't paper over it with winetricks
+		if dotnetMethod == "native" || errors.Is(err, dotnet.ErrChecksumMismatch) {
+			return err
+		}
+		fmt.Printf("   ⚠ Official installer failed: %v\n", err)
+		fmt.Println("   Falling back to winetricks")
+	}
+
+	// Suppress Wine debug output so it doesn't garble the spinner
+	err := runWithSpinner("   Installing", func() error {
+		return runner.InstallDotNetDesktop6(r, true, forceVerbs)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("   ✓ .NET Desktop Runtime 6 installed")
+	return nil
+}
+
+// selectRunner resolves --runner, auto-detecting a backend if needed.
+// protontricks is installed when it is the selected backend and missing.
+func selectRunner() (runner.Kind, *protontricks.Installation, error) {
+	fmt.Println("📦 Checking Wine runner...")
+
+	kind, err := runner.ParseKind(runnerName)
+	if err != nil {
+		return "", nil, err
+	}
+	if kind == runner.KindAuto {
+		kind = runner.Detect()
+	}
+
+	var ptInstall *protontricks.Installation
+	if kind == runner.KindProtontricks {
+		ptInstall, err = ensureProtontricks()
+		if err != nil {
+			return "", nil, err
+		}
+		fmt.Printf("   ✓ protontricks %s (%s)\n", ptInstall.Version, ptInstall.Method)
+	} else {
+		fmt.Printf("   ✓ Using %s runner\n", kind)
+	}
+	fmt.Println()
+
+	return kind, ptInstall, nil
+}
+
+// checkPrefixVersion reports how the selected Proton relates to the version
+// that created an existing prefix. Before a downgrade, which Proton does not
+// support, it warns and offers to snapshot the prefix.
+func checkPrefixVersion(cfg *proton.Config, stateMgr *state.Manager) {
+	if !cfg.HasPrefix() {
+		return
+	}
+
+	check, err := cfg.CheckVersion()
+	if err != nil {
+		fmt.Printf("   ⚠ Could not compare Proton versions: %v\n", err)
+		return
+	}
+
+	switch check.Change {
+	case proton.VersionUpgrade:
+		fmt.Printf("   Upgrading prefix from %s to %s\n", check.PrefixVersion, check.ProtonVersion)
+	case proton.VersionDowngrade:
+		fmt.Printf("   ⚠ The prefix was created by a newer Proton (%s) than %s (%s).\n",
+			check.PrefixVersion, cfg.ProtonName, check.ProtonVersion)
+		fmt.Println("   Proton does not support downgrades, so the game may stop working.")
+		fmt.Println("   If it does, run 'zladxhd-installer prefix reset'.")
+		if !confirm("Snapshot the prefix before downgrading?", "Restore it later with 'zladxhd-installer prefix restore'.") {
+			return
+		}
+		var snap *proton.PrefixSnapshot
+		err := runWithSpinner("   Snapshotting", func() error {
+			var err error
+			snap, err = cfg.Snapshot(stateMgr.PrefixSnapshotDir())
+			return err
+		})
+		if err != nil {
+			fmt.Printf("   ⚠ Failed to snapshot prefix: %v\n", err)
+			return
+		}
+		fmt.Printf("   ✓ Snapshot saved: %s\n", snap.Path)
+	}
+}
+
+// recordProton records the Proton used for the install and the prefix version it left.
+func recordProton(cfg *proton.Config, stateMgr *state.Manager) {
+	st := stateMgr.EnsureState()
+	st.ProtonName = cfg.ProtonName
+	st.ProtonVersion, _ = cfg.ProtonVersion()
+	_ = stateMgr.SaveState()
+}
+
+// runnerPrefix returns the runner prefix for a Proton configuration.
+func runnerPrefix(cfg *proton.Config) runner.Prefix {
+	return runner.Prefix{
+		AppID:          cfg.AppID,
+		SteamPath:      cfg.Steam.Path,
+		CompatDataPath: cfg.CompatDataPath(),
+		ProtonPath:     cfg.ProtonPath,
+	}
+}
+
 func ensureProtontricks() (*protontricks.Installation, error) {
 	install, err := protontricks.Detect()
 	if err == nil {
@@ -465,36 +817,19 @@ func selectSteamUser(s *steam.Steam, stateMgr *state.Manager) (*steam.User, erro
 	return nil, fmt.Errorf("user not found")
 }
 
-func handleBackup(s *steam.Steam) error {
-	if noBackup {
-		return nil
-	}
-
-	doBackup := forceBackup
-	if !forceBackup {
-		form := huh.NewForm(
-			huh.NewGroup(
-				huh.NewConfirm().
-					Title("Create a backup of Steam directory before making changes?").
-					Description("This will backup ~/.local/share/Steam (excluding steamapps)").
-					Value(&doBackup),
-			),
-		)
-
-		if err := form.Run(); err != nil {
-			return fmt.Errorf("backup prompt cancelled: %w", err)
-		}
-	}
-
-	if !doBackup {
-		fmt.Println("⏭️  Skipping backup")
-		fmt.Println()
+// handleFullBackup creates a tarball of the Steam directory, excluding
+// steamapps, with --backup-mode full.
+func handleFullBackup(s *steam.Steam, stateMgr *state.Manager) error {
+	if noBackup || (backupMode != "full" && !forceBackup) {
 		return nil
 	}
 
 	fmt.Println("💾 Creating Steam backup...")
 	fmt.Println("   (excluding steamapps - this may take a minute)")
 	opts := backup.DefaultOptions(s.Path)
+	opts.OutputDir = backupDirectory(stateMgr)
+	opts.Reason = "install"
+	opts.InstallerVersion = Version
 
 	// Create progress bar for backup
 	bar := progressbar.NewOptions64(
@@ -521,17 +856,155 @@ func handleBackup(s *steam.Steam) error {
 
 	_ = bar.Finish()
 	fmt.Printf("   ✓ Backup created: %s (%s, %d files)\n", result.Path, backup.FormatSize(result.Size), result.FileCount)
+	pruneBackups(stateMgr)
 	fmt.Println()
 	return nil
 }
 
-func extractGame(archivePath string, s *steam.Steam, customDir string) (string, error) {
-	// Determine destination directory
-	destDir := customDir
-	if destDir == "" {
-		destDir = filepath.Join(s.CommonPath(), "ZLADXHD")
+// backupSteamFiles backs up the Steam files the installer is about to modify
+// to the state directory, for `backup restore-last`. Returns nil with
+// --no-backup.
+func backupSteamFiles(s *steam.Steam, user *steam.User, stateMgr *state.Manager) (*backup.FileBackup, error) {
+	if noBackup {
+		return nil, nil
+	}
+
+	fmt.Println("💾 Backing up the Steam files the installer changes...")
+	store := backup.NewFileStore(stateMgr.SteamBackupDir())
+	b, err := store.Create(s.Path, "install", steamFilesToChange(s, user))
+	if err != nil {
+		return nil, fmt.Errorf("backup failed: %w", err)
+	}
+	fmt.Printf("   ✓ Backed up %d files (%s) to: %s\n", len(b.Files), backup.FormatSize(b.Size()), b.Dir())
+	fmt.Println("     Undo the installer's changes with: zladxhd-installer backup restore-last")
+	pruneBackups(stateMgr)
+	fmt.Println()
+	return b, nil
+}
+
+// steamFilesToChange returns the Steam files an install modifies: the user's
+// shortcuts, the compatibility tool mapping and, if the game's shortcut's
+// AppID is going to be migrated, its artwork.
+func steamFilesToChange(s *steam.Steam, user *steam.User) []string {
+	paths := []string{
+		user.ShortcutsPath(),
+		filepath.Join(s.ConfigPath, "config.vdf"),
+	}
+	existing, err := steam.FindShortcutByName(user, shortcutName)
+	if err == nil && existing != nil && !existing.HasSteamAppID() {
+		paths = append(paths, user.GridPath())
+	}
+	return paths
+}
+
+// selectSteam returns the Steam installation given with --steam. If there are
+// several, the one used last is picked, unless ask is set or there is none;
+// then the user is asked.
+func selectSteam(stateMgr *state.Manager, ask bool) (*steam.Steam, error) {
+	installs, err := steam.DiscoverAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Steam: %w", err)
+	}
+
+	if steamName != "" {
+		return steam.FindInstall(installs, steamName)
+	}
+	if len(installs) == 1 {
+		return installs[0], nil
+	}
+
+	selected := 0
+	lastPath := stateMgr.Config().LastSteamPath
+	for i, s := range installs {
+		if s.Path == lastPath {
+			if !ask {
+				return s, nil
+			}
+			selected = i
+		}
+	}
+
+	var options []huh.Option[int]
+	for i, s := range installs {
+		options = append(options, huh.NewOption(s.Label(), i))
 	}
 
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().
+				Title("Select Steam installation").
+				Description("Several Steam installations were found; pass --steam to skip this").
+				Options(options...).
+				Value(&selected),
+		),
+	)
+
+	if err := form.Run(); err != nil {
+		return nil, fmt.Errorf("Steam selection cancelled: %w", err)
+	}
+
+	return installs[selected], nil
+}
+
+// selectLibrary returns the Steam library given with --library, or asks the
+// user to pick one if there are several mounted libraries.
+func selectLibrary(s *steam.Steam) (*steam.Library, error) {
+	libraries, err := s.MountedLibraries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Steam libraries: %w", err)
+	}
+	if len(libraries) == 0 {
+		return nil, fmt.Errorf("no mounted Steam library found")
+	}
+
+	if libraryName != "" {
+		all, err := s.GetLibraries()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Steam libraries: %w", err)
+		}
+		library, err := steam.FindLibrary(all, libraryName)
+		if err != nil {
+			return nil, err
+		}
+		if !library.Mounted {
+			return nil, fmt.Errorf("Steam library %s is not mounted", library.Path)
+		}
+		return library, nil
+	}
+
+	if len(libraries) == 1 {
+		return &libraries[0], nil
+	}
+
+	var options []huh.Option[int]
+	for i, lib := range libraries {
+		label := lib.Path
+		if lib.Label != "" {
+			label += " (" + lib.Label + ")"
+		}
+		label += fmt.Sprintf(" — %s free", backup.FormatSize(int64(lib.FreeSpace)))
+		options = append(options, huh.NewOption(label, i))
+	}
+
+	var selected int
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().
+				Title("Select Steam library").
+				Description("The game is installed to steamapps/common/ZLADXHD in this library").
+				Options(options...).
+				Value(&selected),
+		),
+	)
+
+	if err := form.Run(); err != nil {
+		return nil, fmt.Errorf("library selection cancelled: %w", err)
+	}
+
+	return &libraries[selected], nil
+}
+
+func extractGame(archivePath string, destDir string) (string, error) {
 	// Expand ~ in path
 	if destDir[0] == '~' {
 		home, _ := os.UserHomeDir()
@@ -576,60 +1049,49 @@ func extractGame(archivePath string, s *steam.Steam, customDir string) (string,
 	return destDir, nil
 }
 
-func findGameExecutable(gameDir string) (string, error) {
-	// Look for the main game executable
-	patterns := []string{
-		"Link's Awakening DX HD.exe",
-		"LADXHD.exe",
-		"*.exe",
-	}
-
-	for _, pattern := range patterns {
-		matches, err := filepath.Glob(filepath.Join(gameDir, pattern))
-		if err != nil {
-			continue
+func configureProton(s *steam.Steam, user *steam.User, appID uint32, preferredProton string, cacheDir string) (*proton.Config, error) {
+	// List available versions, newest first
+	versions, err := proton.ListProtonVersions(s)
+	if err == nil && len(versions) == 0 {
+		// Offer GE-Proton if Steam has no Proton installed
+		if !confirm("No Proton versions found. Download the latest GE-Proton?",
+			"It is installed to "+s.UserCompatToolsPath()+".") {
+			return nil, fmt.Errorf("no Proton versions found")
 		}
-		for _, match := range matches {
-			info, err := os.Stat(match)
-			if err != nil || info.IsDir() {
-				continue
-			}
-			return match, nil
+		if _, err := installGEProton(s, "", cacheDir); err != nil {
+			return nil, err
 		}
+		versions, err = proton.ListProtonVersions(s)
 	}
-
-	return "", fmt.Errorf("game executable not found in %s", gameDir)
-}
-
-func configureProton(s *steam.Steam, user *steam.User, appID uint32, preferredProton string) (*proton.Config, error) {
-	// List available versions
-	versions, err := proton.GetAvailableProtonVersions(s)
 	if err != nil || len(versions) == 0 {
 		return nil, fmt.Errorf("no Proton versions found")
 	}
 
-	// Find preferred version index for default selection
-	defaultIdx := 0
-	preferredVersion, findErr := proton.FindProtonByName(s, preferredProton)
-	if findErr == nil {
-		for i, v := range versions {
-			if v == preferredVersion {
-				defaultIdx = i
-				break
-			}
+	recommendation, err := proton.Recommend(versions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pre-select --proton if given, otherwise the recommendation
+	protonVersion := recommendation.Version.Name
+	if preferredProton != "" {
+		if preferredVersion, findErr := proton.FindProtonByName(s, preferredProton); findErr == nil {
+			protonVersion = preferredVersion
+		} else {
+			fmt.Printf("   ⚠ %s is not installed\n", preferredProton)
 		}
 	}
 
-	// Build options with default selected
+	// Build options, marking the recommended and known-bad versions
 	var options []huh.Option[string]
 	for _, v := range versions {
-		options = append(options, huh.NewOption(v, v))
-	}
-
-	// Pre-select the default
-	var protonVersion string
-	if defaultIdx < len(versions) {
-		protonVersion = versions[defaultIdx]
+		label := v.Name
+		if v.Name == recommendation.Version.Name {
+			label += " (recommended)"
+		} else if tested := v.Tested(); tested != nil && tested.Status == proton.KnownBad {
+			label += " (known issues)"
+		}
+		options = append(options, huh.NewOption(label, v.Name))
 	}
 
 	// Always prompt user for Proton selection
@@ -637,7 +1099,7 @@ func configureProton(s *steam.Steam, user *steam.User, appID uint32, preferredPr
 		huh.NewGroup(
 			huh.NewSelect[string]().
 				Title("Select Proton version").
-				Description("Choose which Proton version to use for the game").
+				Description(fmt.Sprintf("Recommended: %s, because %s", recommendation.Version.Name, recommendation.Reason)).
 				Options(options...).
 				Value(&protonVersion),
 		),
@@ -647,15 +1109,55 @@ func configureProton(s *steam.Steam, user *steam.User, appID uint32, preferredPr
 		return nil, fmt.Errorf("proton selection cancelled: %w", err)
 	}
 
+	for _, v := range versions {
+		if tested := v.Tested(); v.Name == protonVersion && tested != nil && tested.Status == proton.KnownBad {
+			fmt.Printf("   ⚠ %s has known issues with the game: %s\n", v.Name, tested.Note)
+		}
+	}
+
 	cfg, err := proton.NewConfig(s, user, appID, protonVersion)
 	if err != nil {
 		return nil, err
 	}
 
-	// Configure compatibility in config.vdf
-	if err := cfg.ConfigureCompatibility(); err != nil {
+	// Configure compatibility in config.vdf. If Steam rewrote the file under
+	// us it is running again, and anything written now would be lost.
+	if err := cfg.ConfigureCompatibility(); errors.Is(err, steam.ErrFileChanged) {
+		fmt.Println("   ✗ Steam changed config.vdf while the installer was running.")
+		fmt.Println("     Close Steam completely and run the installer again.")
+		return nil, fmt.Errorf("failed to configure compatibility: %w", err)
+	} else if errors.Is(err, proton.ErrUnknownCompatTool) {
+		fmt.Printf("   ⚠ Set compatibility tool to %q, but Steam may not recognize it: %v\n", cfg.GetCompatToolName(), err)
+		fmt.Println("     Check the game's Properties > Compatibility in Steam.")
+	} else if err != nil {
 		fmt.Printf("   Warning: failed to configure compatibility: %v\n", err)
 	}
 
 	return cfg, nil
 }
+
+// runWithSpinner runs fn while animating a spinner on stderr.
+// Wine debug output should be suppressed by fn so it doesn't garble the spinner.
+func runWithSpinner(description string, fn func() error) error {
+	spinner := progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription(description),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionSpinnerType(14),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			_ = spinner.Finish()
+			fmt.Fprint(os.Stderr, "\r\033[K") // Clear spinner line
+			return err
+		default:
+			_ = spinner.Add(1)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
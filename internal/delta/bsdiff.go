@@ -0,0 +1,91 @@
+package delta
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// bsdiffHeaderSize is the size of the BSDIFF40 header.
+const bsdiffHeaderSize = 32
+
+// ApplyBSDiff applies a BSDIFF40 patch, as produced by bsdiff 4.x, to source.
+func ApplyBSDiff(source []byte, patch []byte) ([]byte, error) {
+	if len(patch) < bsdiffHeaderSize || !bytes.HasPrefix(patch, bsdiffMagic) {
+		return nil, fmt.Errorf("not a bsdiff patch")
+	}
+
+	ctrlLen := offtin(patch[8:16])
+	diffLen := offtin(patch[16:24])
+	newSize := offtin(patch[24:32])
+	// Check each length on its own so huge values can't overflow the sum
+	remaining := int64(len(patch) - bsdiffHeaderSize)
+	if ctrlLen < 0 || ctrlLen > remaining {
+		return nil, fmt.Errorf("corrupt bsdiff header")
+	}
+	remaining -= ctrlLen
+	if diffLen < 0 || diffLen > remaining || newSize < 0 {
+		return nil, fmt.Errorf("corrupt bsdiff header")
+	}
+	if err := checkTargetSize(newSize); err != nil {
+		return nil, err
+	}
+
+	ctrlStart := int64(bsdiffHeaderSize)
+	diffStart := ctrlStart + ctrlLen
+	extraStart := diffStart + diffLen
+
+	ctrl := bzip2.NewReader(bytes.NewReader(patch[ctrlStart:diffStart]))
+	diff := bzip2.NewReader(bytes.NewReader(patch[diffStart:extraStart]))
+	extra := bzip2.NewReader(bytes.NewReader(patch[extraStart:]))
+
+	out := make([]byte, newSize)
+	var newPos, oldPos int64
+	buf := make([]byte, 8)
+
+	for newPos < newSize {
+		// Each control triple: bytes to add from diff, bytes to copy from extra, seek in source
+		var triple [3]int64
+		for i := range triple {
+			if _, err := io.ReadFull(ctrl, buf); err != nil {
+				return nil, fmt.Errorf("corrupt bsdiff control block: %w", err)
+			}
+			triple[i] = offtin(buf)
+		}
+		addLen, copyLen, seek := triple[0], triple[1], triple[2]
+
+		if addLen < 0 || addLen > newSize-newPos || copyLen < 0 || copyLen > newSize-newPos-addLen {
+			return nil, fmt.Errorf("corrupt bsdiff control data")
+		}
+
+		if _, err := io.ReadFull(diff, out[newPos:newPos+addLen]); err != nil {
+			return nil, fmt.Errorf("corrupt bsdiff diff block: %w", err)
+		}
+		for i := int64(0); i < addLen; i++ {
+			if oldPos+i >= 0 && oldPos+i < int64(len(source)) {
+				out[newPos+i] += source[oldPos+i]
+			}
+		}
+		newPos += addLen
+		oldPos += addLen
+
+		if _, err := io.ReadFull(extra, out[newPos:newPos+copyLen]); err != nil {
+			return nil, fmt.Errorf("corrupt bsdiff extra block: %w", err)
+		}
+		newPos += copyLen
+		oldPos += seek
+	}
+
+	return out, nil
+}
+
+// offtin decodes bsdiff's sign-magnitude little-endian 64-bit integer.
+func offtin(b []byte) int64 {
+	v := int64(binary.LittleEndian.Uint64(b) &^ (1 << 63))
+	if b[7]&0x80 != 0 {
+		return -v
+	}
+	return v
+}
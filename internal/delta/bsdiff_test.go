@@ -0,0 +1,61 @@
+package delta_test
+
+import (
+	"encoding/binary"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/delta"
+)
+
+var _ = Describe("BSDiff", func() {
+	readTestdata := func(name string) []byte {
+		data, err := os.ReadFile("testdata/" + name)
+		Expect(err).NotTo(HaveOccurred())
+		return data
+	}
+
+	It("should apply a bsdiff patch", func() {
+		out, err := delta.ApplyBSDiff(readTestdata("source.bin"), readTestdata("patch.bsdiff"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal(readTestdata("target.bin")))
+	})
+
+	It("should reject a corrupt header", func() {
+		patch := readTestdata("patch.bsdiff")
+		patch[15] = 0x7F
+
+		_, err := delta.ApplyBSDiff(readTestdata("source.bin"), patch)
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("should reject huge block lengths",
+		func(ctrlLen, diffLen uint64) {
+			patch := readTestdata("patch.bsdiff")
+			binary.LittleEndian.PutUint64(patch[8:16], ctrlLen)
+			binary.LittleEndian.PutUint64(patch[16:24], diffLen)
+
+			_, err := delta.ApplyBSDiff(readTestdata("source.bin"), patch)
+			Expect(err).To(MatchError(ContainSubstring("corrupt bsdiff header")))
+		},
+		Entry("huge control block", uint64(1<<63-1), uint64(0)),
+		Entry("huge diff block", uint64(0), uint64(1<<63-1)),
+		Entry("lengths overflowing when added", uint64(1<<62), uint64(1<<62)),
+		Entry("negative control block", uint64(1<<63|1), uint64(0)),
+	)
+
+	It("should reject an oversized output before allocating it", func() {
+		patch := readTestdata("patch.bsdiff")
+		copy(patch[24:32], []byte{0, 0, 0, 0, 0, 1, 0, 0}) // 1 TiB
+
+		_, err := delta.ApplyBSDiff(readTestdata("source.bin"), patch)
+		Expect(err).To(MatchError(ContainSubstring("exceeds")))
+	})
+
+	It("should reject data that isn't a bsdiff patch", func() {
+		_, err := delta.ApplyBSDiff(nil, []byte("not a patch"))
+		Expect(err).To(MatchError(ContainSubstring("not a bsdiff patch")))
+	})
+})
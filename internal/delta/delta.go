@@ -0,0 +1,64 @@
+// Package delta applies binary delta patches in VCDIFF (xdelta3) and
+// bsdiff formats.
+package delta
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Format identifies a delta patch format.
+type Format string
+
+const (
+	FormatVCDIFF  Format = "vcdiff"
+	FormatBSDiff  Format = "bsdiff"
+	FormatUnknown Format = "unknown"
+)
+
+var (
+	vcdiffMagic = []byte{0xD6, 0xC3, 0xC4}
+	bsdiffMagic = []byte("BSDIFF40")
+)
+
+// ErrUnsupported is returned for patches using features this package doesn't implement.
+var ErrUnsupported = errors.New("unsupported delta feature")
+
+// MaxTargetSize caps the output size a patch may declare. Output buffers are
+// allocated from the declared size, so larger patches are rejected before
+// allocating rather than letting a corrupt patch exhaust memory.
+var MaxTargetSize int64 = 1 << 30
+
+// checkTargetSize rejects a declared output size above MaxTargetSize.
+func checkTargetSize(size int64) error {
+	if size > MaxTargetSize {
+		return fmt.Errorf("patch output of %d bytes exceeds the %d byte limit", size, MaxTargetSize)
+	}
+	return nil
+}
+
+// Detect returns the format of a patch based on its magic bytes.
+func Detect(patch []byte) Format {
+	switch {
+	case bytes.HasPrefix(patch, vcdiffMagic):
+		return FormatVCDIFF
+	case bytes.HasPrefix(patch, bsdiffMagic):
+		return FormatBSDiff
+	default:
+		return FormatUnknown
+	}
+}
+
+// Apply applies a patch to source and returns the target data.
+// The patch format is detected automatically.
+func Apply(source []byte, patch []byte) ([]byte, error) {
+	switch Detect(patch) {
+	case FormatVCDIFF:
+		return ApplyVCDIFF(source, patch)
+	case FormatBSDiff:
+		return ApplyBSDiff(source, patch)
+	default:
+		return nil, fmt.Errorf("unrecognized patch format")
+	}
+}
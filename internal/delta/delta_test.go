@@ -0,0 +1,46 @@
+package delta_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/delta"
+)
+
+var _ = Describe("Delta", func() {
+	Describe("Detect", func() {
+		It("should detect VCDIFF patches", func() {
+			Expect(delta.Detect([]byte{0xD6, 0xC3, 0xC4, 0x00, 0x00})).To(Equal(delta.FormatVCDIFF))
+		})
+
+		It("should detect bsdiff patches", func() {
+			Expect(delta.Detect([]byte("BSDIFF40...."))).To(Equal(delta.FormatBSDiff))
+		})
+
+		It("should return unknown for other data", func() {
+			Expect(delta.Detect([]byte("PK\x03\x04"))).To(Equal(delta.FormatUnknown))
+		})
+	})
+
+	Describe("Apply", func() {
+		It("should dispatch on the detected format", func() {
+			source, err := os.ReadFile("testdata/source.bin")
+			Expect(err).NotTo(HaveOccurred())
+			patch, err := os.ReadFile("testdata/patch.bsdiff")
+			Expect(err).NotTo(HaveOccurred())
+			target, err := os.ReadFile("testdata/target.bin")
+			Expect(err).NotTo(HaveOccurred())
+
+			out, err := delta.Apply(source, patch)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(Equal(target))
+		})
+
+		It("should reject unrecognized patches", func() {
+			_, err := delta.Apply([]byte("source"), []byte("garbage"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,396 @@
+package delta
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/adler32"
+)
+
+// VCDIFF header and window indicator bits (RFC 3284).
+const (
+	vcdDecompress = 0x01
+	vcdCodeTable  = 0x02
+	vcdAppHeader  = 0x04 // xdelta3 extension
+
+	vcdSource  = 0x01
+	vcdTarget  = 0x02
+	vcdAdler32 = 0x04 // xdelta3 extension
+)
+
+// Instruction types of the VCDIFF code table.
+const (
+	instNoop = iota
+	instAdd
+	instRun
+	instCopy
+)
+
+// Address cache sizes of the default code table.
+const (
+	nearCacheSize = 4
+	sameCacheSize = 3
+)
+
+var errTruncated = errors.New("truncated VCDIFF data")
+
+// codeEntry is one half of a code table entry.
+type codeEntry struct {
+	inst byte
+	size byte
+	mode byte
+}
+
+// defaultCodeTable is the default instruction code table from RFC 3284 section 5.6.
+var defaultCodeTable = buildDefaultCodeTable()
+
+// buildDefaultCodeTable builds the 256-entry default code table.
+func buildDefaultCodeTable() [256][2]codeEntry {
+	var table [256][2]codeEntry
+	i := 0
+
+	// RUN with explicit size
+	table[i][0] = codeEntry{inst: instRun}
+	i++
+
+	// ADD with explicit size, then sizes 1-17
+	for size := 0; size <= 17; size++ {
+		table[i][0] = codeEntry{inst: instAdd, size: byte(size)}
+		i++
+	}
+
+	// COPY for each mode with explicit size, then sizes 4-18
+	for mode := 0; mode < 2+nearCacheSize+sameCacheSize; mode++ {
+		table[i][0] = codeEntry{inst: instCopy, mode: byte(mode)}
+		i++
+		for size := 4; size <= 18; size++ {
+			table[i][0] = codeEntry{inst: instCopy, size: byte(size), mode: byte(mode)}
+			i++
+		}
+	}
+
+	// ADD + COPY pairs
+	for mode := 0; mode < 2+nearCacheSize; mode++ {
+		for addSize := 1; addSize <= 4; addSize++ {
+			for copySize := 4; copySize <= 6; copySize++ {
+				table[i][0] = codeEntry{inst: instAdd, size: byte(addSize)}
+				table[i][1] = codeEntry{inst: instCopy, size: byte(copySize), mode: byte(mode)}
+				i++
+			}
+		}
+	}
+	for mode := 2 + nearCacheSize; mode < 2+nearCacheSize+sameCacheSize; mode++ {
+		for addSize := 1; addSize <= 4; addSize++ {
+			table[i][0] = codeEntry{inst: instAdd, size: byte(addSize)}
+			table[i][1] = codeEntry{inst: instCopy, size: 4, mode: byte(mode)}
+			i++
+		}
+	}
+
+	// COPY + ADD pairs
+	for mode := 0; mode < 2+nearCacheSize+sameCacheSize; mode++ {
+		table[i][0] = codeEntry{inst: instCopy, size: 4, mode: byte(mode)}
+		table[i][1] = codeEntry{inst: instAdd, size: 1}
+		i++
+	}
+
+	return table
+}
+
+// reader reads VCDIFF primitives from a byte slice.
+type reader struct {
+	data []byte
+	pos  int
+}
+
+// readByte reads a single byte.
+func (r *reader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// readVarint reads a VCDIFF variable-length integer (big-endian base 128).
+func (r *reader) readVarint() (int, error) {
+	var v uint64
+	for i := 0; i < 10; i++ {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<7 | uint64(b&0x7F)
+		if b&0x80 == 0 {
+			if v > 1<<40 {
+				return 0, fmt.Errorf("VCDIFF integer too large")
+			}
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid VCDIFF integer")
+}
+
+// readBytes reads n bytes.
+func (r *reader) readBytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errTruncated
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// addressCache implements the VCDIFF near/same address caches.
+type addressCache struct {
+	near     [nearCacheSize]int
+	nextSlot int
+	same     [sameCacheSize * 256]int
+}
+
+// update records a decoded address in the caches.
+func (c *addressCache) update(addr int) {
+	c.near[c.nextSlot] = addr
+	c.nextSlot = (c.nextSlot + 1) % nearCacheSize
+	c.same[addr%(sameCacheSize*256)] = addr
+}
+
+// decode reads a COPY address in the given mode.
+func (c *addressCache) decode(addrs *reader, here int, mode byte) (int, error) {
+	var addr int
+	switch {
+	case mode == 0: // VCD_SELF
+		v, err := addrs.readVarint()
+		if err != nil {
+			return 0, err
+		}
+		addr = v
+	case mode == 1: // VCD_HERE
+		v, err := addrs.readVarint()
+		if err != nil {
+			return 0, err
+		}
+		addr = here - v
+	case int(mode) < 2+nearCacheSize:
+		v, err := addrs.readVarint()
+		if err != nil {
+			return 0, err
+		}
+		addr = c.near[mode-2] + v
+	default:
+		b, err := addrs.readByte()
+		if err != nil {
+			return 0, err
+		}
+		addr = c.same[int(mode-2-nearCacheSize)*256+int(b)]
+	}
+
+	if addr < 0 || addr >= here {
+		return 0, fmt.Errorf("invalid VCDIFF copy address %d", addr)
+	}
+
+	c.update(addr)
+	return addr, nil
+}
+
+// ApplyVCDIFF applies a VCDIFF (RFC 3284) patch, as produced by xdelta3, to source.
+// Secondary compression and custom code tables are not supported; create
+// patches with "xdelta3 -S none" to avoid them.
+func ApplyVCDIFF(source []byte, patch []byte) ([]byte, error) {
+	r := &reader{data: patch}
+
+	magic, err := r.readBytes(4)
+	if err != nil || magic[0] != 0xD6 || magic[1] != 0xC3 || magic[2] != 0xC4 {
+		return nil, fmt.Errorf("not a VCDIFF patch")
+	}
+	if magic[3] != 0 {
+		return nil, fmt.Errorf("%w: VCDIFF version %d", ErrUnsupported, magic[3])
+	}
+
+	hdrIndicator, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if hdrIndicator&vcdDecompress != 0 {
+		return nil, fmt.Errorf("%w: secondary compression", ErrUnsupported)
+	}
+	if hdrIndicator&vcdCodeTable != 0 {
+		return nil, fmt.Errorf("%w: custom code table", ErrUnsupported)
+	}
+	if hdrIndicator&vcdAppHeader != 0 {
+		n, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.readBytes(n); err != nil {
+			return nil, err
+		}
+	}
+
+	var target []byte
+	for r.pos < len(r.data) {
+		window, err := decodeWindow(r, source, target)
+		if err != nil {
+			return nil, err
+		}
+		target = append(target, window...)
+	}
+
+	return target, nil
+}
+
+// decodeWindow decodes a single VCDIFF window.
+func decodeWindow(r *reader, source []byte, target []byte) ([]byte, error) {
+	winIndicator, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	var segment []byte
+	if winIndicator&(vcdSource|vcdTarget) != 0 {
+		size, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		pos, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+
+		from := source
+		if winIndicator&vcdTarget != 0 {
+			from = target
+		}
+		if pos+size > len(from) {
+			return nil, fmt.Errorf("VCDIFF source segment out of range")
+		}
+		segment = from[pos : pos+size]
+	}
+
+	if _, err := r.readVarint(); err != nil { // length of the delta encoding
+		return nil, err
+	}
+	targetLen, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTargetSize(int64(len(target)) + int64(targetLen)); err != nil {
+		return nil, err
+	}
+	deltaIndicator, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if deltaIndicator != 0 {
+		return nil, fmt.Errorf("%w: compressed sections", ErrUnsupported)
+	}
+
+	dataLen, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	instLen, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	addrLen, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+
+	var checksum uint32
+	hasChecksum := winIndicator&vcdAdler32 != 0
+	if hasChecksum {
+		b, err := r.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		checksum = binary.BigEndian.Uint32(b)
+	}
+
+	dataSection, err := r.readBytes(dataLen)
+	if err != nil {
+		return nil, err
+	}
+	instSection, err := r.readBytes(instLen)
+	if err != nil {
+		return nil, err
+	}
+	addrSection, err := r.readBytes(addrLen)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &reader{data: dataSection}
+	insts := &reader{data: instSection}
+	addrs := &reader{data: addrSection}
+	cache := &addressCache{}
+
+	out := make([]byte, 0, targetLen)
+	for insts.pos < len(insts.data) {
+		code, err := insts.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range defaultCodeTable[code] {
+			if entry.inst == instNoop {
+				continue
+			}
+
+			size := int(entry.size)
+			if size == 0 {
+				if size, err = insts.readVarint(); err != nil {
+					return nil, err
+				}
+			}
+			if len(out)+size > targetLen {
+				return nil, fmt.Errorf("VCDIFF window exceeds target size")
+			}
+
+			switch entry.inst {
+			case instAdd:
+				b, err := data.readBytes(size)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, b...)
+
+			case instRun:
+				b, err := data.readByte()
+				if err != nil {
+					return nil, err
+				}
+				for i := 0; i < size; i++ {
+					out = append(out, b)
+				}
+
+			case instCopy:
+				here := len(segment) + len(out)
+				addr, err := cache.decode(addrs, here, entry.mode)
+				if err != nil {
+					return nil, err
+				}
+				// Copies may overlap the output being produced, so go byte by byte
+				for i := 0; i < size; i++ {
+					pos := addr + i
+					if pos < len(segment) {
+						out = append(out, segment[pos])
+					} else {
+						out = append(out, out[pos-len(segment)])
+					}
+				}
+			}
+		}
+	}
+
+	if len(out) != targetLen {
+		return nil, fmt.Errorf("VCDIFF window size mismatch: expected %d, got %d", targetLen, len(out))
+	}
+	if hasChecksum && adler32.Checksum(out) != checksum {
+		return nil, fmt.Errorf("VCDIFF window checksum mismatch")
+	}
+
+	return out, nil
+}
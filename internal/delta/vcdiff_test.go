@@ -0,0 +1,108 @@
+package delta_test
+
+import (
+	"encoding/binary"
+	"hash/adler32"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/delta"
+)
+
+// vcdiffWindow builds a single-window VCDIFF patch with the default code table.
+func vcdiffWindow(winIndicator byte, segment []byte, target string, data, inst, addr []byte) []byte {
+	patch := []byte{0xD6, 0xC3, 0xC4, 0x00, 0x00, winIndicator}
+	patch = append(patch, segment...)
+
+	body := []byte{byte(len(target)), 0x00, byte(len(data)), byte(len(inst)), byte(len(addr))}
+	if winIndicator&0x04 != 0 {
+		body = binary.BigEndian.AppendUint32(body, adler32.Checksum([]byte(target)))
+	}
+	body = append(body, data...)
+	body = append(body, inst...)
+	body = append(body, addr...)
+
+	patch = append(patch, byte(len(body)))
+	return append(patch, body...)
+}
+
+var _ = Describe("VCDIFF", func() {
+	source := []byte("hello world")
+	target := "hello there world!!!"
+
+	// COPY 6 @0, ADD "there ", COPY 5 @6, RUN 3 "!"
+	data := []byte("there !")
+	inst := []byte{19, 6, 1, 6, 19, 5, 0, 3}
+	addr := []byte{0, 6}
+
+	It("should apply a patch against the source", func() {
+		patch := vcdiffWindow(0x01, []byte{11, 0}, target, data, inst, addr)
+
+		out, err := delta.ApplyVCDIFF(source, patch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal(target))
+	})
+
+	It("should verify the xdelta3 Adler-32 window checksum", func() {
+		patch := vcdiffWindow(0x05, []byte{11, 0}, target, data, inst, addr)
+
+		out, err := delta.ApplyVCDIFF(source, patch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal(target))
+
+		_, err = delta.ApplyVCDIFF([]byte("HELLO WORLD"), patch)
+		Expect(err).To(MatchError(ContainSubstring("checksum mismatch")))
+	})
+
+	It("should handle copies overlapping the output", func() {
+		// ADD "ab", COPY 6 @0
+		patch := vcdiffWindow(0x00, nil, "abababab", []byte("ab"), []byte{1, 2, 19, 6}, []byte{0})
+
+		out, err := delta.ApplyVCDIFF(nil, patch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal("abababab"))
+	})
+
+	It("should skip the xdelta3 application header", func() {
+		patch := vcdiffWindow(0x01, []byte{11, 0}, target, data, inst, addr)
+		withHeader := append([]byte{0xD6, 0xC3, 0xC4, 0x00, 0x04, 3, 'a', '/', 'b'}, patch[5:]...)
+
+		out, err := delta.ApplyVCDIFF(source, withHeader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal(target))
+	})
+
+	It("should reject secondary compression", func() {
+		patch := vcdiffWindow(0x01, []byte{11, 0}, target, data, inst, addr)
+		patch[4] = 0x01
+
+		_, err := delta.ApplyVCDIFF(source, patch)
+		Expect(err).To(MatchError(delta.ErrUnsupported))
+	})
+
+	It("should reject truncated patches", func() {
+		patch := vcdiffWindow(0x01, []byte{11, 0}, target, data, inst, addr)
+
+		_, err := delta.ApplyVCDIFF(source, patch[:len(patch)-3])
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject an output larger than MaxTargetSize", func() {
+		original := delta.MaxTargetSize
+		delta.MaxTargetSize = 10
+		defer func() { delta.MaxTargetSize = original }()
+
+		patch := vcdiffWindow(0x01, []byte{11, 0}, target, data, inst, addr)
+
+		_, err := delta.ApplyVCDIFF(source, patch)
+		Expect(err).To(MatchError(ContainSubstring("exceeds")))
+	})
+
+	It("should reject a source segment beyond the source", func() {
+		patch := vcdiffWindow(0x01, []byte{11, 0}, target, data, inst, addr)
+
+		_, err := delta.ApplyVCDIFF([]byte("short"), patch)
+		Expect(err).To(MatchError(ContainSubstring("out of range")))
+	})
+})
@@ -0,0 +1,226 @@
+// Package dotnet installs the .NET Desktop Runtime into a Wine prefix using
+// Microsoft's official installer instead of winetricks.
+package dotnet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+	"github.com/jslay88/zladxhd-installer/internal/runner"
+)
+
+const (
+	// DesktopRuntimeVersion is the pinned .NET Desktop Runtime release.
+	// 6.0.36 is the final .NET 6 servicing release.
+	DesktopRuntimeVersion = "6.0.36"
+	// DesktopRuntimeSHA512 pins the checksum of the windowsdesktop-runtime-6.0.36-win-x64.exe
+	// installer, as published in Microsoft's release metadata for DesktopRuntimeVersion.
+	// Keep it in sync with DesktopRuntimeVersion; metadata that disagrees is refused.
+	// While empty, the checksum from the metadata is used as is.
+	DesktopRuntimeSHA512 = ""
+	// ReleasesMetadataURL is Microsoft's release metadata for the .NET 6 channel.
+	ReleasesMetadataURL = "https://dotnetcli.blob.core.windows.net/dotnet/release-metadata/6.0/releases.json"
+	// installerRID is the runtime identifier of the installer to download.
+	installerRID = "win-x64"
+	// frameworkDir is the shared framework directory of the desktop runtime.
+	frameworkDir = "Microsoft.WindowsDesktop.App"
+)
+
+// ErrChecksumMismatch is returned when the release metadata's installer
+// checksum disagrees with the pinned one, so the metadata can't be trusted.
+var ErrChecksumMismatch = errors.New("release metadata checksum does not match the pinned checksum")
+
+// InstallerArgs are the arguments for a silent, unattended install.
+var InstallerArgs = []string{"/install", "/quiet", "/norestart"}
+
+// Installer downloads and runs the .NET Desktop Runtime installer.
+type Installer struct {
+	// CacheDir is where the installer and its checksum are cached.
+	CacheDir string
+	// Version is the runtime version to install.
+	Version string
+	// MetadataURL is the release metadata used to resolve the download URL and SHA512.
+	MetadataURL string
+	// SHA512 pins the installer checksum; release metadata that disagrees is
+	// refused. If empty, the checksum published in the release metadata is used
+	// and recorded next to the cached installer.
+	SHA512 string
+	// Client is the HTTP client used for metadata requests.
+	Client *http.Client
+	// Force runs the installer even if the runtime is already installed.
+	Force bool
+}
+
+// NewInstaller creates an installer for the pinned runtime version.
+func NewInstaller(cacheDir string) *Installer {
+	return &Installer{
+		CacheDir:    filepath.Join(cacheDir, "dotnet"),
+		Version:     DesktopRuntimeVersion,
+		MetadataURL: ReleasesMetadataURL,
+		SHA512:      DesktopRuntimeSHA512,
+		Client:      http.DefaultClient,
+	}
+}
+
+// InstallerPath returns the cache path of the installer executable.
+func (i *Installer) InstallerPath() string {
+	return filepath.Join(i.CacheDir, fmt.Sprintf("windowsdesktop-runtime-%s-%s.exe", i.Version, installerRID))
+}
+
+// checksumPath returns the path of the recorded installer checksum.
+func (i *Installer) checksumPath() string {
+	return i.InstallerPath() + ".sha512"
+}
+
+// Download ensures a verified installer is cached and returns its path.
+// A cached installer matching the pinned or recorded checksum is reused
+// without network access.
+func (i *Installer) Download(showProgress bool) (string, error) {
+	path := i.InstallerPath()
+
+	expected := i.SHA512
+	if expected == "" {
+		if data, err := os.ReadFile(i.checksumPath()); err == nil {
+			expected = strings.TrimSpace(string(data))
+		}
+	}
+	if expected != "" && archive.FileExists(path) {
+		if err := archive.VerifySHA512(path, expected); err == nil {
+			return path, nil
+		}
+	}
+
+	file, err := i.resolveFile()
+	if err != nil {
+		return "", err
+	}
+	if i.SHA512 != "" && !strings.EqualFold(i.SHA512, file.Hash) {
+		return "", fmt.Errorf(".NET Desktop Runtime %s: %w", i.Version, ErrChecksumMismatch)
+	}
+
+	if err := archive.Download(archive.DownloadOptions{
+		URL:          file.URL,
+		DestPath:     path,
+		ShowProgress: showProgress,
+	}); err != nil {
+		return "", fmt.Errorf("failed to download .NET installer: %w", err)
+	}
+
+	if err := archive.VerifySHA512(path, file.Hash); err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+	if err := os.WriteFile(i.checksumPath(), []byte(strings.ToLower(file.Hash)+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to record installer checksum: %w", err)
+	}
+
+	return path, nil
+}
+
+// Install runs the installer silently in the prefix through r and verifies
+// that the runtime was installed. prefixPath is the Wine prefix (pfx) directory.
+func (i *Installer) Install(r runner.Runner, prefixPath string, showProgress bool, suppressOutput bool) error {
+	if !i.Force && IsInstalled(prefixPath) {
+		return nil
+	}
+
+	path, err := i.Download(showProgress)
+	if err != nil {
+		return err
+	}
+
+	runErr := r.LaunchInDir(path, filepath.Dir(path), runner.LaunchOptions{
+		SuppressOutput: suppressOutput,
+		Args:           InstallerArgs,
+	})
+
+	// The installer exits with 3010 when a reboot is "required", so trust
+	// the files on disk over the exit status.
+	if IsInstalled(prefixPath) {
+		return nil
+	}
+	if runErr != nil {
+		return fmt.Errorf("failed to run .NET installer: %w", runErr)
+	}
+	return fmt.Errorf(".NET Desktop Runtime 6 not found in prefix after install")
+}
+
+// InstalledVersions returns the .NET Desktop Runtime 6 versions installed in the prefix.
+func InstalledVersions(prefixPath string) []string {
+	pattern := filepath.Join(prefixPath, "drive_c", "Program Files", "dotnet", "shared", frameworkDir, "6.*")
+	matches, _ := filepath.Glob(pattern)
+
+	var versions []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			versions = append(versions, filepath.Base(match))
+		}
+	}
+	return versions
+}
+
+// IsInstalled checks if a .NET Desktop Runtime 6 is installed in the prefix.
+func IsInstalled(prefixPath string) bool {
+	return len(InstalledVersions(prefixPath)) > 0
+}
+
+// releaseFile is a downloadable file in the release metadata.
+type releaseFile struct {
+	Name string `json:"name"`
+	RID  string `json:"rid"`
+	URL  string `json:"url"`
+	Hash string `json:"hash"`
+}
+
+// releasesMetadata is the subset of the channel release metadata we use.
+type releasesMetadata struct {
+	Releases []struct {
+		ReleaseVersion string `json:"release-version"`
+		WindowsDesktop *struct {
+			Version string        `json:"version"`
+			Files   []releaseFile `json:"files"`
+		} `json:"windowsdesktop"`
+	} `json:"releases"`
+}
+
+// resolveFile looks up the installer download for the pinned version.
+func (i *Installer) resolveFile() (*releaseFile, error) {
+	client := i.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(i.MetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch .NET release metadata: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(".NET release metadata request failed with status: %s", resp.Status)
+	}
+
+	var metadata releasesMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse .NET release metadata: %w", err)
+	}
+
+	for _, rel := range metadata.Releases {
+		if rel.WindowsDesktop == nil || (rel.ReleaseVersion != i.Version && rel.WindowsDesktop.Version != i.Version) {
+			continue
+		}
+		for _, f := range rel.WindowsDesktop.Files {
+			if f.RID == installerRID && strings.HasSuffix(f.Name, ".exe") && f.URL != "" && f.Hash != "" {
+				return &f, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf(".NET Desktop Runtime %s installer not found in release metadata", i.Version)
+}
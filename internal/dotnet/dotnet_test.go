@@ -0,0 +1,197 @@
+package dotnet_test
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/dotnet"
+	"github.com/jslay88/zladxhd-installer/internal/runner"
+)
+
+// fakeRunner records launches and optionally creates the runtime in the prefix.
+type fakeRunner struct {
+	prefixPath string
+	install    bool
+	err        error
+	launched   []string
+	args       []string
+}
+
+func (f *fakeRunner) Name() string { return "fake" }
+
+func (f *fakeRunner) LaunchInDir(exePath string, workDir string, opts runner.LaunchOptions) error {
+	f.launched = append(f.launched, exePath)
+	f.args = opts.Args
+	if f.install {
+		dir := filepath.Join(f.prefixPath, "drive_c", "Program Files", "dotnet", "shared", "Microsoft.WindowsDesktop.App", "6.0.36")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+	}
+	return f.err
+}
+
+func (f *fakeRunner) WaitForWineserver() error { return nil }
+
+func (f *fakeRunner) InstallVerb(verb string, opts runner.VerbOptions) error {
+	return fmt.Errorf("unexpected verb %s", verb)
+}
+
+var _ = Describe("Installer", func() {
+	var tmpDir string
+	var prefixPath string
+	var server *httptest.Server
+	var installerData []byte
+	var installerHash string
+	var installer *dotnet.Installer
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "dotnet-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		prefixPath = filepath.Join(tmpDir, "pfx")
+
+		installerData = []byte("MZ fake windowsdesktop runtime installer")
+		sum := sha512.Sum512(installerData)
+		installerHash = hex.EncodeToString(sum[:])
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/releases.json", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{
+				"channel-version": "6.0",
+				"releases": [
+					{"release-version": "6.0.36", "windowsdesktop": {"version": "6.0.36", "files": [
+						{"name": "windowsdesktop-runtime-win-arm64.exe", "rid": "win-arm64", "url": "%[1]s/arm64.exe", "hash": "00"},
+						{"name": "windowsdesktop-runtime-win-x64.zip", "rid": "win-x64", "url": "%[1]s/x64.zip", "hash": "00"},
+						{"name": "windowsdesktop-runtime-win-x64.exe", "rid": "win-x64", "url": "%[1]s/x64.exe", "hash": "%[2]s"}
+					]}},
+					{"release-version": "6.0.35", "windowsdesktop": {"version": "6.0.35", "files": []}}
+				]
+			}`, "http://"+r.Host, installerHash)
+		})
+		mux.HandleFunc("/x64.exe", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(installerData)
+		})
+		server = httptest.NewServer(mux)
+
+		installer = dotnet.NewInstaller(tmpDir)
+		installer.MetadataURL = server.URL + "/releases.json"
+		installer.SHA512 = installerHash
+	})
+
+	AfterEach(func() {
+		server.Close()
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("Download", func() {
+		It("should download and verify the x64 installer", func() {
+			path, err := installer.Download(false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(tmpDir, "dotnet", "windowsdesktop-runtime-6.0.36-win-x64.exe")))
+
+			data, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(Equal(installerData))
+		})
+
+		It("should reuse a verified cached installer offline", func() {
+			_, err := installer.Download(false)
+			Expect(err).NotTo(HaveOccurred())
+			server.Close()
+
+			_, err = installer.Download(false)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should reject an installer that doesn't match the metadata checksum", func() {
+			installerData = []byte("tampered")
+
+			_, err := installer.Download(false)
+			Expect(err).To(MatchError(ContainSubstring("checksum mismatch")))
+			Expect(installer.InstallerPath()).NotTo(BeAnExistingFile())
+		})
+
+		It("should refuse metadata that disagrees with the pinned checksum", func() {
+			installer.SHA512 = "deadbeef"
+
+			_, err := installer.Download(false)
+			Expect(err).To(MatchError(dotnet.ErrChecksumMismatch))
+			Expect(installer.InstallerPath()).NotTo(BeAnExistingFile())
+		})
+
+		It("should fail for a version missing from the metadata", func() {
+			installer.Version = "6.0.99"
+
+			_, err := installer.Download(false)
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+		})
+	})
+
+	Describe("Install", func() {
+		It("should refuse to install when the metadata disagrees with the pinned checksum", func() {
+			sum := sha512.Sum512([]byte("the genuine installer"))
+			installer.SHA512 = hex.EncodeToString(sum[:])
+			r := &fakeRunner{prefixPath: prefixPath, install: true}
+
+			err := installer.Install(r, prefixPath, false, true)
+			Expect(err).To(MatchError(dotnet.ErrChecksumMismatch))
+			Expect(r.launched).To(BeEmpty())
+			Expect(dotnet.IsInstalled(prefixPath)).To(BeFalse())
+		})
+
+		It("should run the installer silently and verify the runtime", func() {
+			r := &fakeRunner{prefixPath: prefixPath, install: true}
+
+			Expect(installer.Install(r, prefixPath, false, true)).To(Succeed())
+			Expect(r.launched).To(Equal([]string{installer.InstallerPath()}))
+			Expect(r.args).To(Equal([]string{"/install", "/quiet", "/norestart"}))
+			Expect(dotnet.InstalledVersions(prefixPath)).To(Equal([]string{"6.0.36"}))
+		})
+
+		It("should succeed when the installer requests a reboot", func() {
+			r := &fakeRunner{prefixPath: prefixPath, install: true, err: errors.New("exit status 3010")}
+
+			Expect(installer.Install(r, prefixPath, false, true)).To(Succeed())
+		})
+
+		It("should fail when the runtime isn't in the prefix afterwards", func() {
+			r := &fakeRunner{prefixPath: prefixPath}
+
+			Expect(installer.Install(r, prefixPath, false, true)).To(MatchError(ContainSubstring("not found in prefix")))
+		})
+
+		It("should skip an already installed runtime", func() {
+			Expect(os.MkdirAll(filepath.Join(prefixPath, "drive_c", "Program Files", "dotnet", "shared", "Microsoft.WindowsDesktop.App", "6.0.2"), 0755)).To(Succeed())
+			r := &fakeRunner{prefixPath: prefixPath}
+
+			Expect(installer.Install(r, prefixPath, false, true)).To(Succeed())
+			Expect(r.launched).To(BeEmpty())
+		})
+	})
+
+	Describe("Force", func() {
+		It("should rerun the installer over an installed runtime", func() {
+			Expect(os.MkdirAll(filepath.Join(prefixPath, "drive_c", "Program Files", "dotnet", "shared", "Microsoft.WindowsDesktop.App", "6.0.2"), 0755)).To(Succeed())
+			r := &fakeRunner{prefixPath: prefixPath, install: true}
+			installer.Force = true
+
+			Expect(installer.Install(r, prefixPath, false, true)).To(Succeed())
+			Expect(r.launched).To(HaveLen(1))
+		})
+	})
+
+	Describe("IsInstalled", func() {
+		It("should ignore other runtime versions", func() {
+			Expect(os.MkdirAll(filepath.Join(prefixPath, "drive_c", "Program Files", "dotnet", "shared", "Microsoft.WindowsDesktop.App", "8.0.1"), 0755)).To(Succeed())
+			Expect(dotnet.IsInstalled(prefixPath)).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,324 @@
+package patcher
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/delta"
+	"github.com/jslay88/zladxhd-installer/internal/release"
+)
+
+const (
+	// PatchManifestName is the name of the manifest inside a patch data bundle.
+	PatchManifestName = "patches.json"
+	// PatchDataAssetName is the release asset holding the patch data bundle.
+	PatchDataAssetName = "LADXHD.PatchData.zip"
+)
+
+const (
+	// stagingSuffix marks patched outputs written before they are installed.
+	stagingSuffix = ".patch-new"
+	// originalSuffix marks game files moved aside until the patch is installed.
+	originalSuffix = ".patch-orig"
+)
+
+// Patch actions supported in a patch manifest.
+const (
+	// ActionPatch applies a delta (xdelta3/VCDIFF or bsdiff) to the existing file.
+	ActionPatch = "patch"
+	// ActionReplace writes the file shipped in the bundle, creating it if needed.
+	ActionReplace = "replace"
+	// ActionDelete removes the file from the game directory.
+	ActionDelete = "delete"
+)
+
+// ErrNoPatchData is returned when a release ships no native patch data.
+var ErrNoPatchData = errors.New("release has no native patch data")
+
+// PatchManifest describes the changes of a patch data bundle.
+type PatchManifest struct {
+	// Version is the game version produced by the patch.
+	Version string `json:"version"`
+	// Files lists the per-file changes.
+	Files []PatchEntry `json:"files"`
+}
+
+// PatchEntry describes the change to a single game file.
+type PatchEntry struct {
+	// Path is the file path relative to the game directory.
+	Path string `json:"path"`
+	// Action is one of ActionPatch, ActionReplace or ActionDelete.
+	Action string `json:"action"`
+	// Data is the bundle path of the delta (patch) or new file contents (replace).
+	Data string `json:"data,omitempty"`
+	// SourceSHA256 is the expected hash of the file before patching.
+	SourceSHA256 string `json:"source_sha256,omitempty"`
+	// TargetSHA256 is the expected hash of the file after patching.
+	TargetSHA256 string `json:"target_sha256,omitempty"`
+}
+
+// FindPatchDataAsset finds the native patch data bundle, PatchDataAssetName,
+// in the release assets.
+func FindPatchDataAsset(rel *Release) *Asset {
+	return rel.FindAsset(func(name string) bool {
+		return name == PatchDataAssetName
+	})
+}
+
+// isStagingName checks if a file name is one of ApplyBundle's staging files.
+func isStagingName(name string) bool {
+	return strings.HasSuffix(name, stagingSuffix) || strings.HasSuffix(name, originalSuffix)
+}
+
+// ApplyNative applies the release's patch data directly to the game directory
+// without Wine. Returns ErrNoPatchData if the release has no patch data bundle.
+func (p *Patcher) ApplyNative(showProgress bool) error {
+	rel := p.release
+	if rel == nil {
+		var err error
+		if p.Version != "" {
+			rel, err = p.source().Get(p.Version)
+		} else {
+			rel, err = GetLatestRelease(p.source())
+		}
+		if err != nil {
+			return err
+		}
+		p.release = rel
+		p.Version = rel.TagName
+	}
+
+	asset := FindPatchDataAsset(rel)
+	if asset == nil {
+		return ErrNoPatchData
+	}
+
+	bundlePath := filepath.Join(p.CacheDir, "patches", rel.TagName, asset.Name)
+	if info, err := os.Stat(bundlePath); err != nil || info.Size() == 0 {
+		if err := release.FetchAsset(asset, bundlePath, showProgress); err != nil {
+			return fmt.Errorf("failed to download patch data: %w", err)
+		}
+	}
+
+	if err := ApplyBundle(p.GameDir, bundlePath); err != nil {
+		return err
+	}
+	return WritePatchRecord(p.GameDir, p.Version)
+}
+
+// ApplyBundle applies a patch data bundle to gameDir.
+// Every output is written and verified before any game file is touched, and
+// the files being replaced or deleted are moved aside until every change is
+// installed, so a failed patch is rolled back and leaves the game directory
+// unchanged. Files that already match their target hash are skipped.
+func ApplyBundle(gameDir string, bundlePath string) error {
+	r, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to open patch data: %w", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	manifest, err := readPatchManifest(&r.Reader)
+	if err != nil {
+		return err
+	}
+
+	// change is a verified change to a game file; tmpPath is "" for deletes.
+	type change struct {
+		tmpPath string
+		path    string
+	}
+	var changes []change
+	var deletes []change
+	cleanup := func() {
+		for _, c := range changes {
+			_ = os.Remove(c.tmpPath)
+		}
+	}
+
+	for _, entry := range manifest.Files {
+		path, err := gamePath(gameDir, entry.Path)
+		if err != nil {
+			cleanup()
+			return err
+		}
+
+		if entry.Action == ActionDelete {
+			deletes = append(deletes, change{path: path})
+			continue
+		}
+
+		out, err := patchEntry(&r.Reader, path, entry)
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("failed to patch %s: %w", entry.Path, err)
+		}
+		if out == nil {
+			continue // already up to date
+		}
+
+		tmpPath := path + stagingSuffix
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			cleanup()
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(tmpPath, out, fileMode(path)); err != nil {
+			cleanup()
+			return fmt.Errorf("failed to write %s: %w", entry.Path, err)
+		}
+		changes = append(changes, change{tmpPath: tmpPath, path: path})
+	}
+
+	// installed records what was done to a game file so it can be undone.
+	type installed struct {
+		path     string
+		movedOut bool
+		wroteNew bool
+	}
+	var done []installed
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			if done[i].wroteNew {
+				_ = os.Remove(done[i].path)
+			}
+			if done[i].movedOut {
+				_ = os.Rename(done[i].path+originalSuffix, done[i].path)
+			}
+		}
+		cleanup()
+	}
+
+	for _, c := range append(changes, deletes...) {
+		step := installed{path: c.path}
+		if _, err := os.Lstat(c.path); err == nil {
+			if err := os.Rename(c.path, c.path+originalSuffix); err != nil {
+				rollback()
+				return fmt.Errorf("failed to move %s aside: %w", c.path, err)
+			}
+			step.movedOut = true
+		}
+		if c.tmpPath != "" {
+			if err := os.Rename(c.tmpPath, c.path); err != nil {
+				done = append(done, step)
+				rollback()
+				return fmt.Errorf("failed to install %s: %w", c.path, err)
+			}
+			step.wroteNew = true
+		}
+		done = append(done, step)
+	}
+
+	for _, d := range done {
+		if d.movedOut {
+			_ = os.Remove(d.path + originalSuffix)
+		}
+	}
+
+	return nil
+}
+
+// readPatchManifest reads and validates the bundle manifest.
+func readPatchManifest(r *zip.Reader) (*PatchManifest, error) {
+	data, err := readZipFile(r, PatchManifestName)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest PatchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse patch manifest: %w", err)
+	}
+
+	for _, entry := range manifest.Files {
+		switch entry.Action {
+		case ActionPatch, ActionReplace:
+			if entry.Data == "" || entry.TargetSHA256 == "" {
+				return nil, fmt.Errorf("patch manifest entry %s is missing data or target hash", entry.Path)
+			}
+		case ActionDelete:
+		default:
+			return nil, fmt.Errorf("unknown patch action %q for %s", entry.Action, entry.Path)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// patchEntry produces the verified new contents for a patch or replace entry.
+// Returns nil if the file already matches the target hash.
+func patchEntry(r *zip.Reader, path string, entry PatchEntry) ([]byte, error) {
+	current, err := os.ReadFile(path)
+	if err != nil && !(os.IsNotExist(err) && entry.Action == ActionReplace) {
+		return nil, fmt.Errorf("failed to read game file: %w", err)
+	}
+	if current != nil && hashEqual(current, entry.TargetSHA256) {
+		return nil, nil
+	}
+	if current != nil && entry.SourceSHA256 != "" && !hashEqual(current, entry.SourceSHA256) {
+		return nil, fmt.Errorf("unexpected game file contents (modified or wrong game version)")
+	}
+
+	data, err := readZipFile(r, entry.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := data
+	if entry.Action == ActionPatch {
+		if out, err = delta.Apply(current, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if !hashEqual(out, entry.TargetSHA256) {
+		return nil, fmt.Errorf("patched file hash mismatch")
+	}
+
+	return out, nil
+}
+
+// readZipFile reads a file from the bundle.
+func readZipFile(r *zip.Reader, name string) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("patch data is missing %s: %w", name, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from patch data: %w", name, err)
+	}
+	return data, nil
+}
+
+// gamePath resolves a manifest path inside gameDir, rejecting paths that escape it.
+func gamePath(gameDir string, rel string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(rel))
+	if clean == "." || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path in patch manifest: %s", rel)
+	}
+	return filepath.Join(gameDir, clean), nil
+}
+
+// fileMode returns the permissions of an existing file, or 0644.
+func fileMode(path string) os.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0644
+}
+
+// hashEqual reports whether data has the given hex SHA256 hash.
+func hashEqual(data []byte, expected string) bool {
+	sum := sha256.Sum256(data)
+	return strings.EqualFold(hex.EncodeToString(sum[:]), expected)
+}
@@ -0,0 +1,235 @@
+package patcher_test
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/patcher"
+	"github.com/jslay88/zladxhd-installer/internal/release"
+)
+
+// vcdiffPatch turns "hello world" into "hello there world!!!".
+var vcdiffPatch = []byte{
+	0xD6, 0xC3, 0xC4, 0x00, 0x00, 0x01, 0x0B, 0x00, 0x16, 0x14, 0x00, 0x07, 0x08, 0x02, 0x74, 0x68,
+	0x65, 0x72, 0x65, 0x20, 0x21, 0x13, 0x06, 0x01, 0x06, 0x13, 0x05, 0x00, 0x03, 0x00, 0x06,
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("Native patching", func() {
+	var tmpDir string
+	var gameDir string
+	var bundlePath string
+
+	writeFile := func(rel, content string) {
+		path := filepath.Join(gameDir, rel)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	readFile := func(rel string) string {
+		data, err := os.ReadFile(filepath.Join(gameDir, rel))
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	writeBundle := func(manifest patcher.PatchManifest, files map[string][]byte) {
+		f, err := os.Create(bundlePath)
+		Expect(err).NotTo(HaveOccurred())
+		defer func() { _ = f.Close() }()
+
+		w := zip.NewWriter(f)
+		data, err := json.Marshal(manifest)
+		Expect(err).NotTo(HaveOccurred())
+		files[patcher.PatchManifestName] = data
+		for name, content := range files {
+			fw, err := w.Create(name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = fw.Write(content)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(w.Close()).To(Succeed())
+	}
+
+	defaultBundle := func() {
+		writeBundle(patcher.PatchManifest{
+			Version: "1.2.0",
+			Files: []patcher.PatchEntry{
+				{
+					Path:         "Game.exe",
+					Action:       patcher.ActionPatch,
+					Data:         "deltas/Game.exe.vcdiff",
+					SourceSHA256: sha256Hex("hello world"),
+					TargetSHA256: sha256Hex("hello there world!!!"),
+				},
+				{
+					Path:         "Content/new.xnb",
+					Action:       patcher.ActionReplace,
+					Data:         "files/Content/new.xnb",
+					TargetSHA256: sha256Hex("new data"),
+				},
+				{
+					Path:   "old.dll",
+					Action: patcher.ActionDelete,
+				},
+			},
+		}, map[string][]byte{
+			"deltas/Game.exe.vcdiff": vcdiffPatch,
+			"files/Content/new.xnb":  []byte("new data"),
+		})
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "patcher-native-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		gameDir = filepath.Join(tmpDir, "game")
+		bundlePath = filepath.Join(tmpDir, patcher.PatchDataAssetName)
+
+		writeFile("Game.exe", "hello world")
+		writeFile("old.dll", "obsolete")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("ApplyBundle", func() {
+		It("should patch, add and delete files", func() {
+			defaultBundle()
+
+			Expect(patcher.ApplyBundle(gameDir, bundlePath)).To(Succeed())
+			Expect(readFile("Game.exe")).To(Equal("hello there world!!!"))
+			Expect(readFile("Content/new.xnb")).To(Equal("new data"))
+			Expect(filepath.Join(gameDir, "old.dll")).NotTo(BeAnExistingFile())
+		})
+
+		It("should be idempotent", func() {
+			defaultBundle()
+
+			Expect(patcher.ApplyBundle(gameDir, bundlePath)).To(Succeed())
+			Expect(patcher.ApplyBundle(gameDir, bundlePath)).To(Succeed())
+			Expect(readFile("Game.exe")).To(Equal("hello there world!!!"))
+		})
+
+		It("should leave the game untouched when a source hash doesn't match", func() {
+			writeFile("Game.exe", "modded world")
+			defaultBundle()
+
+			err := patcher.ApplyBundle(gameDir, bundlePath)
+			Expect(err).To(MatchError(ContainSubstring("Game.exe")))
+			Expect(readFile("Game.exe")).To(Equal("modded world"))
+			Expect(readFile("old.dll")).To(Equal("obsolete"))
+			Expect(filepath.Join(gameDir, "Content", "new.xnb")).NotTo(BeAnExistingFile())
+		})
+
+		It("should roll back installed files when a later change fails", func() {
+			// A directory in the way stops old.dll from being moved aside
+			Expect(os.MkdirAll(filepath.Join(gameDir, "old.dll.patch-orig", "busy"), 0755)).To(Succeed())
+			defaultBundle()
+
+			err := patcher.ApplyBundle(gameDir, bundlePath)
+			Expect(err).To(MatchError(ContainSubstring("old.dll")))
+			Expect(readFile("Game.exe")).To(Equal("hello world"))
+			Expect(readFile("old.dll")).To(Equal("obsolete"))
+			Expect(filepath.Join(gameDir, "Content", "new.xnb")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(gameDir, "Game.exe.patch-orig")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(gameDir, "Game.exe.patch-new")).NotTo(BeAnExistingFile())
+		})
+
+		It("should reject output that doesn't match the target hash", func() {
+			writeBundle(patcher.PatchManifest{
+				Files: []patcher.PatchEntry{{
+					Path:         "Game.exe",
+					Action:       patcher.ActionReplace,
+					Data:         "Game.exe",
+					TargetSHA256: sha256Hex("something else"),
+				}},
+			}, map[string][]byte{"Game.exe": []byte("new exe")})
+
+			err := patcher.ApplyBundle(gameDir, bundlePath)
+			Expect(err).To(MatchError(ContainSubstring("hash mismatch")))
+			Expect(readFile("Game.exe")).To(Equal("hello world"))
+		})
+
+		It("should reject paths outside the game directory", func() {
+			writeBundle(patcher.PatchManifest{
+				Files: []patcher.PatchEntry{{Path: "../escape", Action: patcher.ActionDelete}},
+			}, map[string][]byte{})
+
+			err := patcher.ApplyBundle(gameDir, bundlePath)
+			Expect(err).To(MatchError(ContainSubstring("invalid path")))
+		})
+
+		It("should reject unknown actions", func() {
+			writeBundle(patcher.PatchManifest{
+				Files: []patcher.PatchEntry{{Path: "Game.exe", Action: "rename"}},
+			}, map[string][]byte{})
+
+			err := patcher.ApplyBundle(gameDir, bundlePath)
+			Expect(err).To(MatchError(ContainSubstring("unknown patch action")))
+		})
+	})
+
+	Describe("FindPatchDataAsset", func() {
+		It("should find the patch data zip", func() {
+			rel := &patcher.Release{Assets: []patcher.Asset{
+				{Name: "LADXHD.Patcher.v1.2.0.exe"},
+				{Name: "LADXHD.PatchData.zip"},
+			}}
+			asset := patcher.FindPatchDataAsset(rel)
+			Expect(asset).NotTo(BeNil())
+			Expect(asset.Name).To(Equal("LADXHD.PatchData.zip"))
+		})
+
+		It("should return nil when the release has no patch data", func() {
+			rel := &patcher.Release{Assets: []patcher.Asset{
+				{Name: "LADXHD.Patcher.v1.2.0.exe"},
+				{Name: "patch-notes.zip"},
+			}}
+			Expect(patcher.FindPatchDataAsset(rel)).To(BeNil())
+		})
+	})
+
+	Describe("ApplyNative", func() {
+		It("should return ErrNoPatchData without a patch data asset", func() {
+			mirror := filepath.Join(tmpDir, "mirror", "v1.2.0")
+			Expect(os.MkdirAll(mirror, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(mirror, "LADXHD.Patcher.v1.2.0.exe"), []byte("MZ"), 0644)).To(Succeed())
+
+			p := patcher.NewPatcher(gameDir, filepath.Join(tmpDir, "cache"))
+			p.Source = release.NewStaticSource(filepath.Join(tmpDir, "mirror"))
+
+			Expect(p.ApplyNative(false)).To(MatchError(patcher.ErrNoPatchData))
+		})
+
+		It("should apply patch data from the release", func() {
+			mirror := filepath.Join(tmpDir, "mirror", "v1.2.0")
+			Expect(os.MkdirAll(mirror, 0755)).To(Succeed())
+			defaultBundle()
+			Expect(os.Rename(bundlePath, filepath.Join(mirror, patcher.PatchDataAssetName))).To(Succeed())
+
+			p := patcher.NewPatcher(gameDir, filepath.Join(tmpDir, "cache"))
+			p.Source = release.NewStaticSource(filepath.Join(tmpDir, "mirror"))
+
+			Expect(p.ApplyNative(false)).To(Succeed())
+			Expect(p.Version).To(Equal("v1.2.0"))
+			Expect(readFile("Game.exe")).To(Equal("hello there world!!!"))
+
+			record, err := patcher.ReadPatchRecord(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(record.PatcherVersion).To(Equal("v1.2.0"))
+		})
+	})
+})
@@ -2,46 +2,44 @@
 package patcher
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/jslay88/zladxhd-installer/internal/archive"
-	"github.com/jslay88/zladxhd-installer/internal/protontricks"
+	"github.com/jslay88/zladxhd-installer/internal/release"
+	"github.com/jslay88/zladxhd-installer/internal/runner"
 )
 
 const (
+	// ReleaseCacheName names the patcher's release API cache, see
+	// release.CacheDir.
+	ReleaseCacheName = "patcher"
 	// GitHubRepo is the repository for LADXHD patcher releases.
 	// See: https://github.com/BigheadSMZ/Zelda-LA-DX-HD-Updated/releases
 	GitHubRepo = "BigheadSMZ/Zelda-LA-DX-HD-Updated"
-	// GitHubAPIURL is the GitHub API URL for releases.
-	GitHubAPIURL = "https://api.github.com/repos/%s/releases/latest"
 	// PatcherNamePattern is the pattern to match patcher files.
 	PatcherNamePattern = "LADXHD.Patcher"
 )
 
-// Release represents a GitHub release.
-type Release struct {
-	TagName string  `json:"tag_name"`
-	Name    string  `json:"name"`
-	Assets  []Asset `json:"assets"`
-}
+// Release represents a patcher release.
+type Release = release.Release
 
-// Asset represents a GitHub release asset.
-type Asset struct {
-	Name        string `json:"name"`
-	DownloadURL string `json:"browser_download_url"`
-	Size        int64  `json:"size"`
-}
+// Asset represents a patcher release asset.
+type Asset = release.Asset
 
 // Patcher manages the LADXHD patcher.
 type Patcher struct {
 	GameDir     string
 	PatcherPath string
 	CacheDir    string
+	// Version is the release tag of the downloaded patcher.
+	Version string
+	// Source provides patcher releases. Defaults to the GitHub repository.
+	Source release.Source
+
+	release *Release
 }
 
 // NewPatcher creates a new patcher instance.
@@ -49,35 +47,25 @@ func NewPatcher(gameDir string, cacheDir string) *Patcher {
 	return &Patcher{
 		GameDir:  gameDir,
 		CacheDir: cacheDir,
+		Source:   DefaultSource(cacheDir),
 	}
 }
 
-// GetLatestRelease fetches the latest patcher release info from GitHub.
-func GetLatestRelease() (*Release, error) {
-	url := fmt.Sprintf(GitHubAPIURL, GitHubRepo)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch release info: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned status: %s", resp.Status)
-	}
-
-	var release Release
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return nil, fmt.Errorf("failed to parse release info: %w", err)
-	}
+// DefaultSource returns the GitHub release source for the patcher.
+// API responses are cached under cacheDir for conditional requests.
+func DefaultSource(cacheDir string) release.Source {
+	return release.NewGitHubSource(GitHubRepo, release.CacheDir(cacheDir, ReleaseCacheName))
+}
 
-	return &release, nil
+// GetLatestRelease fetches the latest patcher release info from the source.
+func GetLatestRelease(src release.Source) (*Release, error) {
+	return src.Latest()
 }
 
 // FindPatcherAsset finds the patcher executable in the release assets.
-func FindPatcherAsset(release *Release) (*Asset, error) {
+func FindPatcherAsset(rel *Release) (*Asset, error) {
 	// First, try to find an asset with "patcher" in the name
-	for _, asset := range release.Assets {
+	for _, asset := range rel.Assets {
 		name := strings.ToLower(asset.Name)
 		if strings.Contains(name, strings.ToLower(PatcherNamePattern)) && strings.HasSuffix(name, ".exe") {
 			return &asset, nil
@@ -85,7 +73,7 @@ func FindPatcherAsset(release *Release) (*Asset, error) {
 	}
 
 	// Fallback: find any .exe file
-	for _, asset := range release.Assets {
+	for _, asset := range rel.Assets {
 		name := strings.ToLower(asset.Name)
 		if strings.HasSuffix(name, ".exe") {
 			return &asset, nil
@@ -94,30 +82,57 @@ func FindPatcherAsset(release *Release) (*Asset, error) {
 
 	// List available assets for debugging
 	var assetNames []string
-	for _, asset := range release.Assets {
+	for _, asset := range rel.Assets {
 		assetNames = append(assetNames, asset.Name)
 	}
 
 	return nil, fmt.Errorf("patcher executable not found in release assets. Available: %v", assetNames)
 }
 
-// Download downloads the patcher to the game directory.
+// Download downloads the latest patcher to the game directory.
 func (p *Patcher) Download(showProgress bool) error {
-	release, err := GetLatestRelease()
+	rel, err := GetLatestRelease(p.source())
+	if err != nil {
+		return err
+	}
+
+	return p.downloadRelease(rel, false, showProgress)
+}
+
+// DownloadVersion downloads a specific patcher release to the game directory.
+// Any existing patcher with the same file name is replaced.
+func (p *Patcher) DownloadVersion(tag string, showProgress bool) error {
+	rel, err := p.source().Get(tag)
 	if err != nil {
 		return err
 	}
 
-	asset, err := FindPatcherAsset(release)
+	return p.downloadRelease(rel, true, showProgress)
+}
+
+// source returns the configured release source, falling back to GitHub.
+func (p *Patcher) source() release.Source {
+	if p.Source == nil {
+		p.Source = DefaultSource(p.CacheDir)
+	}
+	return p.Source
+}
+
+// downloadRelease downloads the patcher asset from a release.
+// An existing non-empty patcher file is reused unless force is set.
+func (p *Patcher) downloadRelease(rel *Release, force bool, showProgress bool) error {
+	asset, err := FindPatcherAsset(rel)
 	if err != nil {
 		return err
 	}
 
 	// Download to game directory
 	p.PatcherPath = filepath.Join(p.GameDir, asset.Name)
+	p.Version = rel.TagName
+	p.release = rel
 
 	// Check if already downloaded
-	if _, err := os.Stat(p.PatcherPath); err == nil {
+	if _, err := os.Stat(p.PatcherPath); err == nil && !force {
 		// Already exists, verify it's not empty
 		info, _ := os.Stat(p.PatcherPath)
 		if info.Size() > 0 {
@@ -126,12 +141,7 @@ func (p *Patcher) Download(showProgress bool) error {
 	}
 
 	// Download the patcher
-	err = archive.Download(archive.DownloadOptions{
-		URL:          asset.DownloadURL,
-		DestPath:     p.PatcherPath,
-		ShowProgress: showProgress,
-	})
-	if err != nil {
+	if err := release.FetchAsset(asset, p.PatcherPath, showProgress); err != nil {
 		return fmt.Errorf("failed to download patcher: %w", err)
 	}
 
@@ -155,17 +165,70 @@ func (p *Patcher) DownloadFromURL(url string, showProgress bool) error {
 	return nil
 }
 
-// Run runs the patcher using protontricks.
-func (p *Patcher) Run(runner *protontricks.Runner, appID uint32, suppressOutput bool) error {
+// Run runs the patcher in the game's Wine prefix.
+// This is the fallback when the release has no native patch data.
+func (p *Patcher) Run(r runner.Runner, suppressOutput bool) error {
 	if p.PatcherPath == "" {
 		return fmt.Errorf("patcher not downloaded")
 	}
 
+	before := gameExeChecksum(p.GameDir)
+
 	// Run the patcher in the game directory with --silent flag for automated patching
-	return runner.LaunchInDir(appID, p.PatcherPath, p.GameDir, protontricks.LaunchOptions{
+	if err := r.LaunchInDir(p.PatcherPath, p.GameDir, runner.LaunchOptions{
 		SuppressOutput: suppressOutput,
 		Args:           []string{"--silent"},
-	})
+	}); err != nil {
+		return err
+	}
+
+	// The patcher replaces the game executable; if it didn't, nothing was
+	// patched, unless the game already was with this patcher
+	if after := gameExeChecksum(p.GameDir); after == "" || after == before {
+		if after != "" && p.AlreadyApplied() {
+			return nil
+		}
+		return fmt.Errorf("patcher exited without changing the game executable")
+	}
+	return WritePatchRecord(p.GameDir, p.Version)
+}
+
+// AlreadyApplied checks if the game has a verified patch by this patcher
+// version, so running the patcher again would change nothing.
+func (p *Patcher) AlreadyApplied() bool {
+	exePath, err := FindGameExecutable(p.GameDir)
+	if err != nil {
+		return false
+	}
+	record := verifiedPatch(p.GameDir, exePath)
+	return record != nil && p.Version != "" && record.PatcherVersion == p.Version
+}
+
+// gameExeChecksum returns the SHA256 of the game executable, or "".
+func gameExeChecksum(gameDir string) string {
+	exePath, err := FindGameExecutable(gameDir)
+	if err != nil {
+		return ""
+	}
+	sum, err := archive.CalculateChecksum(exePath)
+	if err != nil {
+		return ""
+	}
+	return sum
+}
+
+// Verify detects the game version after patching.
+// Returns the detected version along with an error if no patch record
+// matches the game executable.
+func (p *Patcher) Verify() (*GameVersion, error) {
+	version, err := DetectVersion(p.GameDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect game version: %w", err)
+	}
+	if !version.Patched {
+		return version, fmt.Errorf("game at version %s has no verified patch", version.Version)
+	}
+	return version, nil
 }
 
 // FindExisting looks for an existing patcher in the game directory.
@@ -180,7 +243,7 @@ func (p *Patcher) FindExisting() (string, error) {
 			continue
 		}
 		name := strings.ToLower(entry.Name())
-		if strings.Contains(name, strings.ToLower(PatcherNamePattern)) && strings.HasSuffix(name, ".exe") {
+		if isPatcherName(name) && strings.HasSuffix(name, ".exe") {
 			path := filepath.Join(p.GameDir, entry.Name())
 			p.PatcherPath = path
 			return path, nil
@@ -190,28 +253,25 @@ func (p *Patcher) FindExisting() (string, error) {
 	return "", fmt.Errorf("patcher not found in game directory")
 }
 
-// GetDownloadURL constructs the download URL for a specific patcher version.
-func GetDownloadURL(version string, filename string) string {
-	return fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", GitHubRepo, version, filename)
-}
-
-// ListAvailableVersions lists available patcher versions from GitHub.
-func ListAvailableVersions() ([]string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/releases", GitHubRepo)
-
-	resp, err := http.Get(url)
+// GetDownloadURL returns the download URL of the asset filename of a patcher
+// version, as published by the source.
+func GetDownloadURL(src release.Source, version string, filename string) (string, error) {
+	rel, err := src.Get(version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch releases: %w", err)
+		return "", err
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned status: %s", resp.Status)
+	asset := rel.FindAsset(func(name string) bool { return name == filename })
+	if asset == nil {
+		return "", fmt.Errorf("release %s has no asset %s", version, filename)
 	}
+	return asset.DownloadURL, nil
+}
 
-	var releases []Release
-	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
-		return nil, fmt.Errorf("failed to parse releases: %w", err)
+// ListAvailableVersions lists available patcher versions from the source.
+func ListAvailableVersions(src release.Source) ([]string, error) {
+	releases, err := src.List()
+	if err != nil {
+		return nil, err
 	}
 
 	var versions []string
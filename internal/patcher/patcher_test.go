@@ -8,8 +8,31 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/jslay88/zladxhd-installer/internal/patcher"
+	"github.com/jslay88/zladxhd-installer/internal/release"
+	"github.com/jslay88/zladxhd-installer/internal/runner"
 )
 
+// fakePatcherRunner "runs" the patcher by writing newExe over the game executable.
+type fakePatcherRunner struct {
+	exePath string
+	newExe  []byte
+}
+
+func (f *fakePatcherRunner) Name() string { return "fake" }
+
+func (f *fakePatcherRunner) LaunchInDir(exePath string, workDir string, opts runner.LaunchOptions) error {
+	if f.newExe != nil {
+		return os.WriteFile(f.exePath, f.newExe, 0644)
+	}
+	return nil
+}
+
+func (f *fakePatcherRunner) WaitForWineserver() error { return nil }
+
+func (f *fakePatcherRunner) InstallVerb(verb string, opts runner.VerbOptions) error {
+	return nil
+}
+
 var _ = Describe("Patcher", func() {
 	var tmpDir string
 
@@ -144,10 +167,54 @@ var _ = Describe("Patcher", func() {
 		})
 	})
 
-	Describe("GetDownloadURL", func() {
-		It("should construct correct download URL", func() {
-			url := patcher.GetDownloadURL("v1.2.3", "LADXHD.Patcher.exe")
-			Expect(url).To(Equal("https://github.com/BigheadSMZ/Zelda-LA-DX-HD-Updated/releases/download/v1.2.3/LADXHD.Patcher.exe"))
+	Describe("Download", func() {
+		var mirrorDir string
+		var gameDir string
+
+		BeforeEach(func() {
+			mirrorDir = filepath.Join(tmpDir, "mirror")
+			gameDir = filepath.Join(tmpDir, "game")
+			for _, tag := range []string{"v1.0.0", "v1.1.0"} {
+				err := os.MkdirAll(filepath.Join(mirrorDir, tag), 0755)
+				Expect(err).NotTo(HaveOccurred())
+				err = os.WriteFile(filepath.Join(mirrorDir, tag, "LADXHD.Patcher."+tag+".exe"), []byte(tag), 0644)
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("should download the latest patcher from the source", func() {
+			p := patcher.NewPatcher(gameDir, tmpDir)
+			p.Source = release.NewStaticSource(mirrorDir)
+
+			err := p.Download(false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.PatcherPath).To(Equal(filepath.Join(gameDir, "LADXHD.Patcher.v1.1.0.exe")))
+			Expect(p.PatcherPath).To(BeAnExistingFile())
+		})
+
+		It("should download a specific patcher version", func() {
+			p := patcher.NewPatcher(gameDir, tmpDir)
+			p.Source = release.NewStaticSource(mirrorDir)
+
+			err := p.DownloadVersion("v1.0.0", false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.PatcherPath).To(Equal(filepath.Join(gameDir, "LADXHD.Patcher.v1.0.0.exe")))
+		})
+
+		It("should get download URLs from the source", func() {
+			src := release.NewStaticSource(mirrorDir)
+			url, err := patcher.GetDownloadURL(src, "v1.0.0", "LADXHD.Patcher.v1.0.0.exe")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(url).To(Equal(filepath.Join(mirrorDir, "v1.0.0", "LADXHD.Patcher.v1.0.0.exe")))
+
+			_, err = patcher.GetDownloadURL(src, "v1.0.0", "LADXHD.Patcher.exe")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should list available versions", func() {
+			versions, err := patcher.ListAvailableVersions(release.NewStaticSource(mirrorDir))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]string{"v1.1.0", "v1.0.0"}))
 		})
 	})
 
@@ -156,10 +223,62 @@ var _ = Describe("Patcher", func() {
 			p := patcher.NewPatcher(tmpDir, tmpDir)
 			// PatcherPath is empty by default
 
-			err := p.Run(nil, 12345, false)
+			err := p.Run(nil, false)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("patcher not downloaded"))
 		})
+
+		It("should record a patch that changed the game executable", func() {
+			exePath := filepath.Join(tmpDir, "Game.exe")
+			Expect(os.WriteFile(exePath, []byte("original"), 0644)).To(Succeed())
+			p := patcher.NewPatcher(tmpDir, tmpDir)
+			p.PatcherPath = filepath.Join(tmpDir, "LADXHD.Patcher.exe")
+			p.Version = "v1.2.0"
+
+			Expect(p.Run(&fakePatcherRunner{exePath: exePath, newExe: []byte("patched")}, true)).To(Succeed())
+
+			record, err := patcher.ReadPatchRecord(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(record).NotTo(BeNil())
+			Expect(record.PatcherVersion).To(Equal("v1.2.0"))
+		})
+
+		It("should fail when the patcher didn't change the game executable", func() {
+			exePath := filepath.Join(tmpDir, "Game.exe")
+			Expect(os.WriteFile(exePath, []byte("original"), 0644)).To(Succeed())
+			p := patcher.NewPatcher(tmpDir, tmpDir)
+			p.PatcherPath = filepath.Join(tmpDir, "LADXHD.Patcher.exe")
+
+			err := p.Run(&fakePatcherRunner{exePath: exePath}, true)
+			Expect(err).To(MatchError(ContainSubstring("without changing the game executable")))
+			Expect(filepath.Join(tmpDir, patcher.PatchRecordName)).NotTo(BeAnExistingFile())
+		})
+
+		It("should succeed when re-run on a game already patched by the same patcher", func() {
+			exePath := filepath.Join(tmpDir, "Game.exe")
+			Expect(os.WriteFile(exePath, []byte("patched"), 0644)).To(Succeed())
+			Expect(patcher.WritePatchRecord(tmpDir, "v1.2.0")).To(Succeed())
+			p := patcher.NewPatcher(tmpDir, tmpDir)
+			p.PatcherPath = filepath.Join(tmpDir, "LADXHD.Patcher.exe")
+			p.Version = "v1.2.0"
+			Expect(p.AlreadyApplied()).To(BeTrue())
+
+			Expect(p.Run(&fakePatcherRunner{exePath: exePath}, true)).To(Succeed())
+			Expect(p.AlreadyApplied()).To(BeTrue())
+		})
+
+		It("should fail when re-run on a game patched by another patcher without a change", func() {
+			exePath := filepath.Join(tmpDir, "Game.exe")
+			Expect(os.WriteFile(exePath, []byte("patched"), 0644)).To(Succeed())
+			Expect(patcher.WritePatchRecord(tmpDir, "v1.1.0")).To(Succeed())
+			p := patcher.NewPatcher(tmpDir, tmpDir)
+			p.PatcherPath = filepath.Join(tmpDir, "LADXHD.Patcher.exe")
+			p.Version = "v1.2.0"
+			Expect(p.AlreadyApplied()).To(BeFalse())
+
+			err := p.Run(&fakePatcherRunner{exePath: exePath}, true)
+			Expect(err).To(MatchError(ContainSubstring("without changing the game executable")))
+		})
 	})
 
 	Describe("Constants", func() {
@@ -0,0 +1,80 @@
+package patcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+)
+
+// PatchRecordName is the file in the game directory recording a successful patch.
+const PatchRecordName = ".zladxhd-patch.json"
+
+// PatchRecord records a successful patch run. The game only counts as patched
+// while its executable still matches ExeSHA256.
+type PatchRecord struct {
+	// PatcherVersion is the release tag of the patcher that was applied.
+	PatcherVersion string `json:"patcher_version"`
+	// ExeSHA256 is the hash of the game executable after patching.
+	ExeSHA256 string `json:"exe_sha256"`
+	// PatchedAt is when the patch was applied.
+	PatchedAt time.Time `json:"patched_at"`
+}
+
+// WritePatchRecord records that patcherVersion was applied to the game in
+// gameDir, pinning the hash of the current game executable.
+func WritePatchRecord(gameDir string, patcherVersion string) error {
+	exePath, err := FindGameExecutable(gameDir)
+	if err != nil {
+		return err
+	}
+	sum, err := archive.CalculateChecksum(exePath)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(PatchRecord{
+		PatcherVersion: patcherVersion,
+		ExeSHA256:      sum,
+		PatchedAt:      time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(gameDir, PatchRecordName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write patch record: %w", err)
+	}
+	return nil
+}
+
+// ReadPatchRecord reads the patch record in gameDir. Returns nil if there is none.
+func ReadPatchRecord(gameDir string) (*PatchRecord, error) {
+	data, err := os.ReadFile(filepath.Join(gameDir, PatchRecordName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read patch record: %w", err)
+	}
+
+	var record PatchRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse patch record: %w", err)
+	}
+	return &record, nil
+}
+
+// verifiedPatch returns the patch record if it matches the executable at exePath.
+func verifiedPatch(gameDir string, exePath string) *PatchRecord {
+	record, err := ReadPatchRecord(gameDir)
+	if err != nil || record == nil {
+		return nil
+	}
+	if sum, err := archive.CalculateChecksum(exePath); err != nil || sum != record.ExeSHA256 {
+		return nil
+	}
+	return record
+}
@@ -0,0 +1,419 @@
+package patcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+)
+
+const (
+	snapshotManifest = "snapshot.json"
+	snapshotFilesDir = "files"
+)
+
+// Snapshot is a saved copy of the game directory taken before patching.
+type Snapshot struct {
+	// ID uniquely identifies the snapshot (creation timestamp).
+	ID string `json:"id"`
+	// GameDir is the game directory the snapshot was taken from.
+	GameDir string `json:"game_dir"`
+	// GameVersion is the detected game version at snapshot time.
+	GameVersion string `json:"game_version,omitempty"`
+	// CreatedAt is when the snapshot was taken.
+	CreatedAt time.Time `json:"created_at"`
+	// Reflinked is true if files were cloned with copy-on-write reflinks.
+	Reflinked bool `json:"reflinked"`
+	// Files lists the files in the snapshot.
+	Files []SnapshotFile `json:"files"`
+	// Created lists the files patch runs created after the snapshot was taken.
+	// Restore only removes files recorded here, never the player's own files.
+	Created []string `json:"created,omitempty"`
+	// Changed lists the snapshotted files patch runs modified or removed.
+	// Restore only puts back files recorded here, so saves and settings
+	// changed since are kept.
+	Changed []string `json:"changed,omitempty"`
+	// Recorded is true once the changes of the patch run following the
+	// snapshot were recorded. Snapshots made by older versions lack it.
+	Recorded bool `json:"recorded,omitempty"`
+
+	dir string
+}
+
+// SnapshotFile records a file captured in a snapshot.
+type SnapshotFile struct {
+	Path    string      `json:"path"`
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mod_time"`
+}
+
+// Dir returns the directory where the snapshot is stored.
+func (s *Snapshot) Dir() string {
+	return s.dir
+}
+
+// SnapshotStore manages game directory snapshots.
+type SnapshotStore struct {
+	Dir string
+}
+
+// NewSnapshotStore creates a snapshot store rooted at dir.
+func NewSnapshotStore(dir string) *SnapshotStore {
+	return &SnapshotStore{Dir: dir}
+}
+
+// Create snapshots every file in gameDir.
+// If an existing snapshot already matches the directory contents, it is
+// returned instead of creating a duplicate.
+func (s *SnapshotStore) Create(gameDir string) (*Snapshot, error) {
+	files, err := scanGameDir(gameDir)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.List(gameDir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range existing {
+		if sameFiles(existing[i].Files, files) {
+			return &existing[i], nil
+		}
+	}
+
+	now := time.Now()
+	id := now.Format("20060102-150405.000")
+	for n := 1; archive.FileExists(filepath.Join(s.Dir, id)); n++ {
+		id = fmt.Sprintf("%s-%d", now.Format("20060102-150405.000"), n)
+	}
+
+	snap := &Snapshot{
+		ID:        id,
+		GameDir:   gameDir,
+		CreatedAt: now,
+		Files:     files,
+		dir:       filepath.Join(s.Dir, id),
+	}
+
+	if version, err := DetectVersion(gameDir); err == nil {
+		snap.GameVersion = version.Version
+	}
+
+	snap.Reflinked = true
+	for _, f := range files {
+		cloned, err := archive.CloneFile(filepath.Join(gameDir, f.Path), filepath.Join(snap.dir, snapshotFilesDir, f.Path))
+		if err != nil {
+			_ = os.RemoveAll(snap.dir)
+			return nil, fmt.Errorf("failed to snapshot %s: %w", f.Path, err)
+		}
+		snap.Reflinked = snap.Reflinked && cloned
+	}
+
+	if err := snap.writeManifest(); err != nil {
+		_ = os.RemoveAll(snap.dir)
+		return nil, err
+	}
+
+	return snap, nil
+}
+
+// writeManifest writes the snapshot manifest.
+func (snap *Snapshot) writeManifest() error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(snap.dir, snapshotManifest), data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot manifest: %w", err)
+	}
+	return nil
+}
+
+// RecordCreated records the files in the game directory that aren't in the
+// snapshot as created by the patch run that followed it, and the snapshotted
+// files that differ or are gone as changed by it. Call it after patching,
+// whether or not the patch succeeded.
+func (s *SnapshotStore) RecordCreated(snap *Snapshot) error {
+	current, err := scanGameDir(snap.GameDir)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(snap.Files))
+	for _, f := range snap.Files {
+		known[f.Path] = true
+	}
+	currentByPath := make(map[string]SnapshotFile, len(current))
+	var created []string
+	for _, f := range current {
+		currentByPath[f.Path] = f
+		if !known[f.Path] {
+			created = append(created, f.Path)
+		}
+	}
+	var changed []string
+	for _, f := range snap.Files {
+		if cur, ok := currentByPath[f.Path]; !ok || !sameFile(cur, f) {
+			changed = append(changed, f.Path)
+		}
+	}
+
+	snap.Created = mergePaths(snap.Created, created)
+	snap.Changed = mergePaths(snap.Changed, changed)
+	snap.Recorded = true
+	return snap.writeManifest()
+}
+
+// mergePaths returns the sorted union of two path lists.
+func mergePaths(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var merged []string
+	for _, p := range append(append([]string{}, a...), b...) {
+		if !seen[p] {
+			seen[p] = true
+			merged = append(merged, p)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// List returns snapshots of gameDir, newest first.
+// An empty gameDir returns snapshots of every game directory.
+func (s *SnapshotStore) List(gameDir string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	var snaps []Snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.Dir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, snapshotManifest))
+		if err != nil {
+			continue
+		}
+		var snap Snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			continue
+		}
+		if gameDir != "" && filepath.Clean(snap.GameDir) != filepath.Clean(gameDir) {
+			continue
+		}
+		snap.dir = dir
+		snaps = append(snaps, snap)
+	}
+
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].CreatedAt.After(snaps[j].CreatedAt)
+	})
+
+	return snaps, nil
+}
+
+// Latest returns the most recent snapshot of gameDir.
+func (s *SnapshotStore) Latest(gameDir string) (*Snapshot, error) {
+	snaps, err := s.List(gameDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("no snapshots found for %s", gameDir)
+	}
+	return &snaps[0], nil
+}
+
+// Original returns the oldest unpatched snapshot of gameDir, falling back
+// to the oldest snapshot if the game version could not be determined.
+func (s *SnapshotStore) Original(gameDir string) (*Snapshot, error) {
+	snaps, err := s.List(gameDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("no snapshots found for %s", gameDir)
+	}
+
+	for i := len(snaps) - 1; i >= 0; i-- {
+		if snaps[i].GameVersion == OriginalGameVersion {
+			return &snaps[i], nil
+		}
+	}
+	return &snaps[len(snaps)-1], nil
+}
+
+// Restore undoes the patch runs since the snapshot. Files that patch runs
+// changed since any snapshot of the game directory are put back as they are
+// in the snapshot, and the files they created are removed; other files, such
+// as saves, configs and mods, are left alone. If a snapshot of the game
+// directory predates recording changes, every snapshotted file that differs
+// is put back instead.
+func (s *SnapshotStore) Restore(snap *Snapshot) error {
+	current, err := scanGameDir(snap.GameDir)
+	if err != nil {
+		return err
+	}
+
+	snaps, err := s.List(snap.GameDir)
+	if err != nil {
+		return err
+	}
+	created, changed := snap.Created, snap.Changed
+	recorded := snap.Recorded
+	for _, other := range snaps {
+		created = mergePaths(created, other.Created)
+		changed = mergePaths(changed, other.Changed)
+		recorded = recorded && other.Recorded
+	}
+	patched := make(map[string]bool, len(changed))
+	for _, path := range changed {
+		patched[path] = true
+	}
+
+	keep := make(map[string]bool, len(snap.Files))
+	for _, f := range snap.Files {
+		keep[f.Path] = true
+	}
+	present := make(map[string]bool, len(current))
+	for _, f := range current {
+		present[f.Path] = true
+	}
+
+	// Remove files patch runs created that the snapshot doesn't have
+	for _, path := range created {
+		if keep[path] || !present[path] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(snap.GameDir, path)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removeEmptyParents(snap.GameDir, path)
+	}
+
+	currentByPath := make(map[string]SnapshotFile, len(current))
+	for _, f := range current {
+		currentByPath[f.Path] = f
+	}
+
+	for _, f := range snap.Files {
+		if recorded && !patched[f.Path] {
+			continue
+		}
+		if cur, ok := currentByPath[f.Path]; ok && sameFile(cur, f) {
+			continue
+		}
+		src := filepath.Join(snap.dir, snapshotFilesDir, f.Path)
+		if _, err := archive.CloneFile(src, filepath.Join(snap.GameDir, f.Path)); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", f.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// Prune removes snapshots of gameDir except the original and the newest keep.
+func (s *SnapshotStore) Prune(gameDir string, keep int) error {
+	snaps, err := s.List(gameDir)
+	if err != nil {
+		return err
+	}
+
+	original, err := s.Original(gameDir)
+	if err != nil {
+		return nil
+	}
+
+	for i, snap := range snaps {
+		if i < keep || snap.ID == original.ID {
+			continue
+		}
+		// Keep the record of files patch runs created and changed for Restore
+		original.Created = mergePaths(original.Created, snap.Created)
+		original.Changed = mergePaths(original.Changed, snap.Changed)
+		original.Recorded = original.Recorded && snap.Recorded
+		if err := original.writeManifest(); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(snap.dir); err != nil {
+			return fmt.Errorf("failed to remove snapshot %s: %w", snap.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// scanGameDir lists the regular files in gameDir.
+// Patcher executables are skipped since they are downloaded on demand, and so
+// are the staging files of an interrupted native patch.
+func scanGameDir(gameDir string) ([]SnapshotFile, error) {
+	var files []SnapshotFile
+
+	err := filepath.Walk(gameDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if isPatcherName(info.Name()) || isStagingName(info.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(gameDir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, SnapshotFile{
+			Path:    rel,
+			Size:    info.Size(),
+			Mode:    info.Mode().Perm(),
+			ModTime: info.ModTime().UTC(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan game directory: %w", err)
+	}
+
+	return files, nil
+}
+
+// sameFile reports whether two file records are indistinguishable.
+func sameFile(a, b SnapshotFile) bool {
+	return a.Path == b.Path && a.Size == b.Size && a.ModTime.Equal(b.ModTime)
+}
+
+// sameFiles reports whether two file lists describe the same directory.
+func sameFiles(a, b []SnapshotFile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameFile(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// removeEmptyParents removes the directories of the file at rel inside root
+// that are left empty, deepest first.
+func removeEmptyParents(root string, rel string) {
+	for dir := filepath.Dir(rel); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if os.Remove(filepath.Join(root, dir)) != nil {
+			return
+		}
+	}
+}
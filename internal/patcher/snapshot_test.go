@@ -0,0 +1,253 @@
+package patcher_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/patcher"
+)
+
+var _ = Describe("SnapshotStore", func() {
+	var tmpDir string
+	var gameDir string
+	var store *patcher.SnapshotStore
+
+	writeFile := func(rel, content string) {
+		path := filepath.Join(gameDir, rel)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	readFile := func(rel string) string {
+		data, err := os.ReadFile(filepath.Join(gameDir, rel))
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "patcher-snapshot-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		gameDir = filepath.Join(tmpDir, "game")
+		store = patcher.NewSnapshotStore(filepath.Join(tmpDir, "snapshots"))
+
+		writeFile("Game.exe", "original exe")
+		writeFile("Content/data.xnb", "original data")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("Create", func() {
+		It("should capture every game file", func() {
+			snap, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snap.Files).To(HaveLen(2))
+			Expect(filepath.Join(snap.Dir(), "files", "Content", "data.xnb")).To(BeAnExistingFile())
+		})
+
+		It("should skip patcher executables and native patch staging files", func() {
+			writeFile("LADXHD.Patcher.exe", "patcher")
+			writeFile("Game.exe.patch-new", "staged")
+
+			snap, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snap.Files).To(HaveLen(2))
+		})
+
+		It("should reuse a snapshot of identical contents", func() {
+			first, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			second, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.ID).To(Equal(first.ID))
+
+			snaps, err := store.List(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snaps).To(HaveLen(1))
+		})
+	})
+
+	Describe("Restore", func() {
+		It("should undo modified, added and deleted files", func() {
+			snap, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			writeFile("Game.exe", "patched exe")
+			writeFile("Content/new.xnb", "new data")
+			writeFile("Patch/extra.dll", "extra")
+			Expect(os.Remove(filepath.Join(gameDir, "Content", "data.xnb"))).To(Succeed())
+			Expect(store.RecordCreated(snap)).To(Succeed())
+
+			Expect(store.Restore(snap)).To(Succeed())
+
+			Expect(readFile("Game.exe")).To(Equal("original exe"))
+			Expect(readFile("Content/data.xnb")).To(Equal("original data"))
+			Expect(filepath.Join(gameDir, "Content", "new.xnb")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(gameDir, "Patch")).NotTo(BeAnExistingFile())
+		})
+
+		It("should keep files the patch run didn't create", func() {
+			snap, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			writeFile("Game.exe", "patched exe")
+			Expect(store.RecordCreated(snap)).To(Succeed())
+
+			// Saves and mods added later aren't the patcher's
+			writeFile("Saves/slot1.sav", "save")
+			writeFile("Mods/mod.dll", "mod")
+
+			Expect(store.Restore(snap)).To(Succeed())
+
+			Expect(readFile("Game.exe")).To(Equal("original exe"))
+			Expect(readFile("Saves/slot1.sav")).To(Equal("save"))
+			Expect(readFile("Mods/mod.dll")).To(Equal("mod"))
+		})
+
+		It("should keep changes to snapshotted files the patch run didn't make", func() {
+			writeFile("Saves/slot1.sav", "old save")
+			writeFile("settings.ini", "old settings")
+			snap, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			writeFile("Game.exe", "patched exe")
+			Expect(store.RecordCreated(snap)).To(Succeed())
+			Expect(snap.Changed).To(Equal([]string{"Game.exe"}))
+
+			// The player keeps playing after patching
+			writeFile("Saves/slot1.sav", "new save")
+			Expect(os.Remove(filepath.Join(gameDir, "settings.ini"))).To(Succeed())
+
+			Expect(store.Restore(snap)).To(Succeed())
+
+			Expect(readFile("Game.exe")).To(Equal("original exe"))
+			Expect(readFile("Saves/slot1.sav")).To(Equal("new save"))
+			Expect(filepath.Join(gameDir, "settings.ini")).NotTo(BeAnExistingFile())
+		})
+
+		It("should undo changes made by patch runs after newer snapshots", func() {
+			original, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			writeFile("Game.exe", "v1 exe")
+			Expect(store.RecordCreated(original)).To(Succeed())
+
+			newer, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			writeFile("Content/data.xnb", "v2 data")
+			Expect(store.RecordCreated(newer)).To(Succeed())
+
+			Expect(store.Restore(original)).To(Succeed())
+
+			Expect(readFile("Game.exe")).To(Equal("original exe"))
+			Expect(readFile("Content/data.xnb")).To(Equal("original data"))
+		})
+
+		It("should remove files created by patch runs after newer snapshots", func() {
+			original, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			writeFile("v1.dll", "v1")
+			Expect(store.RecordCreated(original)).To(Succeed())
+
+			newer, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			writeFile("v2.dll", "v2")
+			Expect(store.RecordCreated(newer)).To(Succeed())
+
+			Expect(store.Restore(original)).To(Succeed())
+
+			Expect(filepath.Join(gameDir, "v1.dll")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(gameDir, "v2.dll")).NotTo(BeAnExistingFile())
+		})
+
+		It("should not be affected by in-place writes after the snapshot", func() {
+			snap, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			f, err := os.OpenFile(filepath.Join(gameDir, "Game.exe"), os.O_WRONLY, 0)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.WriteAt([]byte("PATCHED"), 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			Expect(store.Restore(snap)).To(Succeed())
+			Expect(readFile("Game.exe")).To(Equal("original exe"))
+		})
+	})
+
+	Describe("Latest and Original", func() {
+		It("should return newest and oldest snapshots", func() {
+			first, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			writeFile("Game.exe", "patched exe")
+			second, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			latest, err := store.Latest(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latest.ID).To(Equal(second.ID))
+
+			original, err := store.Original(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(original.ID).To(Equal(first.ID))
+		})
+
+		It("should return error when no snapshots exist", func() {
+			_, err := store.Latest(gameDir)
+			Expect(err).To(HaveOccurred())
+
+			_, err = store.Original(gameDir)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should only list snapshots of the given game directory", func() {
+			_, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			snaps, err := store.List(filepath.Join(tmpDir, "other"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snaps).To(BeEmpty())
+		})
+	})
+
+	Describe("Prune", func() {
+		It("should keep the original and the newest snapshots", func() {
+			original, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			for _, content := range []string{"v1", "v2", "v3"} {
+				writeFile("Game.exe", content)
+				_, err := store.Create(gameDir)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(store.Prune(gameDir, 1)).To(Succeed())
+
+			snaps, err := store.List(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snaps).To(HaveLen(2))
+			Expect(snaps[1].ID).To(Equal(original.ID))
+		})
+
+		It("should keep the created files of pruned snapshots", func() {
+			original, err := store.Create(gameDir)
+			Expect(err).NotTo(HaveOccurred())
+			for _, name := range []string{"v1.dll", "v2.dll"} {
+				snap, err := store.Create(gameDir)
+				Expect(err).NotTo(HaveOccurred())
+				writeFile(name, name)
+				Expect(store.RecordCreated(snap)).To(Succeed())
+			}
+
+			Expect(store.Prune(gameDir, 0)).To(Succeed())
+			Expect(store.Restore(original)).To(Succeed())
+
+			Expect(filepath.Join(gameDir, "v1.dll")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(gameDir, "v2.dll")).NotTo(BeAnExistingFile())
+		})
+	})
+})
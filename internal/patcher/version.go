@@ -0,0 +1,382 @@
+package patcher
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/jslay88/zladxhd-installer/internal/release"
+)
+
+// OriginalGameVersion is the version of the unpatched game release.
+const OriginalGameVersion = "1.0.0"
+
+// gameExecutableNames are the known names of the game executable.
+var gameExecutableNames = []string{
+	"Link's Awakening DX HD.exe",
+	"LADXHD.exe",
+}
+
+// ExeVersion holds the version resource of a Windows executable.
+type ExeVersion struct {
+	// FileVersion is the numeric file version from the fixed version info.
+	FileVersion string
+	// ProductVersion is the numeric product version from the fixed version info.
+	ProductVersion string
+	// Strings holds the StringFileInfo entries (ProductVersion, FileDescription, ...).
+	Strings map[string]string
+}
+
+// GameVersion describes the version of an installed game.
+type GameVersion struct {
+	// ExePath is the path to the game executable that was inspected.
+	ExePath string
+	// Version is the normalized game version (e.g. "1.2.3").
+	Version string
+	// Patched is true if a patch record matches the game executable.
+	Patched bool
+	// PatcherVersion is the patcher release recorded for a patched game.
+	PatcherVersion string
+}
+
+// FindGameExecutable finds the main game executable in the game directory.
+// Patcher executables are never returned.
+func FindGameExecutable(gameDir string) (string, error) {
+	patterns := append(append([]string{}, gameExecutableNames...), "*.exe")
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(gameDir, pattern))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			if isPatcherName(filepath.Base(match)) {
+				continue
+			}
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			return match, nil
+		}
+	}
+
+	return "", fmt.Errorf("game executable not found in %s", gameDir)
+}
+
+// isPatcherName checks if a file name looks like the patcher executable.
+func isPatcherName(name string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(PatcherNamePattern))
+}
+
+// DetectVersion detects the game version installed in gameDir.
+func DetectVersion(gameDir string) (*GameVersion, error) {
+	exePath, err := FindGameExecutable(gameDir)
+	if err != nil {
+		return nil, err
+	}
+
+	exeVersion, err := ReadExeVersion(exePath)
+	if err != nil {
+		return nil, err
+	}
+
+	version := exeVersion.Version()
+	if version == "" {
+		return nil, fmt.Errorf("no version information in %s", filepath.Base(exePath))
+	}
+
+	v := &GameVersion{ExePath: exePath, Version: version}
+	if record := verifiedPatch(gameDir, exePath); record != nil {
+		v.Patched = true
+		v.PatcherVersion = record.PatcherVersion
+	}
+	return v, nil
+}
+
+// String returns a human-readable description of the game version.
+func (v *GameVersion) String() string {
+	switch {
+	case v.Patched:
+		return fmt.Sprintf("%s (patched)", v.Version)
+	case release.CompareTags(v.Version, OriginalGameVersion) > 0:
+		return fmt.Sprintf("%s (no verified patch record)", v.Version)
+	default:
+		return fmt.Sprintf("%s (unpatched)", v.Version)
+	}
+}
+
+// Version returns the normalized version of the executable.
+// The ProductVersion string is preferred, falling back to the fixed
+// product and file versions. Trailing ".0" components beyond the third
+// and any "+build" metadata are removed.
+func (v *ExeVersion) Version() string {
+	candidates := []string{
+		v.Strings["ProductVersion"],
+		v.ProductVersion,
+		v.Strings["FileVersion"],
+		v.FileVersion,
+	}
+
+	for _, c := range candidates {
+		c = strings.TrimSpace(c)
+		if idx := strings.IndexAny(c, "+ "); idx != -1 {
+			c = c[:idx]
+		}
+		c = strings.TrimPrefix(strings.TrimPrefix(c, "v"), "V")
+		if c == "" || c == "0.0.0.0" {
+			continue
+		}
+		parts := strings.Split(c, ".")
+		for len(parts) > 3 && parts[len(parts)-1] == "0" {
+			parts = parts[:len(parts)-1]
+		}
+		return strings.Join(parts, ".")
+	}
+
+	return ""
+}
+
+// rtVersion is the resource type ID of version resources.
+const rtVersion = 16
+
+// ReadExeVersion reads the version resource of a PE executable.
+func ReadExeVersion(path string) (*ExeVersion, error) {
+	f, err := pe.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open executable: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	section := f.Section(".rsrc")
+	if section == nil {
+		return nil, fmt.Errorf("no resource section in %s", filepath.Base(path))
+	}
+
+	data, err := section.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource section: %w", err)
+	}
+
+	raw, err := findVersionResource(data, section.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseVersionInfo(raw)
+}
+
+// findVersionResource walks the resource directory tree (type, name,
+// language) to the first RT_VERSION resource and returns its raw data.
+func findVersionResource(rsrc []byte, sectionRVA uint32) ([]byte, error) {
+	entries, err := resourceDirEntries(rsrc, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var offset uint32
+	found := false
+	for _, e := range entries {
+		if e.name == rtVersion && e.isDir {
+			offset = e.offset
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no version resource found")
+	}
+
+	// Descend through the name and language levels, taking the first entry.
+	for level := 0; level < 2; level++ {
+		entries, err := resourceDirEntries(rsrc, offset)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("empty version resource directory")
+		}
+		offset = entries[0].offset
+		if !entries[0].isDir {
+			break
+		}
+	}
+
+	// IMAGE_RESOURCE_DATA_ENTRY: OffsetToData (RVA), Size, CodePage, Reserved
+	if int(offset)+16 > len(rsrc) {
+		return nil, fmt.Errorf("invalid resource data entry")
+	}
+	dataRVA := binary.LittleEndian.Uint32(rsrc[offset:])
+	size := binary.LittleEndian.Uint32(rsrc[offset+4:])
+
+	start := int64(dataRVA) - int64(sectionRVA)
+	if start < 0 || start+int64(size) > int64(len(rsrc)) {
+		return nil, fmt.Errorf("version resource out of bounds")
+	}
+
+	return rsrc[start : start+int64(size)], nil
+}
+
+// resourceDirEntry is an entry of an IMAGE_RESOURCE_DIRECTORY.
+type resourceDirEntry struct {
+	name   uint32
+	offset uint32
+	isDir  bool
+}
+
+// resourceDirEntries reads the entries of the resource directory at offset.
+func resourceDirEntries(rsrc []byte, offset uint32) ([]resourceDirEntry, error) {
+	if int(offset)+16 > len(rsrc) {
+		return nil, fmt.Errorf("invalid resource directory")
+	}
+
+	named := binary.LittleEndian.Uint16(rsrc[offset+12:])
+	ids := binary.LittleEndian.Uint16(rsrc[offset+14:])
+	count := int(named) + int(ids)
+
+	entries := make([]resourceDirEntry, 0, count)
+	pos := int(offset) + 16
+	for i := 0; i < count; i++ {
+		if pos+8 > len(rsrc) {
+			return nil, fmt.Errorf("invalid resource directory entry")
+		}
+		name := binary.LittleEndian.Uint32(rsrc[pos:])
+		target := binary.LittleEndian.Uint32(rsrc[pos+4:])
+		entries = append(entries, resourceDirEntry{
+			name:   name,
+			offset: target &^ 0x80000000,
+			isDir:  target&0x80000000 != 0,
+		})
+		pos += 8
+	}
+
+	return entries, nil
+}
+
+// vsFixedFileInfoSignature identifies a VS_FIXEDFILEINFO structure.
+const vsFixedFileInfoSignature = 0xFEEF04BD
+
+// versionBlock is a node of the VS_VERSIONINFO tree.
+type versionBlock struct {
+	key      string
+	isText   bool
+	value    []byte
+	children []versionBlock
+}
+
+// ParseVersionInfo parses a raw VS_VERSIONINFO resource.
+func ParseVersionInfo(data []byte) (*ExeVersion, error) {
+	root, _, err := parseVersionBlock(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if root.key != "VS_VERSION_INFO" {
+		return nil, fmt.Errorf("invalid version resource key: %q", root.key)
+	}
+
+	v := &ExeVersion{Strings: make(map[string]string)}
+
+	if len(root.value) >= 52 && binary.LittleEndian.Uint32(root.value) == vsFixedFileInfoSignature {
+		v.FileVersion = fixedVersion(root.value[8:16])
+		v.ProductVersion = fixedVersion(root.value[16:24])
+	}
+
+	for _, child := range root.children {
+		if child.key != "StringFileInfo" {
+			continue
+		}
+		for _, table := range child.children {
+			for _, str := range table.children {
+				if _, exists := v.Strings[str.key]; !exists {
+					v.Strings[str.key] = decodeUTF16(str.value)
+				}
+			}
+		}
+	}
+
+	return v, nil
+}
+
+// fixedVersion formats the MS/LS version pair of VS_FIXEDFILEINFO.
+func fixedVersion(b []byte) string {
+	ms := binary.LittleEndian.Uint32(b)
+	ls := binary.LittleEndian.Uint32(b[4:])
+	return fmt.Sprintf("%d.%d.%d.%d", ms>>16, ms&0xFFFF, ls>>16, ls&0xFFFF)
+}
+
+// parseVersionBlock parses a version block starting at offset.
+// Returns the block and the offset just past its end.
+func parseVersionBlock(data []byte, offset int) (versionBlock, int, error) {
+	if offset+6 > len(data) {
+		return versionBlock{}, 0, fmt.Errorf("truncated version block")
+	}
+
+	length := int(binary.LittleEndian.Uint16(data[offset:]))
+	valueLength := int(binary.LittleEndian.Uint16(data[offset+2:]))
+	isText := binary.LittleEndian.Uint16(data[offset+4:]) == 1
+
+	end := offset + length
+	if length < 6 || end > len(data) {
+		return versionBlock{}, 0, fmt.Errorf("invalid version block length")
+	}
+
+	block := versionBlock{isText: isText}
+
+	// Key is a null-terminated UTF-16 string
+	pos := offset + 6
+	keyStart := pos
+	for pos+1 < end && (data[pos] != 0 || data[pos+1] != 0) {
+		pos += 2
+	}
+	block.key = decodeUTF16(data[keyStart:pos])
+	pos = align4(pos + 2)
+
+	// Text values are measured in UTF-16 code units, binary values in bytes
+	if isText {
+		valueLength *= 2
+	}
+	if valueLength > 0 {
+		valueEnd := pos + valueLength
+		if valueEnd > end {
+			valueEnd = end
+		}
+		if pos < valueEnd {
+			block.value = data[pos:valueEnd]
+		}
+		pos = valueEnd
+	}
+	pos = align4(pos)
+
+	for pos < end {
+		child, next, err := parseVersionBlock(data, pos)
+		if err != nil {
+			return versionBlock{}, 0, err
+		}
+		block.children = append(block.children, child)
+		pos = align4(next)
+	}
+
+	return block, end, nil
+}
+
+// align4 rounds n up to a multiple of 4.
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// decodeUTF16 decodes little-endian UTF-16, stopping at the first null.
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := binary.LittleEndian.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,263 @@
+package patcher_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/patcher"
+)
+
+// utf16z encodes s as null-terminated little-endian UTF-16.
+func utf16z(s string) []byte {
+	var buf bytes.Buffer
+	for _, u := range utf16.Encode([]rune(s)) {
+		_ = binary.Write(&buf, binary.LittleEndian, u)
+	}
+	buf.Write([]byte{0, 0})
+	return buf.Bytes()
+}
+
+// pad4 pads buf to a multiple of 4 bytes.
+func pad4(buf *bytes.Buffer) {
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+}
+
+// versionBlock builds a VS_VERSIONINFO style block.
+func versionBlock(key string, value []byte, isText bool, children ...[]byte) []byte {
+	var body bytes.Buffer
+	body.Write(make([]byte, 6))
+	body.Write(utf16z(key))
+	pad4(&body)
+	body.Write(value)
+	for _, child := range children {
+		pad4(&body)
+		body.Write(child)
+	}
+
+	data := body.Bytes()
+	valueLength := len(value)
+	textFlag := uint16(0)
+	if isText {
+		valueLength /= 2
+		textFlag = 1
+	}
+	binary.LittleEndian.PutUint16(data[0:], uint16(len(data)))
+	binary.LittleEndian.PutUint16(data[2:], uint16(valueLength))
+	binary.LittleEndian.PutUint16(data[4:], textFlag)
+	return data
+}
+
+// buildVersionInfo builds a VS_VERSIONINFO resource with the given versions.
+func buildVersionInfo(fileVersion [4]uint16, productVersion string) []byte {
+	fixed := make([]byte, 52)
+	binary.LittleEndian.PutUint32(fixed[0:], 0xFEEF04BD)
+	binary.LittleEndian.PutUint32(fixed[4:], 0x00010000)
+	binary.LittleEndian.PutUint32(fixed[8:], uint32(fileVersion[0])<<16|uint32(fileVersion[1]))
+	binary.LittleEndian.PutUint32(fixed[12:], uint32(fileVersion[2])<<16|uint32(fileVersion[3]))
+	binary.LittleEndian.PutUint32(fixed[16:], uint32(fileVersion[0])<<16|uint32(fileVersion[1]))
+	binary.LittleEndian.PutUint32(fixed[20:], uint32(fileVersion[2])<<16|uint32(fileVersion[3]))
+
+	var strs [][]byte
+	if productVersion != "" {
+		strs = append(strs, versionBlock("ProductVersion", utf16z(productVersion), true))
+	}
+	strs = append(strs, versionBlock("FileDescription", utf16z("Link's Awakening DX HD"), true))
+
+	table := versionBlock("040904b0", nil, true, strs...)
+	stringInfo := versionBlock("StringFileInfo", nil, true, table)
+	return versionBlock("VS_VERSION_INFO", fixed, false, stringInfo)
+}
+
+// buildPE builds a minimal PE32+ executable whose only section is .rsrc
+// holding the given version resource.
+func buildPE(versionInfo []byte) []byte {
+	const sectionRVA = 0x1000
+	const rawOffset = 0x200
+
+	// Resource tree: type (RT_VERSION) -> name (1) -> language (0x409) -> data
+	rsrc := make([]byte, 88)
+	dir := func(off int, name uint32, target uint32) {
+		binary.LittleEndian.PutUint16(rsrc[off+14:], 1)
+		binary.LittleEndian.PutUint32(rsrc[off+16:], name)
+		binary.LittleEndian.PutUint32(rsrc[off+20:], target)
+	}
+	dir(0, 16, 0x80000000|24)
+	dir(24, 1, 0x80000000|48)
+	dir(48, 0x409, 72)
+	binary.LittleEndian.PutUint32(rsrc[72:], sectionRVA+88)
+	binary.LittleEndian.PutUint32(rsrc[76:], uint32(len(versionInfo)))
+	rsrc = append(rsrc, versionInfo...)
+	for len(rsrc)%0x200 != 0 {
+		rsrc = append(rsrc, 0)
+	}
+
+	var buf bytes.Buffer
+	dos := make([]byte, 64)
+	copy(dos, "MZ")
+	binary.LittleEndian.PutUint32(dos[0x3c:], 64)
+	buf.Write(dos)
+	buf.WriteString("PE\x00\x00")
+
+	// COFF file header
+	_ = binary.Write(&buf, binary.LittleEndian, struct {
+		Machine, NumberOfSections                         uint16
+		TimeDateStamp, PointerToSymbolTable, NumberOfSyms uint32
+		SizeOfOptionalHeader, Characteristics             uint16
+	}{0x8664, 1, 0, 0, 0, 240, 0x22})
+
+	// PE32+ optional header with 16 empty data directories
+	opt := make([]byte, 240)
+	binary.LittleEndian.PutUint16(opt[0:], 0x20b)
+	binary.LittleEndian.PutUint32(opt[32:], 0x1000) // SectionAlignment
+	binary.LittleEndian.PutUint32(opt[36:], 0x200)  // FileAlignment
+	binary.LittleEndian.PutUint32(opt[108:], 16)    // NumberOfRvaAndSizes
+	buf.Write(opt)
+
+	// Section header
+	section := make([]byte, 40)
+	copy(section, ".rsrc")
+	binary.LittleEndian.PutUint32(section[8:], uint32(len(rsrc)))
+	binary.LittleEndian.PutUint32(section[12:], sectionRVA)
+	binary.LittleEndian.PutUint32(section[16:], uint32(len(rsrc)))
+	binary.LittleEndian.PutUint32(section[20:], rawOffset)
+	buf.Write(section)
+
+	for buf.Len() < rawOffset {
+		buf.WriteByte(0)
+	}
+	buf.Write(rsrc)
+	return buf.Bytes()
+}
+
+var _ = Describe("Version", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "patcher-version-test-*")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("ParseVersionInfo", func() {
+		It("should read fixed and string versions", func() {
+			v, err := patcher.ParseVersionInfo(buildVersionInfo([4]uint16{1, 2, 3, 0}, "1.2.3+abcdef"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.FileVersion).To(Equal("1.2.3.0"))
+			Expect(v.ProductVersion).To(Equal("1.2.3.0"))
+			Expect(v.Strings).To(HaveKeyWithValue("ProductVersion", "1.2.3+abcdef"))
+			Expect(v.Strings).To(HaveKeyWithValue("FileDescription", "Link's Awakening DX HD"))
+			Expect(v.Version()).To(Equal("1.2.3"))
+		})
+
+		It("should fall back to the fixed version", func() {
+			v, err := patcher.ParseVersionInfo(buildVersionInfo([4]uint16{1, 0, 0, 0}, ""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.Version()).To(Equal("1.0.0"))
+		})
+
+		It("should reject invalid data", func() {
+			_, err := patcher.ParseVersionInfo([]byte{1, 2, 3})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ReadExeVersion", func() {
+		It("should read the version resource from a PE file", func() {
+			exe := filepath.Join(tmpDir, "game.exe")
+			err := os.WriteFile(exe, buildPE(buildVersionInfo([4]uint16{1, 4, 2, 0}, "")), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			v, err := patcher.ReadExeVersion(exe)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.FileVersion).To(Equal("1.4.2.0"))
+		})
+
+		It("should return error for non-PE files", func() {
+			exe := filepath.Join(tmpDir, "fake.exe")
+			err := os.WriteFile(exe, []byte("not an executable"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patcher.ReadExeVersion(exe)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("DetectVersion", func() {
+		It("should report an unpatched game", func() {
+			err := os.WriteFile(filepath.Join(tmpDir, "Link's Awakening DX HD.exe"), buildPE(buildVersionInfo([4]uint16{1, 0, 0, 0}, "")), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			v, err := patcher.DetectVersion(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.Version).To(Equal("1.0.0"))
+			Expect(v.Patched).To(BeFalse())
+			Expect(v.String()).To(ContainSubstring("unpatched"))
+
+			_, err = patcher.NewPatcher(tmpDir, tmpDir).Verify()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should report a patched game", func() {
+			err := os.WriteFile(filepath.Join(tmpDir, "Link's Awakening DX HD.exe"), buildPE(buildVersionInfo([4]uint16{1, 1, 4, 0}, "")), 0644)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patcher.WritePatchRecord(tmpDir, "v1.1.4")).To(Succeed())
+
+			v, err := patcher.NewPatcher(tmpDir, tmpDir).Verify()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.Version).To(Equal("1.1.4"))
+			Expect(v.Patched).To(BeTrue())
+			Expect(v.PatcherVersion).To(Equal("v1.1.4"))
+		})
+
+		It("should not trust the version number without a patch record", func() {
+			err := os.WriteFile(filepath.Join(tmpDir, "Link's Awakening DX HD.exe"), buildPE(buildVersionInfo([4]uint16{1, 1, 4, 0}, "")), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			v, err := patcher.NewPatcher(tmpDir, tmpDir).Verify()
+			Expect(err).To(MatchError(ContainSubstring("no verified patch")))
+			Expect(v.Patched).To(BeFalse())
+			Expect(v.String()).To(ContainSubstring("no verified patch record"))
+		})
+
+		It("should ignore a patch record for a different executable", func() {
+			exePath := filepath.Join(tmpDir, "Link's Awakening DX HD.exe")
+			Expect(os.WriteFile(exePath, buildPE(buildVersionInfo([4]uint16{1, 1, 4, 0}, "")), 0644)).To(Succeed())
+			Expect(patcher.WritePatchRecord(tmpDir, "v1.1.4")).To(Succeed())
+			Expect(os.WriteFile(exePath, buildPE(buildVersionInfo([4]uint16{1, 0, 0, 0}, "")), 0644)).To(Succeed())
+
+			v, err := patcher.DetectVersion(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.Patched).To(BeFalse())
+		})
+	})
+
+	Describe("FindGameExecutable", func() {
+		It("should skip the patcher executable", func() {
+			err := os.WriteFile(filepath.Join(tmpDir, "LADXHD.Patcher.exe"), []byte("x"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+			err = os.WriteFile(filepath.Join(tmpDir, "Game.exe"), []byte("x"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			exe, err := patcher.FindGameExecutable(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.Base(exe)).To(Equal("Game.exe"))
+		})
+
+		It("should return error when no executable exists", func() {
+			_, err := patcher.FindGameExecutable(tmpDir)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,163 @@
+package proton
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+// MigrateAppID moves a shortcut from oldID to newID, e.g. from a random AppID
+// to the one Steam derives from the shortcut (see steam.ShortcutAppID). The
+// shortcut itself, the CompatToolMapping entry in config.vdf, the artwork in
+// the grid directory and the compatdata directory are moved, in that order.
+// If a step fails, the steps already done are undone. Steam must not be
+// running.
+func MigrateAppID(s *steam.Steam, user *steam.User, oldID, newID uint32) error {
+	if oldID == newID {
+		return nil
+	}
+
+	oldCompatData := filepath.Join(s.CompatPath, strconv.FormatUint(uint64(oldID), 10))
+	newCompatData := filepath.Join(s.CompatPath, strconv.FormatUint(uint64(newID), 10))
+	if archive.FileExists(newCompatData) {
+		return fmt.Errorf("compatdata for AppID %d already exists: %s", newID, newCompatData)
+	}
+
+	var undo []func() error
+	rollback := func(err error) error {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				err = fmt.Errorf("%w (undoing the AppID change also failed: %v)", err, undoErr)
+			}
+		}
+		return err
+	}
+
+	if err := steam.ChangeShortcutAppID(user, oldID, newID); err != nil {
+		return err
+	}
+	undo = append(undo, func() error { return steam.ChangeShortcutAppID(user, newID, oldID) })
+
+	moved, err := renameCompatToolMapping(s, oldID, newID)
+	if err != nil {
+		return rollback(err)
+	}
+	if moved {
+		undo = append(undo, func() error {
+			_, err := renameCompatToolMapping(s, newID, oldID)
+			return err
+		})
+	}
+
+	renamed, err := renameGridArtwork(user, oldID, newID)
+	if err != nil {
+		return rollback(err)
+	}
+	undo = append(undo, func() error { return undoRenames(renamed) })
+
+	// The prefix goes last: it's the largest change and a rename can't fail half-way
+	if archive.FileExists(oldCompatData) {
+		if err := os.Rename(oldCompatData, newCompatData); err != nil {
+			return rollback(fmt.Errorf("failed to move compatdata: %w", err))
+		}
+	}
+	return nil
+}
+
+// rename is a rename done by MigrateAppID, from old to new.
+type rename struct {
+	old, new string
+}
+
+// undoRenames reverts renames, newest first.
+func undoRenames(renames []rename) error {
+	for i := len(renames) - 1; i >= 0; i-- {
+		if err := os.Rename(renames[i].new, renames[i].old); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", filepath.Base(renames[i].old), err)
+		}
+	}
+	return nil
+}
+
+// renameCompatToolMapping moves an app's entry in config.vdf's
+// CompatToolMapping to a new AppID. Returns whether there was an entry to move.
+func renameCompatToolMapping(s *steam.Steam, oldID, newID uint32) (bool, error) {
+	configPath := filepath.Join(s.ConfigPath, "config.vdf")
+	doc, state, err := steam.ReadVDF(configPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config.vdf: %w", err)
+	}
+	if doc == nil {
+		return false, nil
+	}
+
+	node := doc.Get("InstallConfigStore")
+	for _, key := range []string{"Software", "Valve", "Steam", "CompatToolMapping"} {
+		if node == nil {
+			return false, nil
+		}
+		node = node.GetObject(key)
+	}
+	if node == nil {
+		return false, nil
+	}
+
+	entry := node.GetObject(strconv.FormatUint(uint64(oldID), 10))
+	if entry == nil {
+		return false, nil
+	}
+	node.Remove(strconv.FormatUint(uint64(newID), 10))
+	entry.Key = strconv.FormatUint(uint64(newID), 10)
+
+	if err := steam.WriteVDF(configPath, doc, state); err != nil {
+		return false, fmt.Errorf("failed to write config.vdf: %w", err)
+	}
+	return true, nil
+}
+
+// renameGridArtwork renames the artwork of a shortcut, named after its AppID
+// ("<appid>p.png", "<appid>_hero.jpg", ...) or, for old Big Picture images,
+// its 64-bit game ID. Existing artwork is never overwritten. Returns the
+// renames done; on failure they are undone.
+func renameGridArtwork(user *steam.User, oldID, newID uint32) ([]rename, error) {
+	entries, err := os.ReadDir(user.GridPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grid directory: %w", err)
+	}
+
+	prefixes := map[string]string{
+		strconv.FormatUint(steam.ShortcutGameID(oldID), 10): strconv.FormatUint(steam.ShortcutGameID(newID), 10),
+		strconv.FormatUint(uint64(oldID), 10):               strconv.FormatUint(uint64(newID), 10),
+	}
+	var renamed []rename
+	for _, entry := range entries {
+		name := entry.Name()
+		for oldPrefix, newPrefix := range prefixes {
+			rest, ok := strings.CutPrefix(name, oldPrefix)
+			// The ID must be followed by a suffix or extension, not more digits
+			if !ok || rest == "" || (rest[0] >= '0' && rest[0] <= '9') {
+				continue
+			}
+			r := rename{old: filepath.Join(user.GridPath(), name), new: filepath.Join(user.GridPath(), newPrefix+rest)}
+			if _, err := os.Lstat(r.new); err == nil {
+				_ = undoRenames(renamed)
+				return nil, fmt.Errorf("artwork %s already exists", newPrefix+rest)
+			}
+			if err := os.Rename(r.old, r.new); err != nil {
+				_ = undoRenames(renamed)
+				return nil, fmt.Errorf("failed to rename artwork %s: %w", name, err)
+			}
+			renamed = append(renamed, r)
+			break
+		}
+	}
+	return renamed, nil
+}
@@ -0,0 +1,134 @@
+package proton_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("MigrateAppID", func() {
+	const (
+		oldID = uint32(0xFF000001)
+		newID = uint32(0x80000001)
+	)
+
+	var (
+		tmpDir    string
+		mockSteam *steam.Steam
+		mockUser  *steam.User
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "migrate-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		steamPath := filepath.Join(tmpDir, "Steam")
+		mockSteam = &steam.Steam{
+			Path:       steamPath,
+			ConfigPath: filepath.Join(steamPath, "config"),
+			AppsPath:   filepath.Join(steamPath, "steamapps"),
+			CompatPath: filepath.Join(steamPath, "steamapps", "compatdata"),
+		}
+		mockUser = &steam.User{ID: "12345", ConfigPath: filepath.Join(steamPath, "userdata", "12345", "config")}
+
+		Expect(os.MkdirAll(filepath.Join(mockSteam.CompatPath, "4278190081", "pfx"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(mockSteam.ConfigPath, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(mockSteam.ConfigPath, "config.vdf"), []byte(`"InstallConfigStore"
+{
+	"Software"
+	{
+		"Valve"
+		{
+			"Steam"
+			{
+				"CompatToolMapping"
+				{
+					"4278190081"
+					{
+						"name"		"proton_10"
+						"config"		""
+						"priority"		"250"
+					}
+				}
+			}
+		}
+	}
+}
+`), 0644)).To(Succeed())
+		Expect(steam.WriteShortcuts(mockUser.ShortcutsPath(), []steam.Shortcut{
+			{AppID: oldID, AppName: "Game", Tags: map[string]string{}},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("should move the compatdata, compatibility tool and shortcut", func() {
+		Expect(proton.MigrateAppID(mockSteam, mockUser, oldID, newID)).To(Succeed())
+
+		Expect(filepath.Join(mockSteam.CompatPath, "2147483649", "pfx")).To(BeADirectory())
+		Expect(filepath.Join(mockSteam.CompatPath, "4278190081")).NotTo(BeAnExistingFile())
+
+		mapping, err := proton.CompatToolMapping(mockSteam)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapping).To(Equal(map[string]string{"2147483649": "proton_10"}))
+
+		shortcut, err := steam.FindShortcutByName(mockUser, "Game")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shortcut.AppID).To(Equal(newID))
+	})
+
+	It("should rename the shortcut's artwork", func() {
+		Expect(os.MkdirAll(mockUser.GridPath(), 0755)).To(Succeed())
+		for _, name := range []string{"4278190081p.png", "4278190081_hero.jpg", "18374686484000145408.png", "42781900810.png"} {
+			Expect(os.WriteFile(filepath.Join(mockUser.GridPath(), name), nil, 0644)).To(Succeed())
+		}
+
+		Expect(proton.MigrateAppID(mockSteam, mockUser, oldID, newID)).To(Succeed())
+
+		entries, err := os.ReadDir(mockUser.GridPath())
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		Expect(names).To(ConsistOf("2147483649p.png", "2147483649_hero.jpg", "9223372041183297536.png", "42781900810.png"))
+	})
+
+	It("should undo the completed steps when a later step fails", func() {
+		Expect(os.MkdirAll(mockUser.GridPath(), 0755)).To(Succeed())
+		for _, name := range []string{"4278190081_hero.jpg", "4278190081p.png", "2147483649p.png"} {
+			Expect(os.WriteFile(filepath.Join(mockUser.GridPath(), name), []byte(name), 0644)).To(Succeed())
+		}
+
+		err := proton.MigrateAppID(mockSteam, mockUser, oldID, newID)
+		Expect(err).To(MatchError(ContainSubstring("already exists")))
+
+		shortcut, err := steam.FindShortcutByName(mockUser, "Game")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shortcut.AppID).To(Equal(oldID))
+
+		mapping, err := proton.CompatToolMapping(mockSteam)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapping).To(Equal(map[string]string{"4278190081": "proton_10"}))
+
+		Expect(filepath.Join(mockUser.GridPath(), "4278190081_hero.jpg")).To(BeARegularFile())
+		Expect(os.ReadFile(filepath.Join(mockUser.GridPath(), "2147483649p.png"))).To(Equal([]byte("2147483649p.png")))
+		Expect(filepath.Join(mockSteam.CompatPath, "4278190081", "pfx")).To(BeADirectory())
+	})
+
+	It("should refuse to overwrite existing compatdata", func() {
+		Expect(os.MkdirAll(filepath.Join(mockSteam.CompatPath, "2147483649"), 0755)).To(Succeed())
+
+		err := proton.MigrateAppID(mockSteam, mockUser, oldID, newID)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("already exists"))
+	})
+})
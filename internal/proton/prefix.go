@@ -0,0 +1,392 @@
+package proton
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+)
+
+// versionFile is the file in compatdata where Proton records the version
+// that created or last upgraded the prefix.
+const versionFile = "version"
+
+// snapshotTimeFormat is the timestamp format used in snapshot file names.
+const snapshotTimeFormat = "20060102-150405"
+
+// SaveDirs are the directories below the Wine user's profile that hold game
+// saves and settings. They are kept when the prefix is reset.
+var SaveDirs = []string{
+	filepath.Join("AppData", "Roaming"),
+	filepath.Join("AppData", "Local"),
+	filepath.Join("AppData", "LocalLow"),
+	"Documents",
+	"Saved Games",
+}
+
+// PrefixSnapshot is a compressed snapshot of an app's compatdata directory.
+type PrefixSnapshot struct {
+	Path      string
+	AppID     uint32
+	CreatedAt time.Time
+	Size      int64
+
+	seq int // orders snapshots taken within the same second
+}
+
+// ProtonVersion returns the Proton version recorded in the prefix's version
+// file, e.g. "9.0-203", or "" if the prefix has no version file.
+func (c *Config) ProtonVersion() (string, error) {
+	data, err := os.ReadFile(filepath.Join(c.CompatDataPath(), versionFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read prefix version: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// PrefixSize returns the total size of the files in the app's compatdata directory.
+func (c *Config) PrefixSize() (int64, error) {
+	var size int64
+	err := filepath.WalkDir(c.CompatDataPath(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure prefix: %w", err)
+	}
+	return size, nil
+}
+
+// userDir returns the Wine user's profile directory in the prefix.
+func (c *Config) userDir() string {
+	return filepath.Join(c.PrefixPath(), "drive_c", "users", "steamuser")
+}
+
+// savesStagingPath returns where saves are kept while the prefix is reset.
+// It is next to compatdata/<appid> so moves stay on the same filesystem.
+func (c *Config) savesStagingPath() string {
+	return filepath.Join(c.Steam.CompatPath, fmt.Sprintf(".%d-saves", c.AppID))
+}
+
+// Reset deletes the app's compatdata directory and initializes a new prefix
+// with Proton. Save directories (see SaveDirs) are moved aside first and
+// restored into the new prefix. If initialization fails, the saves are left
+// in the staging directory named in the error.
+func (c *Config) Reset(suppressOutput bool) error {
+	staging := c.savesStagingPath()
+	if archive.FileExists(staging) {
+		return fmt.Errorf("saves from an interrupted reset are in %s; move them back or remove the directory first", staging)
+	}
+
+	moved, err := moveDirs(c.userDir(), staging, SaveDirs)
+	if err != nil {
+		return fmt.Errorf("failed to preserve saves: %w", err)
+	}
+
+	if err := os.RemoveAll(c.CompatDataPath()); err != nil {
+		return fmt.Errorf("failed to remove compatdata: %w (saves are in %s)", err, staging)
+	}
+
+	if err := c.InitializePrefix(suppressOutput); err != nil {
+		if len(moved) > 0 {
+			return fmt.Errorf("%w (saves are in %s)", err, staging)
+		}
+		_ = os.RemoveAll(staging)
+		return err
+	}
+
+	for _, rel := range moved {
+		dst := filepath.Join(c.userDir(), rel)
+		if err := os.RemoveAll(dst); err != nil {
+			return fmt.Errorf("failed to restore saves: %w (saves are in %s)", err, staging)
+		}
+	}
+	if _, err := moveDirs(staging, c.userDir(), moved); err != nil {
+		return fmt.Errorf("failed to restore saves: %w (saves are in %s)", err, staging)
+	}
+
+	return os.RemoveAll(staging)
+}
+
+// moveDirs renames the dirs (relative paths) that exist below src to the same
+// paths below dst. Returns the dirs that were moved.
+func moveDirs(src, dst string, dirs []string) ([]string, error) {
+	var moved []string
+	for _, rel := range dirs {
+		from := filepath.Join(src, rel)
+		if _, err := os.Lstat(from); err != nil {
+			continue
+		}
+		to := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return moved, err
+		}
+		if err := os.Rename(from, to); err != nil {
+			return moved, err
+		}
+		moved = append(moved, rel)
+	}
+	return moved, nil
+}
+
+// Snapshot writes a compressed snapshot of the app's compatdata directory to dir.
+func (c *Config) Snapshot(dir string) (*PrefixSnapshot, error) {
+	root := c.CompatDataPath()
+	if !c.HasCompatData() {
+		return nil, fmt.Errorf("no prefix found at %s", root)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	now := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("%d-%s.tar.gz", c.AppID, now.Format(snapshotTimeFormat)))
+	for i := 2; archive.FileExists(path); i++ {
+		path = filepath.Join(dir, fmt.Sprintf("%d-%s-%d.tar.gz", c.AppID, now.Format(snapshotTimeFormat), i))
+	}
+
+	if err := writeTarGz(root, path+".tmp"); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return nil, fmt.Errorf("failed to finalize snapshot: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat snapshot: %w", err)
+	}
+	return &PrefixSnapshot{Path: path, AppID: c.AppID, CreatedAt: now, Size: info.Size()}, nil
+}
+
+// writeTarGz archives the contents of root, keeping symlinks as links.
+func writeTarGz(root, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	gzWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			if _, err := io.Copy(tarWriter, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// ListSnapshots returns the prefix snapshots in dir for appID, newest first.
+func ListSnapshots(dir string, appID uint32) ([]PrefixSnapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	prefix := fmt.Sprintf("%d-", appID)
+	var snapshots []PrefixSnapshot
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".tar.gz") {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".tar.gz")
+		if len(stamp) < len(snapshotTimeFormat) {
+			continue
+		}
+		createdAt, err := time.ParseInLocation(snapshotTimeFormat, stamp[:len(snapshotTimeFormat)], time.Local)
+		if err != nil {
+			continue
+		}
+		seq := 1
+		if rest := stamp[len(snapshotTimeFormat):]; rest != "" {
+			if seq, err = strconv.Atoi(strings.TrimPrefix(rest, "-")); err != nil || rest[0] != '-' {
+				continue
+			}
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, PrefixSnapshot{
+			Path:      filepath.Join(dir, name),
+			AppID:     appID,
+			CreatedAt: createdAt,
+			Size:      info.Size(),
+			seq:       seq,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].CreatedAt.Equal(snapshots[j].CreatedAt) {
+			return snapshots[i].seq > snapshots[j].seq
+		}
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Restore replaces the app's compatdata directory with a snapshot. The
+// snapshot is extracted next to the current prefix first, so a failed
+// extraction leaves the current prefix untouched.
+func (c *Config) Restore(snapshotPath string) error {
+	root := c.CompatDataPath()
+	tmpDir := root + ".restore"
+	oldDir := root + ".old"
+	_ = os.RemoveAll(tmpDir)
+	_ = os.RemoveAll(oldDir)
+
+	if err := archive.ExtractTarGz(snapshotPath, tmpDir); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return err
+	}
+
+	if c.HasCompatData() {
+		if err := os.Rename(root, oldDir); err != nil {
+			_ = os.RemoveAll(tmpDir)
+			return fmt.Errorf("failed to move current prefix aside: %w", err)
+		}
+	}
+	if err := os.Rename(tmpDir, root); err != nil {
+		_ = os.Rename(oldDir, root)
+		_ = os.RemoveAll(tmpDir)
+		return fmt.Errorf("failed to restore prefix: %w", err)
+	}
+
+	return os.RemoveAll(oldDir)
+}
+
+// Clone copies the app's compatdata directory to the compatdata of targetAppID.
+// Files are reflinked where the filesystem supports it. The target must not exist.
+func (c *Config) Clone(targetAppID uint32) (*Config, error) {
+	if targetAppID == c.AppID {
+		return nil, fmt.Errorf("cannot clone a prefix onto itself")
+	}
+	if !c.HasPrefix() {
+		return nil, fmt.Errorf("no initialized prefix found at %s", c.PrefixPath())
+	}
+
+	target := &Config{
+		Steam:      c.Steam,
+		User:       c.User,
+		AppID:      targetAppID,
+		ProtonName: c.ProtonName,
+		ProtonPath: c.ProtonPath,
+	}
+	dst := target.CompatDataPath()
+	if archive.FileExists(dst) {
+		return nil, fmt.Errorf("compatdata for AppID %d already exists", targetAppID)
+	}
+
+	if err := copyTree(c.CompatDataPath(), dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return nil, err
+	}
+	return target, nil
+}
+
+// copyTree copies src to dst, cloning regular files and recreating symlinks.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relPath)
+
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()|0700); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink: %w", err)
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return fmt.Errorf("failed to create symlink: %w", err)
+			}
+		case info.Mode().IsRegular():
+			if _, err := archive.CloneFile(path, target); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
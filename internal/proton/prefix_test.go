@@ -0,0 +1,185 @@
+package proton_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+// fakeProton creates an initialized prefix like `proton run` does.
+const fakeProton = `#!/bin/sh
+pfx="$STEAM_COMPAT_DATA_PATH/pfx"
+mkdir -p "$pfx/drive_c/windows/system32" "$pfx/drive_c/users/steamuser/AppData/Roaming" "$pfx/dosdevices"
+echo MZ > "$pfx/drive_c/windows/system32/kernel32.dll"
+printf 'WINE REGISTRY Version 2\n\n[Software\\\\Microsoft\\\\Windows NT\\\\CurrentVersion] 1700000000\n' > "$pfx/system.reg"
+echo 'WINE REGISTRY Version 2' > "$pfx/user.reg"
+echo 'WINE REGISTRY Version 2' > "$pfx/userdef.reg"
+ln -sfn ../drive_c "$pfx/dosdevices/c:"
+echo "9.0-203" > "$STEAM_COMPAT_DATA_PATH/version"
+`
+
+var _ = Describe("Prefix", func() {
+	var tmpDir string
+	var snapshotDir string
+	var cfg *proton.Config
+
+	prefixFile := func(rel string) string {
+		return filepath.Join(cfg.PrefixPath(), rel)
+	}
+
+	writeFile := func(path, content string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	savePath := filepath.Join("drive_c", "users", "steamuser", "AppData", "Roaming", "ZLADXHD", "save.dat")
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "proton-prefix-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		snapshotDir = filepath.Join(tmpDir, "snapshots")
+
+		protonPath := filepath.Join(tmpDir, "Proton 9.0")
+		writeFile(filepath.Join(protonPath, "proton"), fakeProton)
+		Expect(os.Chmod(filepath.Join(protonPath, "proton"), 0755)).To(Succeed())
+
+		compatPath := filepath.Join(tmpDir, "compatdata")
+		cfg = &proton.Config{
+			Steam:      &steam.Steam{Path: tmpDir, CompatPath: compatPath},
+			AppID:      12345,
+			ProtonName: "Proton 9.0",
+			ProtonPath: protonPath,
+		}
+		Expect(cfg.InitializePrefix(true)).To(Succeed())
+		writeFile(prefixFile(savePath), "link's save")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("ProtonVersion", func() {
+		It("should read the prefix version file", func() {
+			Expect(cfg.ProtonVersion()).To(Equal("9.0-203"))
+		})
+
+		It("should return empty without a version file", func() {
+			Expect(os.Remove(filepath.Join(cfg.CompatDataPath(), "version"))).To(Succeed())
+			Expect(cfg.ProtonVersion()).To(BeEmpty())
+		})
+	})
+
+	Describe("PrefixSize", func() {
+		It("should sum the file sizes", func() {
+			size, err := cfg.PrefixSize()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(BeNumerically(">", int64(len("link's save"))))
+		})
+	})
+
+	Describe("Reset", func() {
+		It("should re-create the prefix and keep saves", func() {
+			writeFile(prefixFile("drive_c/windows/system32/broken.dll"), "junk")
+
+			Expect(cfg.Reset(true)).To(Succeed())
+
+			Expect(prefixFile("drive_c/windows/system32/broken.dll")).NotTo(BeAnExistingFile())
+			Expect(cfg.HasPrefix()).To(BeTrue())
+			data, err := os.ReadFile(prefixFile(savePath))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("link's save"))
+			Expect(filepath.Join(cfg.Steam.CompatPath, ".12345-saves")).NotTo(BeAnExistingFile())
+		})
+
+		It("should refuse to run over saves from an interrupted reset", func() {
+			Expect(os.MkdirAll(filepath.Join(cfg.Steam.CompatPath, ".12345-saves"), 0755)).To(Succeed())
+			Expect(cfg.Reset(true)).To(MatchError(ContainSubstring("interrupted reset")))
+			Expect(prefixFile(savePath)).To(BeAnExistingFile())
+		})
+	})
+
+	Describe("Snapshot and Restore", func() {
+		It("should restore the prefix to the snapshot", func() {
+			snap, err := cfg.Snapshot(snapshotDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snap.Size).To(BeNumerically(">", 0))
+
+			Expect(os.Remove(prefixFile(savePath))).To(Succeed())
+			writeFile(prefixFile("drive_c/new.txt"), "new")
+
+			Expect(cfg.Restore(snap.Path)).To(Succeed())
+
+			Expect(prefixFile(savePath)).To(BeAnExistingFile())
+			Expect(prefixFile("drive_c/new.txt")).NotTo(BeAnExistingFile())
+			link, err := os.Readlink(prefixFile("dosdevices/c:"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("../drive_c"))
+			Expect(cfg.HasPrefix()).To(BeTrue())
+		})
+
+		It("should list snapshots for the AppID, newest first", func() {
+			first, err := cfg.Snapshot(snapshotDir)
+			Expect(err).NotTo(HaveOccurred())
+			second, err := cfg.Snapshot(snapshotDir)
+			Expect(err).NotTo(HaveOccurred())
+			writeFile(filepath.Join(snapshotDir, "99-20260101-000000.tar.gz"), "")
+
+			snapshots, err := proton.ListSnapshots(snapshotDir, cfg.AppID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshots).To(HaveLen(2))
+			Expect(snapshots[0].Path).To(Equal(second.Path))
+			Expect(snapshots[1].Path).To(Equal(first.Path))
+		})
+
+		It("should reject snapshots that write through symlinks", func() {
+			outside := filepath.Join(tmpDir, "outside")
+			Expect(os.MkdirAll(outside, 0755)).To(Succeed())
+
+			evil := filepath.Join(tmpDir, "evil.tar.gz")
+			f, err := os.Create(evil)
+			Expect(err).NotTo(HaveOccurred())
+			gz := gzip.NewWriter(f)
+			tw := tar.NewWriter(gz)
+			Expect(tw.WriteHeader(&tar.Header{Name: "pfx/link", Typeflag: tar.TypeSymlink, Linkname: outside})).To(Succeed())
+			Expect(tw.WriteHeader(&tar.Header{Name: "pfx/link/evil", Typeflag: tar.TypeReg, Mode: 0644, Size: 4})).To(Succeed())
+			_, err = tw.Write([]byte("evil"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tw.Close()).To(Succeed())
+			Expect(gz.Close()).To(Succeed())
+			Expect(f.Close()).To(Succeed())
+
+			Expect(cfg.Restore(evil)).NotTo(Succeed())
+			Expect(filepath.Join(outside, "evil")).NotTo(BeAnExistingFile())
+			Expect(prefixFile(savePath)).To(BeAnExistingFile())
+		})
+	})
+
+	Describe("Clone", func() {
+		It("should copy the prefix to another AppID", func() {
+			target, err := cfg.Clone(67890)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target.CompatDataPath()).To(Equal(filepath.Join(cfg.Steam.CompatPath, "67890")))
+
+			data, err := os.ReadFile(filepath.Join(target.PrefixPath(), savePath))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("link's save"))
+			Expect(target.HasPrefix()).To(BeTrue())
+
+			_, err = cfg.Clone(67890)
+			Expect(err).To(MatchError(ContainSubstring("already exists")))
+		})
+
+		It("should refuse to clone onto itself", func() {
+			_, err := cfg.Clone(cfg.AppID)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
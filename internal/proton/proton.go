@@ -3,6 +3,7 @@ package proton
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -62,6 +63,7 @@ func (c *Config) CreateCompatData() error {
 
 // InitializePrefix initializes the Wine prefix by running Proton.
 // This creates the actual Wine prefix structure (drive_c, registry, etc.)
+// An existing prefix created by another Proton version is upgraded in place.
 // If suppressOutput is true, Wine debug output is hidden (but dumped on error).
 func (c *Config) InitializePrefix(suppressOutput bool) error {
 	// Create compatdata directory first
@@ -69,8 +71,9 @@ func (c *Config) InitializePrefix(suppressOutput bool) error {
 		return err
 	}
 
-	// If prefix is already initialized, skip
-	if c.HasPrefix() {
+	// If prefix is already initialized by this Proton version, skip.
+	// Otherwise running Proton upgrades the existing prefix.
+	if c.HasPrefix() && !c.NeedsUpgrade() {
 		return nil
 	}
 
@@ -120,6 +123,7 @@ func (c *Config) HasCompatData() bool {
 }
 
 // HasPrefix checks if the Wine prefix exists and is initialized.
+// Use CheckPrefix to verify the prefix is healthy.
 func (c *Config) HasPrefix() bool {
 	// Check for system.reg which indicates an initialized prefix
 	regPath := filepath.Join(c.PrefixPath(), "system.reg")
@@ -129,23 +133,21 @@ func (c *Config) HasPrefix() bool {
 
 // ConfigureCompatibility sets up Proton compatibility in config.vdf.
 // This configures the "Force the use of a specific Steam Play compatibility tool" setting.
+// If the tool's internal name had to be guessed, the guess is written and an
+// error wrapping ErrUnknownCompatTool is returned.
 func (c *Config) ConfigureCompatibility() error {
 	configPath := filepath.Join(c.Steam.Path, "config", "config.vdf")
 
 	// Read or create config.vdf
-	var doc *vdf.Document
-	var err error
-
-	if _, statErr := os.Stat(configPath); os.IsNotExist(statErr) {
+	doc, state, err := steam.ReadVDF(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config.vdf: %w", err)
+	}
+	if doc == nil {
 		// Create new document
 		doc = vdf.NewDocument()
 		root := vdf.NewObject("InstallConfigStore")
 		doc.AddRoot(root)
-	} else {
-		doc, err = vdf.ParseFile(configPath)
-		if err != nil {
-			return fmt.Errorf("failed to parse config.vdf: %w", err)
-		}
 	}
 
 	// Navigate/create the path: InstallConfigStore -> Software -> Valve -> Steam -> CompatToolMapping
@@ -187,86 +189,39 @@ func (c *Config) ConfigureCompatibility() error {
 		compatMapping.AddChild(appConfig)
 	}
 
-	// Get the internal compat tool name
-	compatToolName := c.GetCompatToolName()
+	// Get the internal compat tool name. If it had to be guessed, the guess
+	// is still written and the caller is told.
+	compatToolName, resolveErr := c.ResolveCompatToolName()
+	if resolveErr != nil && !errors.Is(resolveErr, ErrUnknownCompatTool) {
+		return fmt.Errorf("failed to resolve compatibility tool name: %w", resolveErr)
+	}
 
 	// Set the compatibility tool mapping
 	appConfig.Set("name", compatToolName)
 	appConfig.Set("config", "")
 	appConfig.Set("priority", "250")
 
-	// Write the document back
-	if err := vdf.WriteFile(configPath, doc); err != nil {
+	// Write the document back, unless Steam changed it in the meantime
+	if err := steam.WriteVDF(configPath, doc, state); err != nil {
 		return fmt.Errorf("failed to write config.vdf: %w", err)
 	}
 
-	return nil
+	return resolveErr
 }
 
-// GetCompatToolName returns the internal compatibility tool name for Steam's config.
-// For official Proton versions, this derives the name from the display name.
-// For custom Proton versions (GE-Proton, etc.), the folder name is used directly.
-func (c *Config) GetCompatToolName() string {
-	// Check if it's in compatibilitytools.d (custom proton)
-	customToolPath := filepath.Join(c.Steam.Path, "compatibilitytools.d", c.ProtonName)
-	if _, err := os.Stat(customToolPath); err == nil {
-		// Custom proton - folder name is the internal name
-		return c.ProtonName
-	}
-
-	// Official Proton - derive internal name from display name
-	// "Proton Experimental" -> "proton_experimental"
-	// "Proton 10.0" -> "proton_10"
-	// "Proton Hotfix" -> "proton_hotfix"
-	name := strings.ToLower(c.ProtonName)
-	name = strings.ReplaceAll(name, " - ", " ")
-	name = strings.ReplaceAll(name, " ", "_")
-	// Remove .0 suffix from version numbers (e.g., "proton_10.0" -> "proton_10")
-	if strings.Contains(name, "_") {
-		parts := strings.Split(name, "_")
-		for i, part := range parts {
-			if strings.HasSuffix(part, ".0") {
-				parts[i] = strings.TrimSuffix(part, ".0")
-			}
-		}
-		name = strings.Join(parts, "_")
-	}
-	return name
-}
-
-// GetAvailableProtonVersions returns a list of Proton versions, with recommended ones first.
+// GetAvailableProtonVersions returns the names of the installed Proton
+// versions in the order of ListProtonVersions.
 func GetAvailableProtonVersions(s *steam.Steam) ([]string, error) {
-	versions, err := s.GetProtonVersions()
+	versions, err := ListProtonVersions(s)
 	if err != nil {
 		return nil, err
 	}
 
-	// Sort: Proton Experimental first, then GE-Proton, then others
-	var experimental []string
-	var ge []string
-	var regular []string
-	var other []string
-
+	names := make([]string, 0, len(versions))
 	for _, v := range versions {
-		switch {
-		case strings.Contains(v, "Experimental"):
-			experimental = append(experimental, v)
-		case strings.HasPrefix(v, "GE-Proton"):
-			ge = append(ge, v)
-		case strings.HasPrefix(v, "Proton"):
-			regular = append(regular, v)
-		default:
-			other = append(other, v)
-		}
+		names = append(names, v.Name)
 	}
-
-	var sorted []string
-	sorted = append(sorted, experimental...)
-	sorted = append(sorted, ge...)
-	sorted = append(sorted, regular...)
-	sorted = append(sorted, other...)
-
-	return sorted, nil
+	return names, nil
 }
 
 // FindProtonByName finds a Proton version by partial name match.
@@ -155,6 +155,8 @@ var _ = Describe("Proton", func() {
 				Entry("Proton 10.0", "Proton 10.0", "proton_10"),
 				Entry("Proton Hotfix", "Proton Hotfix", "proton_hotfix"),
 				Entry("Proton 9.0", "Proton 9.0", "proton_9"),
+				Entry("Proton 9.0 (Beta)", "Proton 9.0 (Beta)", "proton_9"),
+				Entry("Proton 6.3", "Proton 6.3", "proton_63"),
 			)
 
 			It("should use folder name for custom Proton", func() {
@@ -169,6 +171,21 @@ var _ = Describe("Proton", func() {
 				}
 				Expect(testCfg.GetCompatToolName()).To(Equal("GE-Proton8-25"))
 			})
+
+			It("should use the internal name from compatibilitytool.vdf", func() {
+				customPath := filepath.Join(mockSteam.Path, "compatibilitytools.d", "proton-tkg")
+				err := os.MkdirAll(customPath, 0755)
+				Expect(err).NotTo(HaveOccurred())
+				manifest := `"compatibilitytools" { "compat_tools" { "proton_tkg_9" { "install_path" "." "display_name" "Proton-tkg 9.0" } } }`
+				err = os.WriteFile(filepath.Join(customPath, "compatibilitytool.vdf"), []byte(manifest), 0644)
+				Expect(err).NotTo(HaveOccurred())
+
+				testCfg := &proton.Config{
+					Steam:      mockSteam,
+					ProtonName: "Proton-tkg 9.0",
+				}
+				Expect(testCfg.GetCompatToolName()).To(Equal("proton_tkg_9"))
+			})
 		})
 	})
 
@@ -0,0 +1,307 @@
+package proton
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/winereg"
+)
+
+// Registry tweak types.
+const (
+	TweakString       = "string"
+	TweakExpandString = "expand_string"
+	TweakDword        = "dword"
+	TweakDelete       = "delete"
+)
+
+// envNamePattern matches valid environment variable names.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Recipe declares what the game needs in its Wine prefix.
+type Recipe struct {
+	// Verbs are winetricks verbs to install, in order.
+	Verbs []string `json:"verbs,omitempty"`
+	// DLLOverrides maps DLL names to load orders (see AddDLLOverride).
+	DLLOverrides map[string]string `json:"dll_overrides,omitempty"`
+	// WindowsVersion is the Windows version to report, e.g. "win10".
+	WindowsVersion string `json:"windows_version,omitempty"`
+	// Registry holds additional registry edits.
+	Registry []RegistryTweak `json:"registry,omitempty"`
+	// Env holds environment variables for the game, e.g. PROTON_USE_WINED3D.
+	// They are set through the Steam shortcut's launch options. In overrides,
+	// an empty value removes the variable.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// RegistryTweak is a single registry value edit.
+type RegistryTweak struct {
+	// File is the registry file to edit: user.reg (default) or system.reg.
+	File string `json:"file,omitempty"`
+	// Key is the key path relative to the file's root, e.g. `Software\Wine\Direct3D`.
+	Key string `json:"key"`
+	// Name is the value name; empty for the default value.
+	Name string `json:"name,omitempty"`
+	// Type is string (default), expand_string, dword or delete.
+	Type string `json:"type,omitempty"`
+	// Value is the data; dwords are decimal or 0x-prefixed hex.
+	Value string `json:"value,omitempty"`
+}
+
+// DefaultRecipe returns the prefix requirements of the game.
+func DefaultRecipe() *Recipe {
+	return &Recipe{
+		Verbs: []string{"dotnetdesktop6"},
+	}
+}
+
+// Merge returns a copy of the recipe with overrides applied. Verbs and
+// registry tweaks are appended, DLL overrides and env vars replace existing
+// entries, and a non-empty Windows version replaces the recipe's.
+func (r *Recipe) Merge(overrides *Recipe) *Recipe {
+	merged := &Recipe{
+		Verbs:          append([]string(nil), r.Verbs...),
+		DLLOverrides:   make(map[string]string),
+		WindowsVersion: r.WindowsVersion,
+		Registry:       append([]RegistryTweak(nil), r.Registry...),
+		Env:            make(map[string]string),
+	}
+	for dll, mode := range r.DLLOverrides {
+		merged.DLLOverrides[dll] = mode
+	}
+	for name, value := range r.Env {
+		merged.Env[name] = value
+	}
+
+	if overrides == nil {
+		return merged
+	}
+
+	for _, verb := range overrides.Verbs {
+		if !containsString(merged.Verbs, verb) {
+			merged.Verbs = append(merged.Verbs, verb)
+		}
+	}
+	for dll, mode := range overrides.DLLOverrides {
+		merged.DLLOverrides[dll] = mode
+	}
+	if overrides.WindowsVersion != "" {
+		merged.WindowsVersion = overrides.WindowsVersion
+	}
+	merged.Registry = append(merged.Registry, overrides.Registry...)
+	for name, value := range overrides.Env {
+		if value == "" {
+			delete(merged.Env, name)
+		} else {
+			merged.Env[name] = value
+		}
+	}
+
+	return merged
+}
+
+// Validate checks the recipe for invalid entries.
+func (r *Recipe) Validate() error {
+	for _, verb := range r.Verbs {
+		if verb == "" || strings.ContainsAny(verb, " \t\n=") {
+			return fmt.Errorf("invalid winetricks verb %q", verb)
+		}
+	}
+	for dll, mode := range r.DLLOverrides {
+		if _, ok := dllOverrideModes[strings.ReplaceAll(strings.ToLower(mode), " ", "")]; !ok {
+			return fmt.Errorf("invalid DLL override mode %q for %s", mode, dll)
+		}
+	}
+	if r.WindowsVersion != "" && !isWindowsVersion(strings.ToLower(r.WindowsVersion)) {
+		return fmt.Errorf("unknown Windows version %q", r.WindowsVersion)
+	}
+	for _, tweak := range r.Registry {
+		if err := tweak.validate(); err != nil {
+			return err
+		}
+	}
+	for name := range r.Env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+	}
+	return nil
+}
+
+// LaunchOptions returns Steam launch options that set the recipe's env vars
+// in front of existing. Variables already set in existing are replaced and
+// "%command%" is added if missing.
+func (r *Recipe) LaunchOptions(existing string) string {
+	if len(r.Env) == 0 {
+		return existing
+	}
+
+	var kept []string
+	rest := strings.Fields(existing)
+	for len(rest) > 0 {
+		name, _, ok := strings.Cut(rest[0], "=")
+		if !ok || !envNamePattern.MatchString(name) {
+			break
+		}
+		if _, overridden := r.Env[name]; !overridden {
+			kept = append(kept, rest[0])
+		}
+		rest = rest[1:]
+	}
+	if len(rest) == 0 {
+		rest = []string{"%command%"}
+	}
+
+	names := make([]string, 0, len(r.Env))
+	for name := range r.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names)+len(kept)+len(rest))
+	for _, name := range names {
+		parts = append(parts, name+"="+shellQuote(r.Env[name]))
+	}
+	parts = append(parts, kept...)
+	parts = append(parts, rest...)
+	return strings.Join(parts, " ")
+}
+
+// ApplyRecipe writes the recipe's Windows version, DLL overrides and registry
+// tweaks to the prefix. Verbs are installed through a runner and env vars
+// through the shortcut, so they are not handled here. Wine must not be
+// running in the prefix, or it will overwrite the changes when it exits.
+func (c *Config) ApplyRecipe(r *Recipe) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	if r.WindowsVersion != "" || len(r.DLLOverrides) > 0 || r.hasTweaks(UserReg) {
+		err := c.updateRegistry(UserReg, func(reg *winereg.File) error {
+			if r.WindowsVersion != "" {
+				if err := setWindowsVersion(reg, r.WindowsVersion); err != nil {
+					return err
+				}
+			}
+			for _, dll := range slices.Sorted(maps.Keys(r.DLLOverrides)) {
+				if err := setDLLOverride(reg, dll, r.DLLOverrides[dll]); err != nil {
+					return err
+				}
+			}
+			return applyTweaks(reg, r.Registry, UserReg)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", UserReg, err)
+		}
+	}
+
+	if r.hasTweaks(SystemReg) {
+		err := c.updateRegistry(SystemReg, func(reg *winereg.File) error {
+			return applyTweaks(reg, r.Registry, SystemReg)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", SystemReg, err)
+		}
+	}
+
+	return nil
+}
+
+// hasTweaks checks if any registry tweak targets file.
+func (r *Recipe) hasTweaks(file string) bool {
+	for _, tweak := range r.Registry {
+		if tweak.file() == file {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTweaks applies the tweaks that target file.
+func applyTweaks(reg *winereg.File, tweaks []RegistryTweak, file string) error {
+	for _, tweak := range tweaks {
+		if tweak.file() != file {
+			continue
+		}
+		if err := tweak.apply(reg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// file returns the registry file the tweak edits.
+func (t RegistryTweak) file() string {
+	if t.File == "" {
+		return UserReg
+	}
+	return t.File
+}
+
+// validate checks the tweak's file, key, type and value.
+func (t RegistryTweak) validate() error {
+	if f := t.file(); f != UserReg && f != SystemReg {
+		return fmt.Errorf("invalid registry file %q: must be %s or %s", t.File, UserReg, SystemReg)
+	}
+	if t.Key == "" {
+		return fmt.Errorf("registry tweak for %q has no key", t.Name)
+	}
+	switch t.Type {
+	case "", TweakString, TweakExpandString, TweakDelete:
+	case TweakDword:
+		if _, err := strconv.ParseUint(t.Value, 0, 32); err != nil {
+			return fmt.Errorf("invalid dword %q for %s\\%s", t.Value, t.Key, t.Name)
+		}
+	default:
+		return fmt.Errorf("invalid registry tweak type %q", t.Type)
+	}
+	return nil
+}
+
+// apply performs the tweak on a parsed registry file.
+func (t RegistryTweak) apply(reg *winereg.File) error {
+	if t.Type == TweakDelete {
+		if key := reg.Key(t.Key); key != nil {
+			key.DeleteValue(t.Name)
+		}
+		return nil
+	}
+
+	key := reg.CreateKey(t.Key)
+	switch t.Type {
+	case TweakDword:
+		n, err := strconv.ParseUint(t.Value, 0, 32)
+		if err != nil {
+			return fmt.Errorf("invalid dword %q for %s\\%s", t.Value, t.Key, t.Name)
+		}
+		key.SetDword(t.Name, uint32(n))
+	case TweakExpandString:
+		key.SetExpandString(t.Name, t.Value)
+	default:
+		key.SetString(t.Name, t.Value)
+	}
+	return nil
+}
+
+// shellQuote quotes a value for launch options if it contains special characters.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`;&|<>()*?[]#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// containsString checks if list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
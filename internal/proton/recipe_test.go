@@ -0,0 +1,147 @@
+package proton_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("Recipe", func() {
+	Describe("Merge", func() {
+		It("should apply user overrides on top of the default recipe", func() {
+			merged := proton.DefaultRecipe().Merge(&proton.Recipe{
+				Verbs:          []string{"dotnetdesktop6", "corefonts"},
+				DLLOverrides:   map[string]string{"dxgi": "native"},
+				WindowsVersion: "win10",
+				Env:            map[string]string{"PROTON_USE_WINED3D": "1"},
+			})
+
+			Expect(merged.Verbs).To(Equal([]string{"dotnetdesktop6", "corefonts"}))
+			Expect(merged.DLLOverrides).To(Equal(map[string]string{"dxgi": "native"}))
+			Expect(merged.WindowsVersion).To(Equal("win10"))
+			Expect(merged.Env).To(Equal(map[string]string{"PROTON_USE_WINED3D": "1"}))
+		})
+
+		It("should remove env vars with empty override values", func() {
+			base := &proton.Recipe{Env: map[string]string{"PROTON_NO_ESYNC": "1"}}
+			merged := base.Merge(&proton.Recipe{Env: map[string]string{"PROTON_NO_ESYNC": ""}})
+			Expect(merged.Env).To(BeEmpty())
+			Expect(base.Env).To(HaveKey("PROTON_NO_ESYNC"))
+		})
+
+		It("should handle no overrides", func() {
+			Expect(proton.DefaultRecipe().Merge(nil).Verbs).To(Equal([]string{"dotnetdesktop6"}))
+		})
+	})
+
+	Describe("Validate", func() {
+		DescribeTable("should reject invalid recipes",
+			func(recipe proton.Recipe) {
+				Expect(recipe.Validate()).NotTo(Succeed())
+			},
+			Entry("verb with spaces", proton.Recipe{Verbs: []string{"dotnet 6"}}),
+			Entry("DLL override mode", proton.Recipe{DLLOverrides: map[string]string{"dxgi": "always"}}),
+			Entry("Windows version", proton.Recipe{WindowsVersion: "win12"}),
+			Entry("registry file", proton.Recipe{Registry: []proton.RegistryTweak{{File: "userdef.reg", Key: "A"}}}),
+			Entry("registry key", proton.Recipe{Registry: []proton.RegistryTweak{{Name: "A"}}}),
+			Entry("dword value", proton.Recipe{Registry: []proton.RegistryTweak{{Key: "A", Type: proton.TweakDword, Value: "x"}}}),
+			Entry("env name", proton.Recipe{Env: map[string]string{"BAD-NAME": "1"}}),
+		)
+
+		It("should accept the default recipe", func() {
+			Expect(proton.DefaultRecipe().Validate()).To(Succeed())
+		})
+	})
+
+	Describe("LaunchOptions", func() {
+		recipe := &proton.Recipe{Env: map[string]string{
+			"PROTON_USE_WINED3D": "1",
+			"DXVK_FILTER":        "a b",
+		}}
+
+		It("should prefix %command% with env vars", func() {
+			Expect(recipe.LaunchOptions("")).To(Equal("DXVK_FILTER='a b' PROTON_USE_WINED3D=1 %command%"))
+		})
+
+		It("should keep the user's own launch options", func() {
+			Expect(recipe.LaunchOptions("PROTON_USE_WINED3D=0 MANGOHUD=1 %command% -windowed")).
+				To(Equal("DXVK_FILTER='a b' PROTON_USE_WINED3D=1 MANGOHUD=1 %command% -windowed"))
+		})
+
+		It("should leave launch options alone without env vars", func() {
+			Expect((&proton.Recipe{}).LaunchOptions("gamemoderun %command%")).To(Equal("gamemoderun %command%"))
+		})
+	})
+
+	Describe("ApplyRecipe", func() {
+		var tmpDir string
+		var cfg *proton.Config
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "proton-recipe-test-*")
+			Expect(err).NotTo(HaveOccurred())
+
+			cfg = &proton.Config{Steam: &steam.Steam{CompatPath: tmpDir}, AppID: 12345}
+			Expect(os.MkdirAll(cfg.PrefixPath(), 0755)).To(Succeed())
+			for _, name := range []string{proton.SystemReg, proton.UserReg} {
+				Expect(os.WriteFile(cfg.RegistryPath(name), []byte("WINE REGISTRY Version 2\n"), 0644)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		It("should write registry settings to the prefix", func() {
+			err := cfg.ApplyRecipe(&proton.Recipe{
+				WindowsVersion: "win7",
+				DLLOverrides:   map[string]string{"d3d11": "builtin"},
+				Registry: []proton.RegistryTweak{
+					{Key: `Software\Wine\Direct3D`, Name: "renderer", Value: "vulkan"},
+					{File: proton.SystemReg, Key: `Software\Test`, Name: "Enabled", Type: proton.TweakDword, Value: "0x10"},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			version, err := cfg.WindowsVersion()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("win7"))
+
+			overrides, err := cfg.DLLOverrides()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(overrides).To(HaveKeyWithValue("d3d11", "builtin"))
+
+			user, err := cfg.ReadRegistry(proton.UserReg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Key(`Software\Wine\Direct3D`).Value("renderer").String()).To(Equal("vulkan"))
+
+			system, err := cfg.ReadRegistry(proton.SystemReg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(system.Key(`Software\Test`).Value("Enabled").Dword()).To(Equal(uint32(16)))
+		})
+
+		It("should not touch the registry for verb-only recipes", func() {
+			Expect(os.Remove(cfg.RegistryPath(proton.UserReg))).To(Succeed())
+			Expect(cfg.ApplyRecipe(proton.DefaultRecipe())).To(Succeed())
+			Expect(filepath.Join(cfg.PrefixPath(), proton.UserReg)).NotTo(BeAnExistingFile())
+		})
+
+		It("should delete values", func() {
+			Expect(cfg.AddDLLOverride("dxgi", "native")).To(Succeed())
+			err := cfg.ApplyRecipe(&proton.Recipe{Registry: []proton.RegistryTweak{
+				{Key: `Software\Wine\DllOverrides`, Name: "dxgi", Type: proton.TweakDelete},
+			}})
+			Expect(err).NotTo(HaveOccurred())
+
+			overrides, err := cfg.DLLOverrides()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(overrides).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,230 @@
+package proton
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/winereg"
+)
+
+// Registry files of a Wine prefix.
+const (
+	SystemReg  = "system.reg"
+	UserReg    = "user.reg"
+	UserDefReg = "userdef.reg"
+)
+
+// Registry keys used for prefix configuration.
+const (
+	wineKey         = `Software\Wine`
+	dllOverridesKey = `Software\Wine\DllOverrides`
+	currentVersion  = `Software\Microsoft\Windows NT\CurrentVersion`
+	uninstallKey    = `Software\Microsoft\Windows\CurrentVersion\Uninstall`
+	uninstallKey32  = `Software\Wow6432Node\Microsoft\Windows\CurrentVersion\Uninstall`
+)
+
+// WindowsVersions are the Windows versions Wine accepts in its "Version" setting.
+var WindowsVersions = []string{
+	"win11", "win10", "win81", "win8", "win2008r2", "win7", "win2008", "vista",
+	"win2003", "winxp64", "winxp", "win2k", "winme", "win98", "win95",
+}
+
+// dllOverrideModes are the valid DLL override load orders.
+var dllOverrideModes = map[string]string{
+	"native":         "native",
+	"builtin":        "builtin",
+	"native,builtin": "native,builtin",
+	"builtin,native": "builtin,native",
+	"disabled":       "",
+	"":               "",
+}
+
+// Program is an installed program listed in the prefix's Uninstall registry key.
+type Program struct {
+	// Key is the name of the program's Uninstall subkey.
+	Key       string
+	Name      string
+	Version   string
+	Publisher string
+}
+
+// RegistryPath returns the path of a registry file in the prefix.
+func (c *Config) RegistryPath(name string) string {
+	return filepath.Join(c.PrefixPath(), name)
+}
+
+// ReadRegistry parses a registry file of the prefix.
+func (c *Config) ReadRegistry(name string) (*winereg.File, error) {
+	return winereg.ParseFile(c.RegistryPath(name))
+}
+
+// updateRegistry parses a registry file, applies fn and writes it back.
+func (c *Config) updateRegistry(name string, fn func(reg *winereg.File) error) error {
+	reg, err := c.ReadRegistry(name)
+	if err != nil {
+		return err
+	}
+	if err := fn(reg); err != nil {
+		return err
+	}
+	return reg.WriteFile(c.RegistryPath(name))
+}
+
+// WindowsVersion returns the Windows version configured for the prefix,
+// or "" if Wine's default is used.
+func (c *Config) WindowsVersion() (string, error) {
+	reg, err := c.ReadRegistry(UserReg)
+	if err != nil {
+		return "", err
+	}
+	if key := reg.Key(wineKey); key != nil {
+		if v := key.Value("Version"); v != nil {
+			return v.String(), nil
+		}
+	}
+	return "", nil
+}
+
+// SetWindowsVersion sets the Windows version Wine reports to programs in the prefix.
+func (c *Config) SetWindowsVersion(version string) error {
+	return c.updateRegistry(UserReg, func(reg *winereg.File) error {
+		return setWindowsVersion(reg, version)
+	})
+}
+
+// setWindowsVersion sets the Windows version in a parsed user.reg.
+func setWindowsVersion(reg *winereg.File, version string) error {
+	version = strings.ToLower(version)
+	if !isWindowsVersion(version) {
+		return fmt.Errorf("unknown Windows version %q", version)
+	}
+	reg.CreateKey(wineKey).SetString("Version", version)
+	return nil
+}
+
+// isWindowsVersion checks if version is one of WindowsVersions.
+func isWindowsVersion(version string) bool {
+	for _, v := range WindowsVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// DLLOverrides returns the prefix's DLL overrides keyed by DLL name.
+// Disabled DLLs have the mode "disabled".
+func (c *Config) DLLOverrides() (map[string]string, error) {
+	reg, err := c.ReadRegistry(UserReg)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]string)
+	if key := reg.Key(dllOverridesKey); key != nil {
+		for _, v := range key.Values {
+			mode := v.String()
+			if mode == "" {
+				mode = "disabled"
+			}
+			overrides[v.Name] = mode
+		}
+	}
+	return overrides, nil
+}
+
+// AddDLLOverride sets the load order of a DLL in the prefix.
+// mode is "native", "builtin", "native,builtin", "builtin,native" or "disabled".
+func (c *Config) AddDLLOverride(dll string, mode string) error {
+	return c.updateRegistry(UserReg, func(reg *winereg.File) error {
+		return setDLLOverride(reg, dll, mode)
+	})
+}
+
+// setDLLOverride sets a DLL override in a parsed user.reg.
+func setDLLOverride(reg *winereg.File, dll string, mode string) error {
+	value, ok := dllOverrideModes[strings.ReplaceAll(strings.ToLower(mode), " ", "")]
+	if !ok {
+		return fmt.Errorf("invalid DLL override mode %q", mode)
+	}
+	dll = strings.TrimSuffix(strings.ToLower(dll), ".dll")
+	if dll == "" {
+		return fmt.Errorf("empty DLL name")
+	}
+	reg.CreateKey(dllOverridesKey).SetString(dll, value)
+	return nil
+}
+
+// RemoveDLLOverride removes a DLL override from the prefix.
+func (c *Config) RemoveDLLOverride(dll string) error {
+	dll = strings.TrimSuffix(strings.ToLower(dll), ".dll")
+	return c.updateRegistry(UserReg, func(reg *winereg.File) error {
+		if key := reg.Key(dllOverridesKey); key != nil {
+			key.DeleteValue(dll)
+		}
+		return nil
+	})
+}
+
+// InstalledPrograms lists the programs registered in the prefix's
+// Uninstall keys (both 64-bit and 32-bit views), sorted by name.
+func (c *Config) InstalledPrograms() ([]Program, error) {
+	reg, err := c.ReadRegistry(SystemReg)
+	if err != nil {
+		return nil, err
+	}
+
+	var programs []Program
+	for _, parent := range []string{uninstallKey, uninstallKey32} {
+		for _, key := range reg.Subkeys(parent) {
+			p := Program{Key: key.Name[len(parent)+1:]}
+			if v := key.Value("DisplayName"); v != nil {
+				p.Name = v.String()
+			}
+			if p.Name == "" {
+				continue
+			}
+			if v := key.Value("DisplayVersion"); v != nil {
+				p.Version = v.String()
+			}
+			if v := key.Value("Publisher"); v != nil {
+				p.Publisher = v.String()
+			}
+			programs = append(programs, p)
+		}
+	}
+
+	sort.Slice(programs, func(i, j int) bool {
+		return strings.ToLower(programs[i].Name) < strings.ToLower(programs[j].Name)
+	})
+	return programs, nil
+}
+
+// CheckPrefix checks that the Wine prefix is fully initialized: all registry
+// files parse, the Windows version key exists and system32 is populated.
+// Returns nil if the prefix is healthy, or an error listing every problem.
+func (c *Config) CheckPrefix() error {
+	var problems []error
+
+	for _, name := range []string{SystemReg, UserReg, UserDefReg} {
+		reg, err := c.ReadRegistry(name)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if name == SystemReg && reg.Key(currentVersion) == nil {
+			problems = append(problems, fmt.Errorf("%s: missing %s", name, currentVersion))
+		}
+	}
+
+	system32 := filepath.Join(c.PrefixPath(), "drive_c", "windows", "system32")
+	if entries, err := os.ReadDir(system32); err != nil || len(entries) == 0 {
+		problems = append(problems, fmt.Errorf("drive_c/windows/system32 is missing or empty"))
+	}
+
+	return errors.Join(problems...)
+}
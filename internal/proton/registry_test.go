@@ -0,0 +1,153 @@
+package proton_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("Registry", func() {
+	var tmpDir string
+	var cfg *proton.Config
+
+	systemRegistry := `WINE REGISTRY Version 2
+;; All keys relative to \\Machine
+
+#arch=win64
+
+[Software\\Microsoft\\Windows NT\\CurrentVersion] 1700000000
+"CurrentVersion"="6.3"
+
+[Software\\Microsoft\\Windows\\CurrentVersion\\Uninstall\\{4F9B1B6E-0000}] 1700000000
+"DisplayName"="Microsoft Windows Desktop Runtime - 6.0.36 (x64)"
+"DisplayVersion"="48.144.23186"
+"Publisher"="Microsoft Corporation"
+
+[Software\\Microsoft\\Windows\\CurrentVersion\\Uninstall\\Hidden] 1700000000
+"SystemComponent"=dword:00000001
+
+[Software\\Wow6432Node\\Microsoft\\Windows\\CurrentVersion\\Uninstall\\Wine Mono] 1700000000
+"DisplayName"="Wine Mono Runtime"
+"DisplayVersion"="9.3.0"
+`
+
+	userRegistry := `WINE REGISTRY Version 2
+;; All keys relative to \\User\\S-1-5-21-0-0-0-1000
+
+#arch=win64
+
+[Software\\Wine\\DllOverrides] 1700000000
+"d3d9"="native,builtin"
+`
+
+	writeRegistry := func(name, content string) {
+		Expect(os.WriteFile(cfg.RegistryPath(name), []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "proton-registry-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		cfg = &proton.Config{
+			Steam: &steam.Steam{CompatPath: tmpDir},
+			AppID: 12345,
+		}
+		system32 := filepath.Join(cfg.PrefixPath(), "drive_c", "windows", "system32")
+		Expect(os.MkdirAll(system32, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(system32, "kernel32.dll"), []byte("MZ"), 0644)).To(Succeed())
+
+		writeRegistry(proton.SystemReg, systemRegistry)
+		writeRegistry(proton.UserReg, userRegistry)
+		writeRegistry(proton.UserDefReg, "WINE REGISTRY Version 2\n")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("SetWindowsVersion", func() {
+		It("should set the version in user.reg", func() {
+			Expect(cfg.SetWindowsVersion("Win10")).To(Succeed())
+
+			version, err := cfg.WindowsVersion()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("win10"))
+
+			overrides, err := cfg.DLLOverrides()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(overrides).To(HaveKeyWithValue("d3d9", "native,builtin"))
+		})
+
+		It("should reject unknown versions", func() {
+			Expect(cfg.SetWindowsVersion("win12")).NotTo(Succeed())
+		})
+	})
+
+	Describe("AddDLLOverride", func() {
+		It("should add and replace overrides", func() {
+			Expect(cfg.AddDLLOverride("dxgi.dll", "native")).To(Succeed())
+			Expect(cfg.AddDLLOverride("d3d9", "disabled")).To(Succeed())
+
+			overrides, err := cfg.DLLOverrides()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(overrides).To(Equal(map[string]string{"dxgi": "native", "d3d9": "disabled"}))
+		})
+
+		It("should reject invalid modes", func() {
+			Expect(cfg.AddDLLOverride("dxgi", "sometimes")).NotTo(Succeed())
+		})
+
+		It("should remove overrides", func() {
+			Expect(cfg.RemoveDLLOverride("D3D9.dll")).To(Succeed())
+
+			overrides, err := cfg.DLLOverrides()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(overrides).To(BeEmpty())
+		})
+	})
+
+	Describe("InstalledPrograms", func() {
+		It("should list programs from both registry views", func() {
+			programs, err := cfg.InstalledPrograms()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(programs).To(Equal([]proton.Program{
+				{
+					Key:       "{4F9B1B6E-0000}",
+					Name:      "Microsoft Windows Desktop Runtime - 6.0.36 (x64)",
+					Version:   "48.144.23186",
+					Publisher: "Microsoft Corporation",
+				},
+				{Key: "Wine Mono", Name: "Wine Mono Runtime", Version: "9.3.0"},
+			}))
+		})
+	})
+
+	Describe("CheckPrefix", func() {
+		It("should pass for an initialized prefix", func() {
+			Expect(cfg.CheckPrefix()).To(Succeed())
+		})
+
+		It("should report a missing registry file", func() {
+			Expect(os.Remove(cfg.RegistryPath(proton.UserDefReg))).To(Succeed())
+			err := cfg.CheckPrefix()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("userdef.reg"))
+		})
+
+		It("should report a truncated system.reg", func() {
+			writeRegistry(proton.SystemReg, "WINE REGISTRY Version 2\n")
+			Expect(cfg.CheckPrefix()).To(MatchError(ContainSubstring("Windows NT")))
+		})
+
+		It("should report an empty system32", func() {
+			Expect(os.RemoveAll(filepath.Join(cfg.PrefixPath(), "drive_c"))).To(Succeed())
+			Expect(cfg.CheckPrefix()).To(MatchError(ContainSubstring("system32")))
+		})
+	})
+})
@@ -0,0 +1,121 @@
+package proton
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+// ErrUnknownCompatTool is returned when the internal name of a compatibility
+// tool can't be determined from Steam's data and had to be guessed.
+var ErrUnknownCompatTool = errors.New("unknown compatibility tool")
+
+// OfficialToolNames maps the AppIDs of Valve's Proton releases to the internal
+// names Steam uses for them in CompatToolMapping.
+var OfficialToolNames = map[uint32]string{
+	1113280: "proton_411",
+	1245040: "proton_5",
+	1420170: "proton_513",
+	1493710: "proton_experimental",
+	1580130: "proton_63",
+	1887720: "proton_7",
+	2180100: "proton_hotfix",
+	2348590: "proton_8",
+	2805730: "proton_9",
+	3658110: "proton_10",
+}
+
+// parenthesizedPattern matches suffixes such as " (Beta)".
+var parenthesizedPattern = regexp.MustCompile(`\s*\([^)]*\)`)
+
+// ResolveCompatToolName returns the internal compatibility tool name Steam
+// knows the selected Proton by. It is taken from, in order:
+//   - the compatibilitytool.vdf of a tool in compatibilitytools.d
+//   - a compatibilitytool.vdf in the Proton directory itself
+//   - the appmanifest_<appid>.acf that installed the Proton directory,
+//     looked up in OfficialToolNames
+//
+// toolmanifest.vdf, which every tool has, only describes how to launch the
+// tool and carries no name. If none of the sources match, a name guessed
+// from the display name is returned along with ErrUnknownCompatTool.
+func (c *Config) ResolveCompatToolName() (string, error) {
+	tool, err := c.Steam.FindCompatTool(c.ProtonName)
+	if err != nil {
+		return "", err
+	}
+	if tool != nil {
+		return tool.Name, nil
+	}
+
+	protonPath := c.ProtonPath
+	if protonPath == "" {
+		if protonPath, err = c.Steam.GetProtonPath(c.ProtonName); err != nil {
+			protonPath = filepath.Join(c.Steam.CommonPath(), c.ProtonName)
+		}
+	}
+
+	manifestPath := filepath.Join(protonPath, steam.CompatToolManifest)
+	if _, err := os.Stat(manifestPath); err == nil {
+		tools, err := steam.ParseCompatToolManifest(manifestPath)
+		if err != nil {
+			return "", err
+		}
+		return tools[0].Name, nil
+	}
+
+	app, err := c.Steam.FindAppByInstallDir(filepath.Base(protonPath))
+	if err != nil {
+		return "", err
+	}
+	if app != nil {
+		if name, ok := OfficialToolNames[app.AppID]; ok {
+			return name, nil
+		}
+	}
+
+	// Without a readable manifest, a custom tool's folder name is the best guess
+	for _, dir := range c.Steam.CompatToolsPaths() {
+		if _, err := os.Stat(filepath.Join(dir, c.ProtonName)); err == nil {
+			return c.ProtonName, fmt.Errorf("%w: %s has no %s", ErrUnknownCompatTool, c.ProtonName, steam.CompatToolManifest)
+		}
+	}
+
+	name := guessCompatToolName(c.ProtonName)
+	if app != nil {
+		return name, fmt.Errorf("%w: AppID %d (%s) is not a known Proton release", ErrUnknownCompatTool, app.AppID, app.Name)
+	}
+	return name, fmt.Errorf("%w: no manifest found for %s", ErrUnknownCompatTool, c.ProtonName)
+}
+
+// GetCompatToolName returns the internal compatibility tool name for Steam's
+// config, falling back to a guess (see ResolveCompatToolName).
+func (c *Config) GetCompatToolName() string {
+	name, _ := c.ResolveCompatToolName()
+	if name == "" {
+		name = guessCompatToolName(c.ProtonName)
+	}
+	return name
+}
+
+// guessCompatToolName derives an internal name from an official Proton
+// display name:
+// "Proton Experimental" -> "proton_experimental"
+// "Proton 10.0" -> "proton_10"
+// "Proton 9.0 (Beta)" -> "proton_9"
+func guessCompatToolName(protonName string) string {
+	name := strings.ToLower(parenthesizedPattern.ReplaceAllString(protonName, ""))
+	name = strings.ReplaceAll(name, " - ", " ")
+	name = strings.Join(strings.Fields(name), "_")
+	// Remove .0 suffix from version numbers (e.g., "proton_10.0" -> "proton_10")
+	parts := strings.Split(name, "_")
+	for i, part := range parts {
+		parts[i] = strings.TrimSuffix(part, ".0")
+	}
+	// Minor versions are written without the dot (e.g., "proton_6.3" -> "proton_63")
+	return strings.ReplaceAll(strings.Join(parts, "_"), ".", "")
+}
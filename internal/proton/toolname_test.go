@@ -0,0 +1,136 @@
+package proton_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/jslay88/vdf"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("ToolName", func() {
+	var tmpDir string
+	var mockSteam *steam.Steam
+
+	// writeAppManifest installs an app manifest for a Proton directory.
+	writeAppManifest := func(appID, name, installDir string) {
+		manifest := `"AppState"
+{
+	"appid"		"` + appID + `"
+	"name"		"` + name + `"
+	"installdir"		"` + installDir + `"
+}
+`
+		err := os.WriteFile(filepath.Join(mockSteam.AppsPath, "appmanifest_"+appID+".acf"), []byte(manifest), 0644)
+		Expect(err).NotTo(HaveOccurred())
+		err = os.MkdirAll(filepath.Join(mockSteam.CommonPath(), installDir), 0755)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "toolname-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		steamPath := filepath.Join(tmpDir, "Steam")
+		err = os.MkdirAll(filepath.Join(steamPath, "steamapps", "common"), 0755)
+		Expect(err).NotTo(HaveOccurred())
+		err = os.MkdirAll(filepath.Join(steamPath, "config"), 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		mockSteam = &steam.Steam{
+			Path:       steamPath,
+			ConfigPath: filepath.Join(steamPath, "config"),
+			AppsPath:   filepath.Join(steamPath, "steamapps"),
+			CompatPath: filepath.Join(steamPath, "steamapps", "compatdata"),
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("ResolveCompatToolName", func() {
+		It("should use the app manifest of an official Proton", func() {
+			writeAppManifest("2805730", "Proton 9.0", "Proton 9.0 (Beta)")
+
+			cfg := &proton.Config{Steam: mockSteam, ProtonName: "Proton 9.0 (Beta)"}
+			name, err := cfg.ResolveCompatToolName()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("proton_9"))
+		})
+
+		It("should use the Proton path's directory name", func() {
+			writeAppManifest("1493710", "Proton Experimental", "Proton - Experimental")
+
+			cfg := &proton.Config{
+				Steam:      mockSteam,
+				ProtonName: "Proton Experimental",
+				ProtonPath: filepath.Join(mockSteam.CommonPath(), "Proton - Experimental"),
+			}
+			name, err := cfg.ResolveCompatToolName()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("proton_experimental"))
+		})
+
+		It("should prefer a compatibilitytool.vdf in the Proton directory", func() {
+			writeAppManifest("2805730", "Proton 9.0", "Proton 9.0")
+			manifest := `"compatibilitytools" { "compat_tools" { "proton_9_custom" { "install_path" "." } } }`
+			err := os.WriteFile(filepath.Join(mockSteam.CommonPath(), "Proton 9.0", steam.CompatToolManifest), []byte(manifest), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			cfg := &proton.Config{Steam: mockSteam, ProtonName: "Proton 9.0"}
+			name, err := cfg.ResolveCompatToolName()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("proton_9_custom"))
+		})
+
+		It("should guess and report an unknown AppID", func() {
+			writeAppManifest("4242", "Proton 42.0", "Proton 42.0")
+
+			cfg := &proton.Config{Steam: mockSteam, ProtonName: "Proton 42.0"}
+			name, err := cfg.ResolveCompatToolName()
+			Expect(err).To(MatchError(proton.ErrUnknownCompatTool))
+			Expect(err.Error()).To(ContainSubstring("4242"))
+			Expect(name).To(Equal("proton_42"))
+		})
+
+		It("should guess and report a Proton without a manifest", func() {
+			cfg := &proton.Config{Steam: mockSteam, ProtonName: "Proton 10.0"}
+			name, err := cfg.ResolveCompatToolName()
+			Expect(err).To(MatchError(proton.ErrUnknownCompatTool))
+			Expect(name).To(Equal("proton_10"))
+		})
+	})
+
+	Describe("ConfigureCompatibility", func() {
+		It("should write the resolved name to config.vdf", func() {
+			writeAppManifest("2805730", "Proton 9.0", "Proton 9.0 (Beta)")
+
+			cfg := &proton.Config{Steam: mockSteam, AppID: 123, ProtonName: "Proton 9.0 (Beta)"}
+			Expect(cfg.ConfigureCompatibility()).To(Succeed())
+
+			doc, err := vdf.ParseFile(filepath.Join(mockSteam.ConfigPath, "config.vdf"))
+			Expect(err).NotTo(HaveOccurred())
+			mapping := doc.Get("InstallConfigStore").GetObject("Software").GetObject("Valve").
+				GetObject("Steam").GetObject("CompatToolMapping").GetObject("123")
+			Expect(mapping.GetString("name")).To(Equal("proton_9"))
+		})
+
+		It("should write the guess and return ErrUnknownCompatTool", func() {
+			cfg := &proton.Config{Steam: mockSteam, AppID: 123, ProtonName: "Proton 10.0"}
+			err := cfg.ConfigureCompatibility()
+			Expect(err).To(MatchError(proton.ErrUnknownCompatTool))
+
+			doc, err := vdf.ParseFile(filepath.Join(mockSteam.ConfigPath, "config.vdf"))
+			Expect(err).NotTo(HaveOccurred())
+			mapping := doc.Get("InstallConfigStore").GetObject("Software").GetObject("Valve").
+				GetObject("Steam").GetObject("CompatToolMapping").GetObject("123")
+			Expect(mapping.GetString("name")).To(Equal("proton_10"))
+		})
+	})
+})
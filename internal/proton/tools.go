@@ -0,0 +1,215 @@
+package proton
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jslay88/vdf"
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+	"github.com/jslay88/zladxhd-installer/internal/release"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+// GEProtonRepo is the repository for GE-Proton releases.
+// See: https://github.com/GloriousEggroll/proton-ge-custom/releases
+const GEProtonRepo = "GloriousEggroll/proton-ge-custom"
+
+// ReleaseCacheName names the GE-Proton release API cache, see
+// release.CacheDir.
+const ReleaseCacheName = "ge-proton"
+
+// ToolAssets returns a tool release's tarball and its published sha512sum.
+func ToolAssets(rel *release.Release) (tarball *release.Asset, checksum *release.Asset, err error) {
+	tarball = rel.FindAsset(func(name string) bool {
+		return strings.HasSuffix(name, ".tar.gz")
+	})
+	if tarball == nil {
+		return nil, nil, fmt.Errorf("release %s has no .tar.gz asset", rel.TagName)
+	}
+	checksum = rel.FindAsset(func(name string) bool {
+		return name == strings.TrimSuffix(tarball.Name, ".tar.gz")+".sha512sum" || name == tarball.Name+".sha512sum"
+	})
+	if checksum == nil {
+		return nil, nil, fmt.Errorf("release %s has no .sha512sum asset", rel.TagName)
+	}
+	return tarball, checksum, nil
+}
+
+// InstallCompatTool downloads a compatibility tool release to cacheDir,
+// verifies the tarball against the release's sha512sum and extracts it into
+// destDir, a compatibilitytools.d directory. The tarball must hold a single
+// top-level directory. Returns the path of the installed tool.
+func InstallCompatTool(rel *release.Release, destDir string, cacheDir string, showProgress bool) (string, error) {
+	tarball, checksum, err := ToolAssets(rel)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	checksumPath := filepath.Join(cacheDir, filepath.Base(checksum.Name))
+	if err := release.FetchAsset(checksum, checksumPath, false); err != nil {
+		return "", fmt.Errorf("failed to download checksum: %w", err)
+	}
+	expected, err := readSHA512Sum(checksumPath, tarball.Name)
+	if err != nil {
+		return "", err
+	}
+
+	tarballPath := filepath.Join(cacheDir, filepath.Base(tarball.Name))
+	if archive.FileExists(tarballPath) && archive.VerifySHA512(tarballPath, expected) != nil {
+		_ = os.Remove(tarballPath)
+	}
+	if !archive.FileExists(tarballPath) {
+		if err := release.FetchAsset(tarball, tarballPath, showProgress); err != nil {
+			return "", fmt.Errorf("failed to download %s: %w", tarball.Name, err)
+		}
+	}
+	if err := archive.VerifySHA512(tarballPath, expected); err != nil {
+		_ = os.Remove(tarballPath)
+		return "", fmt.Errorf("failed to verify %s: %w", tarball.Name, err)
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", destDir, err)
+	}
+	tmpDir, err := os.MkdirTemp(destDir, ".install-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	if err := archive.ExtractTarGz(tarballPath, tmpDir); err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w", tarball.Name, err)
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read extracted files: %w", err)
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return "", fmt.Errorf("%s must contain a single top-level directory", tarball.Name)
+	}
+
+	toolPath := filepath.Join(destDir, entries[0].Name())
+	if archive.FileExists(toolPath) {
+		return "", fmt.Errorf("%s is already installed at %s", entries[0].Name(), toolPath)
+	}
+	if err := os.Rename(filepath.Join(tmpDir, entries[0].Name()), toolPath); err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", entries[0].Name(), err)
+	}
+	return toolPath, nil
+}
+
+// readSHA512Sum returns the hash for fileName from a sha512sum file.
+// A file with a single unnamed hash is accepted too.
+func readSHA512Sum(path string, fileName string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open checksum file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var lines [][]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read checksum file: %w", err)
+	}
+
+	for _, fields := range lines {
+		if len(fields) >= 2 && filepath.Base(strings.TrimPrefix(fields[1], "*")) == fileName {
+			return fields[0], nil
+		}
+	}
+	if len(lines) == 1 && len(lines[0]) == 1 {
+		return lines[0][0], nil
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", fileName, filepath.Base(path))
+}
+
+// CompatToolMapping returns the compatibility tool configured for each AppID
+// in Steam's config.vdf. AppID "0" is the default for all games.
+func CompatToolMapping(s *steam.Steam) (map[string]string, error) {
+	configPath := filepath.Join(s.ConfigPath, "config.vdf")
+	if !archive.FileExists(configPath) {
+		return map[string]string{}, nil
+	}
+
+	doc, err := vdf.ParseFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config.vdf: %w", err)
+	}
+
+	mapping := make(map[string]string)
+	node := doc.Get("InstallConfigStore")
+	for _, key := range []string{"Software", "Valve", "Steam", "CompatToolMapping"} {
+		if node == nil {
+			return mapping, nil
+		}
+		node = node.GetObject(key)
+	}
+	if node == nil {
+		return mapping, nil
+	}
+	for _, app := range node.Children {
+		if app.IsObject {
+			mapping[app.Key] = app.GetString("name")
+		}
+	}
+	return mapping, nil
+}
+
+// CompatToolUsers returns the AppIDs configured to use a tool, sorted.
+func CompatToolUsers(s *steam.Steam, toolName string) ([]string, error) {
+	mapping, err := CompatToolMapping(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []string
+	for appID, name := range mapping {
+		if strings.EqualFold(name, toolName) {
+			users = append(users, appID)
+		}
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+// RemoveCompatTool deletes a custom compatibility tool. It refuses if any app,
+// or the global default, is still configured to use the tool.
+func RemoveCompatTool(s *steam.Steam, tool *steam.CompatTool) error {
+	users, err := CompatToolUsers(s, tool.Name)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		for i, appID := range users {
+			if appID == "0" {
+				users[i] = "the default for all games"
+			}
+		}
+		return fmt.Errorf("%s is still used by %s; choose another compatibility tool in Steam first",
+			tool.Name, strings.Join(users, ", "))
+	}
+
+	// The tool's folder is the one holding its manifest
+	toolDir := filepath.Dir(tool.ManifestPath)
+	if filepath.Base(filepath.Dir(toolDir)) != "compatibilitytools.d" {
+		return fmt.Errorf("refusing to remove %s: not inside a compatibilitytools.d directory", toolDir)
+	}
+	if err := os.RemoveAll(toolDir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", toolDir, err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package proton_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/release"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("Tools", func() {
+	var tmpDir string
+	var mockSteam *steam.Steam
+	var mirrorDir string
+
+	// publishRelease writes a GE-Proton style release to the mirror and
+	// returns it. checksum overrides the published hash if non-empty.
+	publishRelease := func(tag string, checksum string) *release.Release {
+		tagDir := filepath.Join(mirrorDir, tag)
+		Expect(os.MkdirAll(tagDir, 0755)).To(Succeed())
+
+		tarball := filepath.Join(tagDir, tag+".tar.gz")
+		f, err := os.Create(tarball)
+		Expect(err).NotTo(HaveOccurred())
+		gz := gzip.NewWriter(f)
+		tw := tar.NewWriter(gz)
+		manifest := `"compatibilitytools" { "compat_tools" { "` + tag + `" { "install_path" "." "display_name" "` + tag + `" } } }`
+		files := map[string]string{
+			tag + "/compatibilitytool.vdf": manifest,
+			tag + "/proton":                "#!/bin/sh\n",
+		}
+		Expect(tw.WriteHeader(&tar.Header{Name: tag + "/", Typeflag: tar.TypeDir, Mode: 0755})).To(Succeed())
+		for name, content := range files {
+			Expect(tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(content))})).To(Succeed())
+			_, err := tw.Write([]byte(content))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(tw.Close()).To(Succeed())
+		Expect(gz.Close()).To(Succeed())
+		Expect(f.Close()).To(Succeed())
+
+		if checksum == "" {
+			data, err := os.ReadFile(tarball)
+			Expect(err).NotTo(HaveOccurred())
+			sum := sha512.Sum512(data)
+			checksum = hex.EncodeToString(sum[:])
+		}
+		sumFile := checksum + "  " + tag + ".tar.gz\n"
+		Expect(os.WriteFile(filepath.Join(tagDir, tag+".sha512sum"), []byte(sumFile), 0644)).To(Succeed())
+
+		rel, err := release.NewStaticSource(mirrorDir).Get(tag)
+		Expect(err).NotTo(HaveOccurred())
+		return rel
+	}
+
+	writeConfig := func(content string) {
+		Expect(os.WriteFile(filepath.Join(mockSteam.ConfigPath, "config.vdf"), []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "tools-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		steamPath := filepath.Join(tmpDir, "Steam")
+		Expect(os.MkdirAll(filepath.Join(steamPath, "config"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(steamPath, "steamapps", "common"), 0755)).To(Succeed())
+		mockSteam = &steam.Steam{
+			Path:       steamPath,
+			ConfigPath: filepath.Join(steamPath, "config"),
+			AppsPath:   filepath.Join(steamPath, "steamapps"),
+		}
+
+		mirrorDir = filepath.Join(tmpDir, "mirror")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("InstallCompatTool", func() {
+		It("should verify and install a release into compatibilitytools.d", func() {
+			rel := publishRelease("GE-Proton9-20", "")
+
+			toolPath, err := proton.InstallCompatTool(rel, mockSteam.UserCompatToolsPath(), filepath.Join(tmpDir, "cache"), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(toolPath).To(Equal(filepath.Join(mockSteam.UserCompatToolsPath(), "GE-Proton9-20")))
+
+			path, err := mockSteam.GetProtonPath("GE-Proton9-20")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(toolPath))
+
+			entries, err := os.ReadDir(mockSteam.UserCompatToolsPath())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("should reject a tarball that doesn't match its sha512sum", func() {
+			rel := publishRelease("GE-Proton9-20", "deadbeef")
+
+			_, err := proton.InstallCompatTool(rel, mockSteam.UserCompatToolsPath(), filepath.Join(tmpDir, "cache"), false)
+			Expect(err).To(MatchError(ContainSubstring("checksum mismatch")))
+			Expect(filepath.Join(mockSteam.UserCompatToolsPath(), "GE-Proton9-20")).NotTo(BeADirectory())
+			Expect(filepath.Join(tmpDir, "cache", "GE-Proton9-20.tar.gz")).NotTo(BeAnExistingFile())
+		})
+
+		It("should refuse to overwrite an installed tool", func() {
+			rel := publishRelease("GE-Proton9-20", "")
+			_, err := proton.InstallCompatTool(rel, mockSteam.UserCompatToolsPath(), filepath.Join(tmpDir, "cache"), false)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = proton.InstallCompatTool(rel, mockSteam.UserCompatToolsPath(), filepath.Join(tmpDir, "cache"), false)
+			Expect(err).To(MatchError(ContainSubstring("already installed")))
+		})
+
+		It("should fail for a release without a checksum", func() {
+			rel := &release.Release{TagName: "GE-Proton9-20", Assets: []release.Asset{{Name: "GE-Proton9-20.tar.gz"}}}
+
+			_, err := proton.InstallCompatTool(rel, mockSteam.UserCompatToolsPath(), filepath.Join(tmpDir, "cache"), false)
+			Expect(err).To(MatchError(ContainSubstring("sha512sum")))
+		})
+	})
+
+	Describe("CompatToolUsers", func() {
+		It("should return the AppIDs mapped to a tool", func() {
+			writeConfig(`"InstallConfigStore" { "Software" { "Valve" { "Steam" { "CompatToolMapping" {
+				"0" { "name" "proton_9" "config" "" "priority" "75" }
+				"4278190081" { "name" "GE-Proton9-20" "config" "" "priority" "250" }
+				"620" { "name" "GE-Proton9-20" "config" "" "priority" "250" }
+			} } } } }`)
+
+			users, err := proton.CompatToolUsers(mockSteam, "GE-Proton9-20")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(users).To(Equal([]string{"4278190081", "620"}))
+		})
+
+		It("should return nothing without a config.vdf", func() {
+			users, err := proton.CompatToolUsers(mockSteam, "GE-Proton9-20")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(users).To(BeEmpty())
+		})
+	})
+
+	Describe("RemoveCompatTool", func() {
+		var tool *steam.CompatTool
+
+		BeforeEach(func() {
+			rel := publishRelease("GE-Proton9-20", "")
+			_, err := proton.InstallCompatTool(rel, mockSteam.UserCompatToolsPath(), filepath.Join(tmpDir, "cache"), false)
+			Expect(err).NotTo(HaveOccurred())
+			tool, err = mockSteam.FindCompatTool("GE-Proton9-20")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tool).NotTo(BeNil())
+		})
+
+		It("should refuse to remove a tool that is still mapped", func() {
+			writeConfig(`"InstallConfigStore" { "Software" { "Valve" { "Steam" { "CompatToolMapping" {
+				"0" { "name" "GE-Proton9-20" }
+			} } } } }`)
+
+			err := proton.RemoveCompatTool(mockSteam, tool)
+			Expect(err).To(MatchError(ContainSubstring("the default for all games")))
+			Expect(tool.Path).To(BeADirectory())
+		})
+
+		It("should remove an unused tool", func() {
+			Expect(proton.RemoveCompatTool(mockSteam, tool)).To(Succeed())
+			Expect(tool.Path).NotTo(BeADirectory())
+		})
+	})
+})
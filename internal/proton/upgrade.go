@@ -0,0 +1,139 @@
+package proton
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// VersionChange describes how the selected Proton relates to the version
+// that created the prefix.
+type VersionChange int
+
+const (
+	// VersionUnknown means either version could not be determined.
+	VersionUnknown VersionChange = iota
+	// VersionSame means the prefix matches the selected Proton.
+	VersionSame
+	// VersionUpgrade means the selected Proton is newer than the prefix.
+	VersionUpgrade
+	// VersionDowngrade means the selected Proton is older than the prefix.
+	// Proton does not support downgrades and the prefix may break.
+	VersionDowngrade
+)
+
+// String returns a human-readable name for the change.
+func (v VersionChange) String() string {
+	switch v {
+	case VersionSame:
+		return "same"
+	case VersionUpgrade:
+		return "upgrade"
+	case VersionDowngrade:
+		return "downgrade"
+	default:
+		return "unknown"
+	}
+}
+
+// VersionCheck compares a prefix's version with the selected Proton.
+type VersionCheck struct {
+	// PrefixVersion is the version recorded in the prefix's version file.
+	PrefixVersion string
+	// ProtonVersion is the prefix version the selected Proton creates.
+	ProtonVersion string
+	Change        VersionChange
+}
+
+// prefixVersionPattern matches the prefix version constant in the proton script.
+var prefixVersionPattern = regexp.MustCompile(`(?m)^\s*CURRENT_PREFIX_VERSION\s*=\s*["']([^"']+)["']`)
+
+// versionNumberPattern matches the numeric parts of a version string.
+var versionNumberPattern = regexp.MustCompile(`\d+`)
+
+// CurrentPrefixVersion returns the prefix version a Proton installation
+// writes to new and upgraded prefixes, read from its proton script.
+// Returns "" if the script doesn't declare one.
+func CurrentPrefixVersion(protonPath string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(protonPath, "proton"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read proton script: %w", err)
+	}
+	if m := prefixVersionPattern.FindSubmatch(data); m != nil {
+		return string(m[1]), nil
+	}
+	return "", nil
+}
+
+// CompareVersions compares two Proton prefix versions such as "9.0-203" or
+// "GE-Proton9-20" by their numeric parts. Returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	an := versionNumbers(a)
+	bn := versionNumbers(b)
+	for i := 0; i < len(an) && i < len(bn); i++ {
+		if an[i] != bn[i] {
+			if an[i] < bn[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(an) < len(bn):
+		return -1
+	case len(an) > len(bn):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// versionNumbers extracts the numbers in a version string.
+func versionNumbers(v string) []int {
+	var numbers []int
+	for _, s := range versionNumberPattern.FindAllString(v, -1) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			n = 0
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// CheckVersion compares the prefix's version with the selected Proton.
+func (c *Config) CheckVersion() (*VersionCheck, error) {
+	prefixVersion, err := c.ProtonVersion()
+	if err != nil {
+		return nil, err
+	}
+	protonVersion, err := CurrentPrefixVersion(c.ProtonPath)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &VersionCheck{PrefixVersion: prefixVersion, ProtonVersion: protonVersion}
+	switch {
+	case prefixVersion == "" || protonVersion == "":
+		check.Change = VersionUnknown
+	case prefixVersion == protonVersion:
+		check.Change = VersionSame
+	case CompareVersions(protonVersion, prefixVersion) < 0:
+		check.Change = VersionDowngrade
+	default:
+		check.Change = VersionUpgrade
+	}
+	return check, nil
+}
+
+// NeedsUpgrade checks if an existing prefix was created by a different
+// Proton version than the selected one, so Proton must run to migrate it.
+func (c *Config) NeedsUpgrade() bool {
+	check, err := c.CheckVersion()
+	if err != nil {
+		return false
+	}
+	return check.Change == VersionUpgrade || check.Change == VersionDowngrade
+}
@@ -0,0 +1,114 @@
+package proton_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+// versionedProton returns a fake proton script that declares its prefix
+// version like the real script does, creates a prefix and logs each run.
+func versionedProton(version string) string {
+	return strings.Replace(fakeProton, "#!/bin/sh\n",
+		"#!/bin/sh\nCURRENT_PREFIX_VERSION=\""+version+"\"\necho run >> \"$STEAM_COMPAT_DATA_PATH/runs\"\n", 1) +
+		"echo \"$CURRENT_PREFIX_VERSION\" > \"$STEAM_COMPAT_DATA_PATH/version\"\n"
+}
+
+var _ = Describe("Upgrade", func() {
+	DescribeTable("CompareVersions",
+		func(a, b string, expected int) {
+			Expect(proton.CompareVersions(a, b)).To(Equal(expected))
+		},
+		Entry("same", "9.0-203", "9.0-203", 0),
+		Entry("newer build", "9.0-204", "9.0-203", 1),
+		Entry("newer major", "10.0-100", "9.0-203", 1),
+		Entry("older major", "8.0-104", "9.0-203", -1),
+		Entry("GE versions", "GE-Proton9-20", "GE-Proton10-4", -1),
+	)
+
+	Describe("with a prefix", func() {
+		var tmpDir string
+		var cfg *proton.Config
+
+		writeProton := func(version string) {
+			path := filepath.Join(cfg.ProtonPath, "proton")
+			Expect(os.MkdirAll(cfg.ProtonPath, 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte(versionedProton(version)), 0755)).To(Succeed())
+		}
+
+		runs := func() int {
+			data, _ := os.ReadFile(filepath.Join(cfg.CompatDataPath(), "runs"))
+			return strings.Count(string(data), "run")
+		}
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "proton-upgrade-test-*")
+			Expect(err).NotTo(HaveOccurred())
+
+			cfg = &proton.Config{
+				Steam:      &steam.Steam{Path: tmpDir, CompatPath: filepath.Join(tmpDir, "compatdata")},
+				AppID:      12345,
+				ProtonName: "Proton 9.0",
+				ProtonPath: filepath.Join(tmpDir, "Proton 9.0"),
+			}
+			writeProton("9.0-203")
+			Expect(cfg.InitializePrefix(true)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		It("should read the prefix version from the proton script", func() {
+			Expect(proton.CurrentPrefixVersion(cfg.ProtonPath)).To(Equal("9.0-203"))
+		})
+
+		It("should not rerun Proton for the same version", func() {
+			check, err := cfg.CheckVersion()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.Change).To(Equal(proton.VersionSame))
+
+			Expect(cfg.InitializePrefix(true)).To(Succeed())
+			Expect(runs()).To(Equal(1))
+		})
+
+		It("should upgrade the prefix with a newer Proton", func() {
+			writeProton("10.0-100")
+
+			check, err := cfg.CheckVersion()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.Change).To(Equal(proton.VersionUpgrade))
+			Expect(check.PrefixVersion).To(Equal("9.0-203"))
+			Expect(check.ProtonVersion).To(Equal("10.0-100"))
+
+			Expect(cfg.InitializePrefix(true)).To(Succeed())
+			Expect(runs()).To(Equal(2))
+			Expect(cfg.ProtonVersion()).To(Equal("10.0-100"))
+		})
+
+		It("should detect downgrades", func() {
+			writeProton("8.0-104")
+
+			check, err := cfg.CheckVersion()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.Change).To(Equal(proton.VersionDowngrade))
+			Expect(cfg.NeedsUpgrade()).To(BeTrue())
+		})
+
+		It("should treat a Proton without a declared version as unknown", func() {
+			Expect(os.WriteFile(filepath.Join(cfg.ProtonPath, "proton"), []byte(fakeProton), 0755)).To(Succeed())
+
+			check, err := cfg.CheckVersion()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.Change).To(Equal(proton.VersionUnknown))
+			Expect(cfg.NeedsUpgrade()).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,112 @@
+package proton
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/winereg"
+)
+
+// winetricksLog is the file winetricks appends each installed verb to.
+const winetricksLog = "winetricks.log"
+
+// verbCheck detects a verb installed without winetricks (e.g. by an official installer).
+type verbCheck func(prefixPath string) bool
+
+// verbChecks holds file and registry checks for verbs we install.
+var verbChecks = map[string]verbCheck{
+	"dotnetdesktop6": func(prefixPath string) bool {
+		matches, _ := filepath.Glob(filepath.Join(prefixPath, "drive_c", "Program Files", "dotnet", "shared", "Microsoft.WindowsDesktop.App", "6.*"))
+		if len(matches) == 0 {
+			return false
+		}
+		return regKeyHasValue(filepath.Join(prefixPath, SystemReg),
+			`Software\dotnet\Setup\InstalledVersions\x64\sharedfx\Microsoft.WindowsDesktop.App`, "6.")
+	},
+}
+
+// InstalledVerbs returns the verbs recorded in the prefix's winetricks.log.
+func InstalledVerbs(prefixPath string) (map[string]bool, error) {
+	f, err := os.Open(filepath.Join(prefixPath, winetricksLog))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]bool{}, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	verbs := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// winetricks also logs option pseudo-verbs like "-q" or "isolate_home"
+		if line == "" || strings.HasPrefix(line, "-") {
+			continue
+		}
+		verbs[line] = true
+	}
+
+	return verbs, scanner.Err()
+}
+
+// VerbInstalled checks if a winetricks verb is satisfied in the prefix,
+// either because winetricks logged it or because its files and registry
+// keys are present.
+func VerbInstalled(prefixPath string, verb string) bool {
+	if verbs, err := InstalledVerbs(prefixPath); err == nil && verbs[verb] {
+		return true
+	}
+	if check, ok := verbChecks[verb]; ok {
+		return check(prefixPath)
+	}
+	return false
+}
+
+// VerbInstalled checks if a winetricks verb is satisfied in the app's prefix.
+func (c *Config) VerbInstalled(verb string) bool {
+	return VerbInstalled(c.PrefixPath(), verb)
+}
+
+// InstalledVerbs returns the verbs satisfied in the app's prefix, sorted:
+// those logged by winetricks plus those detected by their files and registry keys.
+func (c *Config) InstalledVerbs() ([]string, error) {
+	verbs, err := InstalledVerbs(c.PrefixPath())
+	if err != nil {
+		return nil, err
+	}
+	for verb, check := range verbChecks {
+		if !verbs[verb] && check(c.PrefixPath()) {
+			verbs[verb] = true
+		}
+	}
+
+	list := make([]string, 0, len(verbs))
+	for verb := range verbs {
+		list = append(list, verb)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// regKeyHasValue checks if a key in a Wine registry file has a value whose
+// name starts with valuePrefix.
+func regKeyHasValue(regPath string, key string, valuePrefix string) bool {
+	reg, err := winereg.ParseFile(regPath)
+	if err != nil {
+		return false
+	}
+	k := reg.Key(key)
+	if k == nil {
+		return false
+	}
+	for _, v := range k.Values {
+		if strings.HasPrefix(v.Name, valuePrefix) {
+			return true
+		}
+	}
+	return false
+}
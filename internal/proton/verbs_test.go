@@ -0,0 +1,103 @@
+package proton_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("Verbs", func() {
+	var tmpDir string
+	var prefixPath string
+
+	writePrefixFile := func(rel, content string) {
+		path := filepath.Join(prefixPath, rel)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	dotnetRegistry := `WINE REGISTRY Version 2
+;; All keys relative to \\Machine
+
+[Software\\dotnet\\Setup\\InstalledVersions\\x64\\sharedfx\\Microsoft.WindowsDesktop.App] 1700000000
+#time=1da0000000000000
+"6.0.36"=dword:00000001
+
+[Software\\Microsoft\\Windows] 1700000000
+`
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "proton-verbs-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		prefixPath = filepath.Join(tmpDir, "pfx")
+		Expect(os.MkdirAll(prefixPath, 0755)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("InstalledVerbs", func() {
+		It("should read winetricks.log", func() {
+			writePrefixFile("winetricks.log", "-q\ncorefonts\ndotnetdesktop6\n\n")
+
+			verbs, err := proton.InstalledVerbs(prefixPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verbs).To(Equal(map[string]bool{"corefonts": true, "dotnetdesktop6": true}))
+		})
+
+		It("should return no verbs without a log", func() {
+			verbs, err := proton.InstalledVerbs(prefixPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verbs).To(BeEmpty())
+		})
+	})
+
+	Describe("VerbInstalled", func() {
+		It("should trust winetricks.log", func() {
+			writePrefixFile("winetricks.log", "corefonts\n")
+			Expect(proton.VerbInstalled(prefixPath, "corefonts")).To(BeTrue())
+			Expect(proton.VerbInstalled(prefixPath, "vcrun2019")).To(BeFalse())
+		})
+
+		It("should detect .NET Desktop 6 from its files and registry keys", func() {
+			writePrefixFile("drive_c/Program Files/dotnet/shared/Microsoft.WindowsDesktop.App/6.0.36/WindowsBase.dll", "")
+			writePrefixFile("system.reg", dotnetRegistry)
+
+			Expect(proton.VerbInstalled(prefixPath, "dotnetdesktop6")).To(BeTrue())
+		})
+
+		It("should not detect .NET Desktop 6 from files alone", func() {
+			writePrefixFile("drive_c/Program Files/dotnet/shared/Microsoft.WindowsDesktop.App/6.0.36/WindowsBase.dll", "")
+			writePrefixFile("system.reg", "WINE REGISTRY Version 2\n")
+
+			Expect(proton.VerbInstalled(prefixPath, "dotnetdesktop6")).To(BeFalse())
+		})
+
+		It("should not detect .NET Desktop 6 from the registry alone", func() {
+			writePrefixFile("system.reg", dotnetRegistry)
+
+			Expect(proton.VerbInstalled(prefixPath, "dotnetdesktop6")).To(BeFalse())
+		})
+	})
+
+	Describe("Config.InstalledVerbs", func() {
+		It("should combine logged and detected verbs", func() {
+			cfg := &proton.Config{Steam: &steam.Steam{CompatPath: tmpDir}, AppID: 1}
+			prefixPath = cfg.PrefixPath()
+			writePrefixFile("winetricks.log", "-q\ncorefonts\n")
+			writePrefixFile("drive_c/Program Files/dotnet/shared/Microsoft.WindowsDesktop.App/6.0.36/WindowsBase.dll", "")
+			writePrefixFile("system.reg", dotnetRegistry)
+
+			verbs, err := cfg.InstalledVerbs()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verbs).To(Equal([]string{"corefonts", "dotnetdesktop6"}))
+		})
+	})
+})
@@ -0,0 +1,295 @@
+package proton
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+// Kind is the release channel of a Proton installation.
+type Kind int
+
+const (
+	// KindOther is a tool that isn't recognized, e.g. a custom build.
+	KindOther Kind = iota
+	// KindOfficial is a numbered Valve release such as "Proton 9.0".
+	KindOfficial
+	// KindExperimental is Proton Experimental.
+	KindExperimental
+	// KindHotfix is Proton Hotfix.
+	KindHotfix
+	// KindGE is a GloriousEggroll build such as "GE-Proton9-20".
+	KindGE
+)
+
+// Compatibility statuses of tested versions.
+const (
+	KnownGood = "good"
+	KnownBad  = "bad"
+	Untested  = "untested"
+)
+
+// ProtonVersion is an installed Proton with its parsed version.
+type ProtonVersion struct {
+	// Name is the name listed by Steam.GetProtonVersions.
+	Name string
+	// Path is the installation directory.
+	Path string
+	Kind Kind
+	// Version is the build version, e.g. "9.0-2" or "9-20"; "" if unknown.
+	Version string
+	// Major is the major version; 0 if unknown.
+	Major int
+}
+
+// TestedVersion records how a Proton family works with the game.
+type TestedVersion struct {
+	// Family is a version family as returned by ProtonVersion.Family.
+	Family string
+	// Status is KnownGood, KnownBad or Untested.
+	Status string
+	// Note explains the status.
+	Note string
+	// Source links the test report behind a KnownGood or KnownBad status.
+	Source string
+}
+
+// TestedVersions lists the Proton families in order of preference. Only
+// families with a test report as Source are KnownGood or KnownBad; the
+// others are preferred for being newer, but haven't been tested with the game.
+var TestedVersions = []TestedVersion{
+	{Family: "Proton 10", Status: Untested},
+	{Family: "Proton 9", Status: Untested},
+	{Family: "GE-Proton 10", Status: Untested},
+	{Family: "GE-Proton 9", Status: Untested},
+	{Family: "Proton Experimental", Status: Untested, Note: "changes often"},
+}
+
+var (
+	officialNamePattern = regexp.MustCompile(`^Proton\s+(\d+(?:\.\d+)*)`)
+	geNamePattern       = regexp.MustCompile(`^GE-Proton(\d+)-(\d+)`)
+	// versionFilePattern matches "<timestamp> <build>" in a tool's version file.
+	versionFilePattern = regexp.MustCompile(`^\d+\s+(\S+)`)
+	// buildVersionPattern matches the version in builds like "proton-9.0-2".
+	buildVersionPattern = regexp.MustCompile(`\d+(?:[.-]\d+)*`)
+)
+
+// ParseProtonVersion determines the kind and version of a Proton from its
+// name and, if present, the version file in its directory. The version file
+// is preferred since it also carries the minor build number.
+func ParseProtonVersion(name, path string) ProtonVersion {
+	v := ProtonVersion{Name: name, Path: path}
+
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "experimental"):
+		v.Kind = KindExperimental
+	case strings.Contains(lower, "hotfix"):
+		v.Kind = KindHotfix
+	case geNamePattern.MatchString(name):
+		v.Kind = KindGE
+		m := geNamePattern.FindStringSubmatch(name)
+		v.Version = m[1] + "-" + m[2]
+	case officialNamePattern.MatchString(name):
+		v.Kind = KindOfficial
+		v.Version = officialNamePattern.FindStringSubmatch(name)[1]
+	}
+
+	if build := readVersionFile(path); build != "" {
+		if strings.HasPrefix(build, "GE-Proton") && v.Kind == KindOther {
+			v.Kind = KindGE
+		}
+		if version := buildVersionPattern.FindString(strings.TrimPrefix(build, "GE-Proton")); version != "" {
+			v.Version = version
+		}
+	}
+
+	if numbers := versionNumbers(v.Version); len(numbers) > 0 {
+		v.Major = numbers[0]
+	}
+	return v
+}
+
+// readVersionFile returns the build name from a tool's version file, or "".
+func readVersionFile(path string) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(path, versionFile))
+	if err != nil {
+		return ""
+	}
+	if m := versionFilePattern.FindStringSubmatch(strings.TrimSpace(string(data))); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Family returns the version family used in TestedVersions, e.g. "Proton 9",
+// "GE-Proton 9" or "Proton Experimental". Returns "" for unknown tools.
+func (v ProtonVersion) Family() string {
+	switch {
+	case v.Kind == KindExperimental:
+		return "Proton Experimental"
+	case v.Kind == KindHotfix:
+		return "Proton Hotfix"
+	case v.Kind == KindGE && v.Major > 0:
+		return fmt.Sprintf("GE-Proton %d", v.Major)
+	case v.Kind == KindOfficial && v.Major > 0:
+		return fmt.Sprintf("Proton %d", v.Major)
+	default:
+		return ""
+	}
+}
+
+// Tested returns the tested-compatibility entry for the version, or nil.
+func (v ProtonVersion) Tested() *TestedVersion {
+	family := v.Family()
+	if family == "" {
+		return nil
+	}
+	for i := range TestedVersions {
+		if TestedVersions[i].Family == family {
+			return &TestedVersions[i]
+		}
+	}
+	return nil
+}
+
+// kindOrder is the listing order of the kinds.
+var kindOrder = map[Kind]int{
+	KindExperimental: 0,
+	KindGE:           1,
+	KindOfficial:     2,
+	KindHotfix:       2,
+	KindOther:        3,
+}
+
+// SortProtonVersions sorts versions by kind (Experimental, GE-Proton, official,
+// others), newest first within each kind.
+func SortProtonVersions(versions []ProtonVersion) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, b := versions[i], versions[j]
+		if kindOrder[a.Kind] != kindOrder[b.Kind] {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if c := CompareVersions(a.Version, b.Version); c != 0 {
+			return c > 0
+		}
+		return a.Name < b.Name
+	})
+}
+
+// ListProtonVersions returns the installed Proton versions, parsed and sorted.
+func ListProtonVersions(s *steam.Steam) ([]ProtonVersion, error) {
+	names, err := s.GetProtonVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]ProtonVersion, 0, len(names))
+	for _, name := range names {
+		path, err := s.GetProtonPath(name)
+		if err != nil {
+			path = ""
+		}
+		versions = append(versions, ParseProtonVersion(name, path))
+	}
+	SortProtonVersions(versions)
+	return versions, nil
+}
+
+// Recommendation is the suggested default Proton and why it was picked.
+type Recommendation struct {
+	Version ProtonVersion
+	Reason  string
+}
+
+// Recommend picks a default from versions, which must be sorted. It prefers
+// the first family in TestedVersions that isn't known-bad, then the newest
+// version without known problems, and only falls back to a known-bad version
+// if nothing else is installed.
+func Recommend(versions []ProtonVersion) (*Recommendation, error) {
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no Proton versions found")
+	}
+
+	for _, tested := range TestedVersions {
+		if tested.Status == KnownBad {
+			continue
+		}
+		if v := newestInFamily(versions, tested.Family); v != nil {
+			return &Recommendation{Version: *v, Reason: tested.reason()}, nil
+		}
+	}
+
+	newest := newestUntested(versions)
+	if newest != nil {
+		return &Recommendation{
+			Version: *newest,
+			Reason:  "no preferred version is installed; this is the newest installed version without known problems",
+		}, nil
+	}
+
+	reason := "only versions with known problems are installed"
+	for _, tested := range TestedVersions {
+		if tested.Status != KnownBad {
+			reason += fmt.Sprintf("; consider installing %s", tested.Family)
+			break
+		}
+	}
+	return &Recommendation{Version: versions[0], Reason: reason}, nil
+}
+
+// reason explains why the family is recommended.
+func (t TestedVersion) reason() string {
+	if t.Status == KnownGood {
+		reason := fmt.Sprintf("%s is known to work", t.Family)
+		if t.Note != "" {
+			reason += ": " + t.Note
+		}
+		if t.Source != "" {
+			reason += " (" + t.Source + ")"
+		}
+		return reason
+	}
+
+	reason := fmt.Sprintf("%s is the preferred Proton, but hasn't been tested with the game", t.Family)
+	if t.Note != "" {
+		reason += "; it " + t.Note
+	}
+	return reason
+}
+
+// newestInFamily returns the newest version in a family, or nil.
+func newestInFamily(versions []ProtonVersion, family string) *ProtonVersion {
+	var newest *ProtonVersion
+	for i := range versions {
+		if versions[i].Family() != family {
+			continue
+		}
+		if newest == nil || CompareVersions(versions[i].Version, newest.Version) > 0 {
+			newest = &versions[i]
+		}
+	}
+	return newest
+}
+
+// newestUntested returns the newest version that isn't known-bad, or nil.
+func newestUntested(versions []ProtonVersion) *ProtonVersion {
+	var newest *ProtonVersion
+	for i := range versions {
+		if tested := versions[i].Tested(); tested != nil && tested.Status == KnownBad {
+			continue
+		}
+		if newest == nil || CompareVersions(versions[i].Version, newest.Version) > 0 {
+			newest = &versions[i]
+		}
+	}
+	return newest
+}
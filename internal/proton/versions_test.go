@@ -0,0 +1,196 @@
+package proton_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("Versions", func() {
+	var tmpDir string
+
+	// versionDir creates a tool directory with a version file.
+	versionDir := func(name, build string) string {
+		path := filepath.Join(tmpDir, name)
+		Expect(os.MkdirAll(path, 0755)).To(Succeed())
+		if build != "" {
+			Expect(os.WriteFile(filepath.Join(path, "version"), []byte("1718888888 "+build+"\n"), 0644)).To(Succeed())
+		}
+		return path
+	}
+
+	// names returns the names of versions.
+	names := func(versions []proton.ProtonVersion) []string {
+		var result []string
+		for _, v := range versions {
+			result = append(result, v.Name)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "versions-test-*")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("ParseProtonVersion", func() {
+		DescribeTable("should parse names",
+			func(name string, kind proton.Kind, version string, family string) {
+				v := proton.ParseProtonVersion(name, "")
+				Expect(v.Kind).To(Equal(kind))
+				Expect(v.Version).To(Equal(version))
+				Expect(v.Family()).To(Equal(family))
+			},
+			Entry("official", "Proton 9.0", proton.KindOfficial, "9.0", "Proton 9"),
+			Entry("official beta", "Proton 9.0 (Beta)", proton.KindOfficial, "9.0", "Proton 9"),
+			Entry("minor release", "Proton 6.3", proton.KindOfficial, "6.3", "Proton 6"),
+			Entry("GE-Proton", "GE-Proton9-20", proton.KindGE, "9-20", "GE-Proton 9"),
+			Entry("experimental", "Proton - Experimental", proton.KindExperimental, "", "Proton Experimental"),
+			Entry("hotfix", "Proton Hotfix", proton.KindHotfix, "", "Proton Hotfix"),
+			Entry("unknown", "Proton-tkg", proton.KindOther, "", ""),
+		)
+
+		It("should prefer the version file", func() {
+			v := proton.ParseProtonVersion("Proton 9.0", versionDir("Proton 9.0", "proton-9.0-4"))
+			Expect(v.Version).To(Equal("9.0-4"))
+			Expect(v.Major).To(Equal(9))
+		})
+
+		It("should detect GE-Proton from the version file", func() {
+			v := proton.ParseProtonVersion("My GE Build", versionDir("custom", "GE-Proton10-3"))
+			Expect(v.Kind).To(Equal(proton.KindGE))
+			Expect(v.Version).To(Equal("10-3"))
+			Expect(v.Family()).To(Equal("GE-Proton 10"))
+		})
+	})
+
+	Describe("SortProtonVersions", func() {
+		It("should sort by kind and newest first", func() {
+			versions := []proton.ProtonVersion{
+				proton.ParseProtonVersion("Proton 9.0", ""),
+				proton.ParseProtonVersion("GE-Proton9-20", ""),
+				proton.ParseProtonVersion("Proton 10.0", ""),
+				proton.ParseProtonVersion("Proton-tkg", ""),
+				proton.ParseProtonVersion("GE-Proton10-3", ""),
+				proton.ParseProtonVersion("Proton Experimental", ""),
+				proton.ParseProtonVersion("Proton 8.0", ""),
+				proton.ParseProtonVersion("GE-Proton9-7", ""),
+			}
+			proton.SortProtonVersions(versions)
+			Expect(names(versions)).To(Equal([]string{
+				"Proton Experimental",
+				"GE-Proton10-3", "GE-Proton9-20", "GE-Proton9-7",
+				"Proton 10.0", "Proton 9.0", "Proton 8.0",
+				"Proton-tkg",
+			}))
+		})
+
+		It("should order builds of the same version by the version file", func() {
+			versions := []proton.ProtonVersion{
+				proton.ParseProtonVersion("Proton 9.0", versionDir("a", "proton-9.0-2")),
+				proton.ParseProtonVersion("Proton 9.0 (Beta)", versionDir("b", "proton-9.0-10")),
+			}
+			proton.SortProtonVersions(versions)
+			Expect(names(versions)).To(Equal([]string{"Proton 9.0 (Beta)", "Proton 9.0"}))
+		})
+	})
+
+	Describe("ListProtonVersions", func() {
+		It("should list installed versions newest first", func() {
+			steamPath := filepath.Join(tmpDir, "Steam")
+			for _, name := range []string{"Proton 9.0", "Proton 10.0", "Proton 8.0"} {
+				Expect(os.MkdirAll(filepath.Join(steamPath, "steamapps", "common", name), 0755)).To(Succeed())
+			}
+			s := &steam.Steam{Path: steamPath, AppsPath: filepath.Join(steamPath, "steamapps")}
+
+			versions, err := proton.ListProtonVersions(s)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(versions)).To(Equal([]string{"Proton 10.0", "Proton 9.0", "Proton 8.0"}))
+			Expect(versions[0].Path).To(Equal(filepath.Join(steamPath, "steamapps", "common", "Proton 10.0")))
+		})
+	})
+
+	Describe("Recommend", func() {
+		recommend := func(names ...string) *proton.Recommendation {
+			var versions []proton.ProtonVersion
+			for _, name := range names {
+				versions = append(versions, proton.ParseProtonVersion(name, ""))
+			}
+			proton.SortProtonVersions(versions)
+			rec, err := proton.Recommend(versions)
+			Expect(err).NotTo(HaveOccurred())
+			return rec
+		}
+
+		It("should prefer the first family in the table over newer builds", func() {
+			rec := recommend("Proton Experimental", "GE-Proton10-3", "Proton 9.0", "Proton 10.0")
+			Expect(rec.Version.Name).To(Equal("Proton 10.0"))
+		})
+
+		It("should not claim untested families are known to work", func() {
+			for _, tested := range proton.TestedVersions {
+				if tested.Status != proton.Untested {
+					Expect(tested.Source).NotTo(BeEmpty(), tested.Family)
+				}
+			}
+
+			rec := recommend("Proton 9.0")
+			Expect(rec.Reason).To(ContainSubstring("hasn't been tested"))
+			Expect(rec.Reason).NotTo(ContainSubstring("known to work"))
+		})
+
+		It("should pick the newest build within a family", func() {
+			rec := recommend("GE-Proton9-7", "GE-Proton9-20", "Proton 8.0")
+			Expect(rec.Version.Name).To(Equal("GE-Proton9-20"))
+		})
+
+		Context("with test results", func() {
+			var original []proton.TestedVersion
+
+			BeforeEach(func() {
+				original = proton.TestedVersions
+				proton.TestedVersions = []proton.TestedVersion{
+					{Family: "Proton 9", Status: proton.KnownGood, Note: "runs the patched game", Source: "https://example.com/report/1"},
+					{Family: "Proton 5", Status: proton.KnownBad, Note: "the .NET 6 runtime installer fails", Source: "https://example.com/report/2"},
+				}
+			})
+
+			AfterEach(func() {
+				proton.TestedVersions = original
+			})
+
+			It("should cite the test report of a known-good family", func() {
+				rec := recommend("Proton 10.0", "Proton 9.0")
+				Expect(rec.Version.Name).To(Equal("Proton 9.0"))
+				Expect(rec.Reason).To(Equal("Proton 9 is known to work: runs the patched game (https://example.com/report/1)"))
+			})
+
+			It("should fall back to the newest version without known problems", func() {
+				rec := recommend("Proton 5.0", "Proton 8.0", "Proton 7.0")
+				Expect(rec.Version.Name).To(Equal("Proton 8.0"))
+				Expect(rec.Reason).To(ContainSubstring("no preferred version"))
+			})
+
+			It("should suggest a family from the table when only known-bad versions are installed", func() {
+				rec := recommend("Proton 5.0")
+				Expect(rec.Version.Name).To(Equal("Proton 5.0"))
+				Expect(rec.Reason).To(Equal("only versions with known problems are installed; consider installing Proton 9"))
+			})
+		})
+
+		It("should fail without versions", func() {
+			_, err := proton.Recommend(nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -210,6 +210,25 @@ func (r *Runner) LaunchInDir(appID uint32, exePath string, workDir string, opts
 	return nil
 }
 
+// WaitForWineserver waits until the wineserver of a game's Wine prefix has
+// exited, by running "wineserver -w" in the prefix.
+func (r *Runner) WaitForWineserver(appID uint32) error {
+	cmd := r.buildCommand("-c", "wineserver -w", fmt.Sprintf("%d", appID))
+
+	var outputBuf bytes.Buffer
+	cmd.Stdout = &outputBuf
+	cmd.Stderr = &outputBuf
+
+	if err := cmd.Run(); err != nil {
+		if outputBuf.Len() > 0 {
+			fmt.Fprintf(os.Stderr, "\n--- Command output (on error) ---\n%s\n--- End output ---\n", outputBuf.String())
+		}
+		return fmt.Errorf("failed to wait for wineserver: %w", err)
+	}
+
+	return nil
+}
+
 // RunWinetricks runs winetricks directly with custom arguments.
 func (r *Runner) RunWinetricks(appID uint32, args ...string) error {
 	fullArgs := append([]string{fmt.Sprintf("%d", appID)}, args...)
@@ -0,0 +1,271 @@
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultGitHubAPIURL is the base URL of the public GitHub API.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubSource reads releases from the GitHub REST API or a compatible API.
+type GitHubSource struct {
+	// BaseURL is the API base URL. Defaults to DefaultGitHubAPIURL.
+	BaseURL string
+	// Repo is the repository in "owner/name" form.
+	Repo string
+	// Token is sent as a bearer token when set. Defaults to $GITHUB_TOKEN.
+	Token string
+	// CacheDir stores ETag-tagged responses for conditional requests.
+	// Caching is disabled when empty.
+	CacheDir string
+	// Client is the HTTP client used for requests.
+	Client *http.Client
+}
+
+// NewGitHubSource creates a GitHub release source for a repository.
+func NewGitHubSource(repo string, cacheDir string) *GitHubSource {
+	return &GitHubSource{
+		BaseURL:  DefaultGitHubAPIURL,
+		Repo:     repo,
+		Token:    os.Getenv("GITHUB_TOKEN"),
+		CacheDir: cacheDir,
+		Client:   http.DefaultClient,
+	}
+}
+
+// RateLimitError is returned when the API rate limit has been exhausted.
+type RateLimitError struct {
+	Limit int
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	msg := "GitHub API rate limit exceeded"
+	if e.Limit > 0 {
+		msg += fmt.Sprintf(" (%d requests/hour)", e.Limit)
+	}
+	if !e.Reset.IsZero() {
+		wait := time.Until(e.Reset).Round(time.Minute)
+		if wait < time.Minute {
+			wait = time.Minute
+		}
+		msg += fmt.Sprintf("; resets at %s (in %s)", e.Reset.Local().Format("15:04"), wait)
+	}
+	return msg + ". Set GITHUB_TOKEN to raise the limit"
+}
+
+// Latest returns the latest release.
+func (g *GitHubSource) Latest() (*Release, error) {
+	var release Release
+	if err := g.get(fmt.Sprintf("/repos/%s/releases/latest", g.Repo), &release); err != nil {
+		return nil, fmt.Errorf("failed to fetch release info: %w", err)
+	}
+	return &release, nil
+}
+
+// listPageSize is how many releases List requests per page, the API's maximum.
+const listPageSize = 100
+
+// List returns all releases, newest first, following the API's pagination.
+func (g *GitHubSource) List() ([]Release, error) {
+	var releases []Release
+	reqURL := g.BaseURL + fmt.Sprintf("/repos/%s/releases?per_page=%d", g.Repo, listPageSize)
+	seen := make(map[string]bool)
+	for reqURL != "" && !seen[reqURL] {
+		seen[reqURL] = true
+
+		var page []Release
+		next, err := g.fetch(reqURL, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch releases: %w", err)
+		}
+		releases = append(releases, page...)
+		reqURL = next
+	}
+	return releases, nil
+}
+
+// Get returns the release with the given tag.
+func (g *GitHubSource) Get(tag string) (*Release, error) {
+	var release Release
+	if err := g.get(fmt.Sprintf("/repos/%s/releases/tags/%s", g.Repo, url.PathEscape(tag)), &release); err != nil {
+		return nil, fmt.Errorf("failed to fetch release %s: %w", tag, err)
+	}
+	return &release, nil
+}
+
+// cacheEntry is a cached API response.
+type cacheEntry struct {
+	ETag string          `json:"etag"`
+	Next string          `json:"next,omitempty"`
+	Body json.RawMessage `json:"body"`
+}
+
+// get performs a GET request against the API and decodes the JSON response.
+func (g *GitHubSource) get(path string, v interface{}) error {
+	_, err := g.fetch(g.BaseURL+path, v)
+	return err
+}
+
+// fetch performs a GET request for reqURL and decodes the JSON response.
+// Returns the URL of the next page from the Link header, if any. Responses
+// carrying an ETag are cached and revalidated with If-None-Match.
+func (g *GitHubSource) fetch(reqURL string, v interface{}) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	cached := g.loadCache(reqURL)
+	if cached != nil && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return cached.Next, json.Unmarshal(cached.Body, v)
+
+	case resp.StatusCode == http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read response: %w", err)
+		}
+		if err := json.Unmarshal(body, v); err != nil {
+			return "", fmt.Errorf("failed to parse response: %w", err)
+		}
+		next := nextPageURL(resp.Header.Get("Link"))
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			g.saveCache(reqURL, &cacheEntry{ETag: etag, Next: next, Body: body})
+		}
+		return next, nil
+	}
+
+	if rlErr := rateLimitError(resp); rlErr != nil {
+		// Serve stale data rather than failing outright
+		if cached != nil {
+			return cached.Next, json.Unmarshal(cached.Body, v)
+		}
+		return "", rlErr
+	}
+
+	return "", fmt.Errorf("GitHub API returned status: %s", resp.Status)
+}
+
+// nextPageURL returns the rel="next" URL of a Link header, or "" if there is
+// none.
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// rateLimitError inspects a response for rate limit headers.
+// Returns nil if the response is not a rate limit rejection.
+func rateLimitError(resp *http.Response) error {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+
+	rlErr := &RateLimitError{}
+	rlErr.Limit, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+
+	if retry := resp.Header.Get("Retry-After"); retry != "" {
+		if secs, err := strconv.Atoi(retry); err == nil {
+			rlErr.Reset = time.Now().Add(time.Duration(secs) * time.Second)
+		}
+		return rlErr
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return nil
+	}
+
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rlErr.Reset = time.Unix(reset, 0)
+	}
+
+	return rlErr
+}
+
+// IsRateLimited reports whether err was caused by an exhausted API rate limit.
+func IsRateLimited(err error) bool {
+	var rlErr *RateLimitError
+	return errors.As(err, &rlErr)
+}
+
+// cachePath returns the cache file path for a request URL.
+func (g *GitHubSource) cachePath(reqURL string) string {
+	sum := sha256.Sum256([]byte(reqURL))
+	return filepath.Join(g.CacheDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// loadCache returns the cached response for a URL, or nil if none exists.
+func (g *GitHubSource) loadCache(reqURL string) *cacheEntry {
+	if g.CacheDir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(g.cachePath(reqURL))
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// saveCache stores a response in the cache. Failures are ignored.
+func (g *GitHubSource) saveCache(reqURL string, entry *cacheEntry) {
+	if g.CacheDir == "" {
+		return
+	}
+
+	if err := os.MkdirAll(g.CacheDir, 0755); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(g.cachePath(reqURL), data, 0644)
+}
@@ -0,0 +1,187 @@
+package release_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/release"
+)
+
+var _ = Describe("GitHubSource", func() {
+	var tmpDir string
+	var server *httptest.Server
+	var handler http.HandlerFunc
+	var src *release.GitHubSource
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "release-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+
+		src = release.NewGitHubSource("owner/repo", tmpDir)
+		src.BaseURL = server.URL
+		src.Token = ""
+	})
+
+	AfterEach(func() {
+		server.Close()
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("Latest", func() {
+		It("should fetch the latest release from the base URL", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal("/repos/owner/repo/releases/latest"))
+				_, _ = fmt.Fprint(w, `{"tag_name":"v1.2.3","assets":[{"name":"LADXHD.Patcher.exe","browser_download_url":"https://example.com/p.exe"}]}`)
+			}
+
+			rel, err := src.Latest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rel.TagName).To(Equal("v1.2.3"))
+			Expect(rel.Assets).To(HaveLen(1))
+		})
+
+		It("should send the token as a bearer token", func() {
+			src.Token = "secret"
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer secret"))
+				_, _ = fmt.Fprint(w, `{"tag_name":"v1"}`)
+			}
+
+			_, err := src.Latest()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should revalidate cached responses with If-None-Match", func() {
+			requests := 0
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.Header.Get("If-None-Match") == `"abc"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"abc"`)
+				_, _ = fmt.Fprint(w, `{"tag_name":"v2.0.0"}`)
+			}
+
+			rel, err := src.Latest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rel.TagName).To(Equal("v2.0.0"))
+
+			rel, err = src.Latest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rel.TagName).To(Equal("v2.0.0"))
+			Expect(requests).To(Equal(2))
+		})
+
+		It("should return a rate limit error with reset time", func() {
+			reset := time.Now().Add(30 * time.Minute)
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-RateLimit-Limit", "60")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+			}
+
+			_, err := src.Latest()
+			Expect(err).To(HaveOccurred())
+			Expect(release.IsRateLimited(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("rate limit exceeded (60 requests/hour)"))
+			Expect(err.Error()).To(ContainSubstring("GITHUB_TOKEN"))
+		})
+
+		It("should serve cached data when rate limited", func() {
+			limited := false
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				if limited {
+					w.Header().Set("X-RateLimit-Remaining", "0")
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Header().Set("ETag", `"abc"`)
+				_, _ = fmt.Fprint(w, `{"tag_name":"v3.0.0"}`)
+			}
+
+			_, err := src.Latest()
+			Expect(err).NotTo(HaveOccurred())
+
+			limited = true
+			rel, err := src.Latest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rel.TagName).To(Equal("v3.0.0"))
+		})
+
+		It("should return error for other failures", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			}
+
+			_, err := src.Latest()
+			Expect(err).To(HaveOccurred())
+			Expect(release.IsRateLimited(err)).To(BeFalse())
+			Expect(err.Error()).To(ContainSubstring("403"))
+		})
+	})
+
+	Describe("List", func() {
+		It("should list releases", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal("/repos/owner/repo/releases"))
+				_, _ = fmt.Fprint(w, `[{"tag_name":"v2"},{"tag_name":"v1"}]`)
+			}
+
+			releases, err := src.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(HaveLen(2))
+			Expect(releases[0].TagName).To(Equal("v2"))
+		})
+
+		It("should follow the next page links", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Query().Get("per_page")).To(Equal("100"))
+				switch r.URL.Query().Get("page") {
+				case "":
+					w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/releases?per_page=100&page=2>; rel="next", <%s/repos/owner/repo/releases?per_page=100&page=2>; rel="last"`, server.URL, server.URL))
+					_, _ = fmt.Fprint(w, `[{"tag_name":"v3"},{"tag_name":"v2"}]`)
+				case "2":
+					w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/releases?per_page=100&page=1>; rel="prev", <%s/repos/owner/repo/releases?per_page=100&page=1>; rel="first"`, server.URL, server.URL))
+					_, _ = fmt.Fprint(w, `[{"tag_name":"v1"}]`)
+				default:
+					Fail("unexpected page " + r.URL.RawQuery)
+				}
+			}
+
+			releases, err := src.List()
+			Expect(err).NotTo(HaveOccurred())
+			var tags []string
+			for _, r := range releases {
+				tags = append(tags, r.TagName)
+			}
+			Expect(tags).To(Equal([]string{"v3", "v2", "v1"}))
+		})
+	})
+
+	Describe("Get", func() {
+		It("should fetch a release by tag", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal("/repos/owner/repo/releases/tags/v1.0.0"))
+				_, _ = fmt.Fprint(w, `{"tag_name":"v1.0.0"}`)
+			}
+
+			rel, err := src.Get("v1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rel.TagName).To(Equal("v1.0.0"))
+		})
+	})
+})
@@ -0,0 +1,102 @@
+// Package release provides access to published releases from GitHub-compatible
+// APIs and static mirrors.
+package release
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+)
+
+// Release represents a published release.
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Name    string  `json:"name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset represents a downloadable release asset.
+type Asset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+	Size        int64  `json:"size"`
+}
+
+// Source provides release information for a project.
+type Source interface {
+	// Latest returns the most recent release.
+	Latest() (*Release, error)
+	// List returns all releases, newest first.
+	List() ([]Release, error)
+	// Get returns the release with the given tag.
+	Get(tag string) (*Release, error)
+}
+
+// Open returns a release source for the given location.
+//
+// An empty location uses the public GitHub API. A URL ending in .json, a
+// local file, or a local directory is treated as a static mirror. Any other
+// URL is treated as the base URL of a GitHub-compatible API (for example a
+// Gitea instance at https://gitea.example.com/api/v1).
+func Open(location string, repo string, cacheDir string) (Source, error) {
+	if location == "" {
+		return NewGitHubSource(repo, cacheDir), nil
+	}
+
+	if archive.IsURL(location) {
+		if strings.HasSuffix(strings.ToLower(location), ".json") {
+			return NewStaticSource(location), nil
+		}
+		src := NewGitHubSource(repo, cacheDir)
+		src.BaseURL = strings.TrimSuffix(location, "/")
+		return src, nil
+	}
+
+	location = strings.TrimPrefix(location, "file://")
+	if strings.HasPrefix(location, "~") {
+		home, _ := os.UserHomeDir()
+		location = filepath.Join(home, location[1:])
+	}
+
+	if !archive.FileExists(location) {
+		return nil, fmt.Errorf("release source not found: %s", location)
+	}
+
+	return NewStaticSource(location), nil
+}
+
+// CacheDir returns the directory caching the API responses of the release
+// source name under cacheDir. Caching is disabled when cacheDir is empty.
+func CacheDir(cacheDir string, name string) string {
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "releases", name)
+}
+
+// FindAsset returns the first asset whose name matches the predicate.
+func (r *Release) FindAsset(match func(name string) bool) *Asset {
+	for i := range r.Assets {
+		if match(r.Assets[i].Name) {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// FetchAsset downloads an asset to destPath.
+// Assets served from a local mirror are copied instead of downloaded.
+func FetchAsset(asset *Asset, destPath string, showProgress bool) error {
+	if archive.IsURL(asset.DownloadURL) {
+		return archive.Download(archive.DownloadOptions{
+			URL:          asset.DownloadURL,
+			DestPath:     destPath,
+			ShowProgress: showProgress,
+		})
+	}
+
+	return archive.CopyFile(strings.TrimPrefix(asset.DownloadURL, "file://"), destPath)
+}
@@ -0,0 +1,104 @@
+package release_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/release"
+)
+
+var _ = Describe("Release", func() {
+	Describe("CacheDir", func() {
+		It("should give each source its own directory", func() {
+			Expect(release.CacheDir("/cache", "patcher")).To(Equal(filepath.Join("/cache", "releases", "patcher")))
+			Expect(release.CacheDir("/cache", "ge-proton")).NotTo(Equal(release.CacheDir("/cache", "patcher")))
+		})
+
+		It("should disable caching without a cache directory", func() {
+			Expect(release.CacheDir("", "patcher")).To(BeEmpty())
+		})
+	})
+
+	Describe("Open", func() {
+		var tmpDir string
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "release-open-test-*")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		It("should default to the public GitHub API", func() {
+			src, err := release.Open("", "owner/repo", tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			gh, ok := src.(*release.GitHubSource)
+			Expect(ok).To(BeTrue())
+			Expect(gh.BaseURL).To(Equal(release.DefaultGitHubAPIURL))
+			Expect(gh.Repo).To(Equal("owner/repo"))
+		})
+
+		It("should use a custom API base URL", func() {
+			src, err := release.Open("https://gitea.example.com/api/v1/", "owner/repo", tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			gh, ok := src.(*release.GitHubSource)
+			Expect(ok).To(BeTrue())
+			Expect(gh.BaseURL).To(Equal("https://gitea.example.com/api/v1"))
+		})
+
+		It("should treat JSON URLs as static mirrors", func() {
+			src, err := release.Open("https://mirror.example.com/releases.json", "owner/repo", tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			_, ok := src.(*release.StaticSource)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should treat local directories as static mirrors", func() {
+			src, err := release.Open("file://"+tmpDir, "owner/repo", "")
+			Expect(err).NotTo(HaveOccurred())
+			static, ok := src.(*release.StaticSource)
+			Expect(ok).To(BeTrue())
+			Expect(static.Location).To(Equal(tmpDir))
+		})
+
+		It("should return error for missing local paths", func() {
+			_, err := release.Open(filepath.Join(tmpDir, "missing"), "owner/repo", "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("FindAsset", func() {
+		It("should return the first matching asset", func() {
+			rel := &release.Release{Assets: []release.Asset{{Name: "a.txt"}, {Name: "b.exe"}}}
+			asset := rel.FindAsset(func(name string) bool { return filepath.Ext(name) == ".exe" })
+			Expect(asset).NotTo(BeNil())
+			Expect(asset.Name).To(Equal("b.exe"))
+			Expect(rel.FindAsset(func(string) bool { return false })).To(BeNil())
+		})
+	})
+
+	DescribeTable("CompareTags",
+		func(a, b string, expected int) {
+			result := release.CompareTags(a, b)
+			switch {
+			case expected > 0:
+				Expect(result).To(BeNumerically(">", 0))
+			case expected < 0:
+				Expect(result).To(BeNumerically("<", 0))
+			default:
+				Expect(result).To(Equal(0))
+			}
+		},
+		Entry("numeric components", "v1.10.0", "v1.9.0", 1),
+		Entry("leading v ignored", "1.2.0", "v1.2.0", 0),
+		Entry("older", "v1.0.0", "v2.0.0", -1),
+		Entry("GE-Proton style", "GE-Proton10-3", "GE-Proton9-27", 1),
+		Entry("longer suffix", "v1.0.0.1", "v1.0.0", 1),
+	)
+})
@@ -0,0 +1,248 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jslay88/zladxhd-installer/internal/archive"
+)
+
+// StaticSource reads releases from a static mirror.
+//
+// The location may be a JSON file (local or remote) in the same shape as the
+// GitHub releases API, either a single release object or an array of them.
+// Relative asset URLs are resolved against the JSON file's location.
+//
+// The location may also be a local directory containing one subdirectory per
+// release tag, each holding that release's asset files. If the directory
+// contains a releases.json file, that file is used instead.
+type StaticSource struct {
+	Location string
+}
+
+// NewStaticSource creates a static release source.
+func NewStaticSource(location string) *StaticSource {
+	return &StaticSource{Location: location}
+}
+
+// Latest returns the first release listed by the mirror.
+func (s *StaticSource) Latest() (*Release, error) {
+	releases, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("no releases found in %s", s.Location)
+	}
+	return &releases[0], nil
+}
+
+// List returns all releases from the mirror.
+func (s *StaticSource) List() ([]Release, error) {
+	if !archive.IsURL(s.Location) {
+		info, err := os.Stat(s.Location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read release source: %w", err)
+		}
+		if info.IsDir() {
+			indexPath := filepath.Join(s.Location, "releases.json")
+			if archive.FileExists(indexPath) {
+				return readReleasesFile(indexPath)
+			}
+			return scanReleaseDir(s.Location)
+		}
+		return readReleasesFile(s.Location)
+	}
+
+	resp, err := http.Get(s.Location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release mirror returned status: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read releases: %w", err)
+	}
+
+	releases, err := parseReleases(data)
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(s.Location)
+	for i := range releases {
+		for j := range releases[i].Assets {
+			asset := &releases[i].Assets[j]
+			if ref, err := url.Parse(asset.DownloadURL); err == nil && base != nil {
+				asset.DownloadURL = base.ResolveReference(ref).String()
+			}
+		}
+	}
+
+	return releases, nil
+}
+
+// Get returns the release with the given tag.
+func (s *StaticSource) Get(tag string) (*Release, error) {
+	releases, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		if releases[i].TagName == tag {
+			return &releases[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release not found: %s", tag)
+}
+
+// readReleasesFile reads a local releases JSON file.
+// Relative asset paths are resolved against the file's directory.
+func readReleasesFile(file string) ([]Release, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read releases: %w", err)
+	}
+
+	releases, err := parseReleases(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(file)
+	for i := range releases {
+		for j := range releases[i].Assets {
+			asset := &releases[i].Assets[j]
+			if !archive.IsURL(asset.DownloadURL) && !filepath.IsAbs(asset.DownloadURL) {
+				asset.DownloadURL = filepath.Join(dir, asset.DownloadURL)
+			}
+		}
+	}
+
+	return releases, nil
+}
+
+// parseReleases decodes either a single release or an array of releases.
+func parseReleases(data []byte) ([]Release, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "{") {
+		var release Release
+		if err := json.Unmarshal(data, &release); err != nil {
+			return nil, fmt.Errorf("failed to parse release info: %w", err)
+		}
+		return []Release{release}, nil
+	}
+
+	var releases []Release
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse releases: %w", err)
+	}
+	return releases, nil
+}
+
+// scanReleaseDir builds releases from a directory with one subdirectory per tag.
+// Releases are ordered newest first by comparing their tags.
+func scanReleaseDir(dir string) ([]Release, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release directory: %w", err)
+	}
+
+	var releases []Release
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		tagDir := filepath.Join(dir, entry.Name())
+		files, err := os.ReadDir(tagDir)
+		if err != nil {
+			continue
+		}
+
+		release := Release{
+			TagName: entry.Name(),
+			Name:    entry.Name(),
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			info, err := f.Info()
+			if err != nil {
+				continue
+			}
+			release.Assets = append(release.Assets, Asset{
+				Name:        f.Name(),
+				DownloadURL: filepath.Join(tagDir, f.Name()),
+				Size:        info.Size(),
+			})
+		}
+		releases = append(releases, release)
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		return CompareTags(releases[i].TagName, releases[j].TagName) > 0
+	})
+
+	return releases, nil
+}
+
+// CompareTags compares two release tags, treating runs of digits as numbers.
+// Returns a positive value if a sorts after b, negative if before, 0 if equal.
+// A leading "v" is ignored, so "v1.10.0" sorts after "1.9.2".
+func CompareTags(a, b string) int {
+	a = strings.TrimPrefix(strings.ToLower(path.Base(a)), "v")
+	b = strings.TrimPrefix(strings.ToLower(path.Base(b)), "v")
+
+	for a != "" && b != "" {
+		aNum, aRest := splitDigits(a)
+		bNum, bRest := splitDigits(b)
+
+		if aNum != "" && bNum != "" {
+			an, _ := strconv.ParseUint(aNum, 10, 64)
+			bn, _ := strconv.ParseUint(bNum, 10, 64)
+			if an != bn {
+				if an > bn {
+					return 1
+				}
+				return -1
+			}
+			a, b = aRest, bRest
+			continue
+		}
+
+		if a[0] != b[0] {
+			if a[0] > b[0] {
+				return 1
+			}
+			return -1
+		}
+		a, b = a[1:], b[1:]
+	}
+
+	return len(a) - len(b)
+}
+
+// splitDigits splits a leading run of digits from s.
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i], s[i:]
+}
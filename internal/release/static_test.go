@@ -0,0 +1,117 @@
+package release_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/release"
+)
+
+var _ = Describe("StaticSource", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "release-static-test-*")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("JSON file", func() {
+		It("should read an array of releases and resolve relative assets", func() {
+			file := filepath.Join(tmpDir, "releases.json")
+			err := os.WriteFile(file, []byte(`[{"tag_name":"v2","assets":[{"name":"a.exe","browser_download_url":"v2/a.exe"}]},{"tag_name":"v1"}]`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			src := release.NewStaticSource(file)
+			rel, err := src.Latest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rel.TagName).To(Equal("v2"))
+			Expect(rel.Assets[0].DownloadURL).To(Equal(filepath.Join(tmpDir, "v2", "a.exe")))
+
+			rel, err = src.Get("v1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rel.TagName).To(Equal("v1"))
+		})
+
+		It("should read a single release object", func() {
+			file := filepath.Join(tmpDir, "latest.json")
+			err := os.WriteFile(file, []byte(`{"tag_name":"v5"}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			releases, err := release.NewStaticSource(file).List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(HaveLen(1))
+		})
+
+		It("should return error for unknown tag", func() {
+			file := filepath.Join(tmpDir, "releases.json")
+			err := os.WriteFile(file, []byte(`[]`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = release.NewStaticSource(file).Get("v1")
+			Expect(err).To(HaveOccurred())
+
+			_, err = release.NewStaticSource(file).Latest()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("remote JSON", func() {
+		It("should resolve asset URLs against the JSON location", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprint(w, `[{"tag_name":"v1","assets":[{"name":"a.exe","browser_download_url":"files/a.exe"}]}]`)
+			}))
+			defer server.Close()
+
+			rel, err := release.NewStaticSource(server.URL + "/mirror/releases.json").Latest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rel.Assets[0].DownloadURL).To(Equal(server.URL + "/mirror/files/a.exe"))
+		})
+	})
+
+	Describe("directory", func() {
+		It("should build releases from tag subdirectories, newest first", func() {
+			for _, tag := range []string{"v1.9.0", "v1.10.0", "v1.2.0"} {
+				err := os.MkdirAll(filepath.Join(tmpDir, tag), 0755)
+				Expect(err).NotTo(HaveOccurred())
+				err = os.WriteFile(filepath.Join(tmpDir, tag, "LADXHD.Patcher.exe"), []byte(tag), 0644)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			src := release.NewStaticSource(tmpDir)
+			releases, err := src.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(HaveLen(3))
+			Expect(releases[0].TagName).To(Equal("v1.10.0"))
+			Expect(releases[2].TagName).To(Equal("v1.2.0"))
+			Expect(releases[0].Assets[0].DownloadURL).To(Equal(filepath.Join(tmpDir, "v1.10.0", "LADXHD.Patcher.exe")))
+			Expect(releases[0].Assets[0].Size).To(Equal(int64(7)))
+		})
+	})
+
+	Describe("FetchAsset", func() {
+		It("should copy local assets", func() {
+			srcFile := filepath.Join(tmpDir, "a.exe")
+			err := os.WriteFile(srcFile, []byte("exe"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			dest := filepath.Join(tmpDir, "out", "a.exe")
+			err = release.FetchAsset(&release.Asset{Name: "a.exe", DownloadURL: srcFile}, dest, false)
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := os.ReadFile(dest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("exe"))
+		})
+	})
+})
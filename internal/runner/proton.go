@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// DefaultWinetricksCommand is the winetricks executable looked up in PATH.
+const DefaultWinetricksCommand = "winetricks"
+
+// Proton runs programs by invoking "<proton>/proton run" directly, the same
+// way Steam does. Verbs are installed with winetricks using Proton's Wine.
+type Proton struct {
+	Prefix Prefix
+	// Winetricks is the winetricks command used for verbs.
+	Winetricks string
+}
+
+// NewProton creates a direct Proton runner for prefix.
+func NewProton(prefix Prefix) *Proton {
+	return &Proton{
+		Prefix:     prefix,
+		Winetricks: DefaultWinetricksCommand,
+	}
+}
+
+// Name returns the backend name.
+func (p *Proton) Name() string {
+	return string(KindProton)
+}
+
+// LaunchInDir launches an executable with "proton run".
+func (p *Proton) LaunchInDir(exePath string, workDir string, opts LaunchOptions) error {
+	args := append([]string{"run", exePath}, opts.Args...)
+
+	cmd := exec.Command(filepath.Join(p.Prefix.ProtonPath, "proton"), args...)
+	cmd.Env = append(os.Environ(), p.steamEnv()...)
+	cmd.Dir = workDir
+
+	if err := run(cmd, opts.SuppressOutput); err != nil {
+		return fmt.Errorf("failed to launch %s: %w", exePath, err)
+	}
+	return nil
+}
+
+// InstallVerb installs a winetricks verb using Proton's Wine build.
+func (p *Proton) InstallVerb(verb string, opts VerbOptions) error {
+	if verbSatisfied(p.Prefix, verb, opts) {
+		return nil
+	}
+
+	wine, err := WineBinary(p.Prefix.ProtonPath)
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	if opts.Quiet {
+		args = append(args, "-q")
+	}
+	args = append(args, verb)
+
+	cmd := exec.Command(p.Winetricks, args...)
+	cmd.Env = append(os.Environ(), p.steamEnv()...)
+	cmd.Env = append(cmd.Env,
+		fmt.Sprintf("WINEPREFIX=%s", p.Prefix.WinePrefix()),
+		fmt.Sprintf("WINE=%s", wine),
+		fmt.Sprintf("WINESERVER=%s", filepath.Join(filepath.Dir(wine), "wineserver")),
+	)
+
+	if err := run(cmd, opts.SuppressOutput); err != nil {
+		return fmt.Errorf("failed to install %s: %w", verb, err)
+	}
+	return nil
+}
+
+// WaitForWineserver runs Proton's "wineserver -w" for the prefix.
+func (p *Proton) WaitForWineserver() error {
+	return waitForWineserver(p.Prefix.ProtonPath, p.Prefix.WinePrefix())
+}
+
+// waitForWineserver runs "wineserver -w" of the Proton at protonPath for
+// winePrefix, which returns once the prefix's wineserver has exited.
+func waitForWineserver(protonPath string, winePrefix string) error {
+	wine, err := WineBinary(protonPath)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(filepath.Join(filepath.Dir(wine), "wineserver"), "-w")
+	cmd.Env = append(os.Environ(), fmt.Sprintf("WINEPREFIX=%s", winePrefix))
+	if err := run(cmd, true); err != nil {
+		return fmt.Errorf("failed to wait for wineserver: %w", err)
+	}
+	return nil
+}
+
+// steamEnv returns the environment Proton expects from Steam.
+func (p *Proton) steamEnv() []string {
+	env := []string{
+		fmt.Sprintf("STEAM_COMPAT_CLIENT_INSTALL_PATH=%s", p.Prefix.SteamPath),
+		fmt.Sprintf("STEAM_COMPAT_DATA_PATH=%s", p.Prefix.CompatDataPath),
+	}
+	if p.Prefix.AppID != 0 {
+		env = append(env,
+			fmt.Sprintf("SteamAppId=%d", p.Prefix.AppID),
+			fmt.Sprintf("SteamGameId=%d", p.Prefix.AppID),
+		)
+	}
+	return env
+}
+
+// WineBinary returns the wine executable shipped with a Proton version.
+// Proton 5.13 and newer use files/, older versions use dist/.
+func WineBinary(protonPath string) (string, error) {
+	for _, dir := range []string{"files", "dist"} {
+		path := filepath.Join(protonPath, dir, "bin", "wine")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("wine not found in %s", protonPath)
+}
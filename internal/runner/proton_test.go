@@ -0,0 +1,129 @@
+package runner_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/runner"
+)
+
+var _ = Describe("Proton", func() {
+	var tmpDir string
+	var prefix runner.Prefix
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "runner-proton-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		prefix = runner.Prefix{
+			AppID:          12345,
+			SteamPath:      filepath.Join(tmpDir, "steam"),
+			CompatDataPath: filepath.Join(tmpDir, "compatdata", "12345"),
+			ProtonPath:     filepath.Join(tmpDir, "proton"),
+		}
+		writeRecorder(filepath.Join(prefix.ProtonPath, "proton"))
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("LaunchInDir", func() {
+		It("should invoke proton run with the Steam compat environment", func() {
+			workDir := filepath.Join(tmpDir, "game")
+			Expect(os.MkdirAll(workDir, 0755)).To(Succeed())
+
+			r := runner.NewProton(prefix)
+			err := r.LaunchInDir("/game/Patcher.exe", workDir, runner.LaunchOptions{
+				SuppressOutput: true,
+				Args:           []string{"--silent"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			log := readRecorder(filepath.Join(prefix.ProtonPath, "proton"))
+			Expect(log).To(ContainSubstring("args:run /game/Patcher.exe --silent"))
+			Expect(log).To(ContainSubstring("pwd:" + workDir))
+			Expect(log).To(ContainSubstring("STEAM_COMPAT_CLIENT_INSTALL_PATH=" + prefix.SteamPath))
+			Expect(log).To(ContainSubstring("STEAM_COMPAT_DATA_PATH=" + prefix.CompatDataPath))
+			Expect(log).To(ContainSubstring("SteamAppId=12345"))
+		})
+	})
+
+	Describe("InstallVerb", func() {
+		It("should run winetricks with Proton's Wine", func() {
+			wine := filepath.Join(prefix.ProtonPath, "files", "bin", "wine")
+			writeRecorder(wine)
+			winetricks := filepath.Join(tmpDir, "bin", "winetricks")
+			writeRecorder(winetricks)
+
+			r := runner.NewProton(prefix)
+			r.Winetricks = winetricks
+			Expect(r.InstallVerb("corefonts", runner.VerbOptions{Quiet: true, SuppressOutput: true})).To(Succeed())
+
+			log := readRecorder(winetricks)
+			Expect(log).To(ContainSubstring("args:-q corefonts"))
+			Expect(log).To(ContainSubstring("WINEPREFIX=" + prefix.WinePrefix()))
+			Expect(log).To(ContainSubstring("WINE=" + wine))
+			Expect(log).To(ContainSubstring("WINESERVER=" + filepath.Join(filepath.Dir(wine), "wineserver")))
+		})
+
+		It("should skip verbs already in winetricks.log", func() {
+			winetricks := filepath.Join(tmpDir, "bin", "winetricks")
+			writeRecorder(winetricks)
+			Expect(os.MkdirAll(prefix.WinePrefix(), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(prefix.WinePrefix(), "winetricks.log"), []byte("corefonts\n"), 0644)).To(Succeed())
+
+			r := runner.NewProton(prefix)
+			r.Winetricks = winetricks
+			Expect(r.InstallVerb("corefonts", runner.VerbOptions{})).To(Succeed())
+			Expect(winetricks + ".log").NotTo(BeAnExistingFile())
+		})
+
+		It("should reinstall satisfied verbs when forced", func() {
+			writeRecorder(filepath.Join(prefix.ProtonPath, "files", "bin", "wine"))
+			winetricks := filepath.Join(tmpDir, "bin", "winetricks")
+			writeRecorder(winetricks)
+			Expect(os.MkdirAll(prefix.WinePrefix(), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(prefix.WinePrefix(), "winetricks.log"), []byte("corefonts\n"), 0644)).To(Succeed())
+
+			r := runner.NewProton(prefix)
+			r.Winetricks = winetricks
+			Expect(r.InstallVerb("corefonts", runner.VerbOptions{Force: true, SuppressOutput: true})).To(Succeed())
+			Expect(readRecorder(winetricks)).To(ContainSubstring("args:corefonts"))
+		})
+
+		It("should fail when Proton ships no Wine", func() {
+			r := runner.NewProton(prefix)
+			Expect(r.InstallVerb("corefonts", runner.VerbOptions{})).To(MatchError(ContainSubstring("wine not found")))
+		})
+	})
+
+	Describe("WaitForWineserver", func() {
+		It("should run Proton's wineserver -w for the prefix", func() {
+			writeRecorder(filepath.Join(prefix.ProtonPath, "files", "bin", "wine"))
+			wineserver := filepath.Join(prefix.ProtonPath, "files", "bin", "wineserver")
+			writeRecorder(wineserver)
+
+			Expect(runner.NewProton(prefix).WaitForWineserver()).To(Succeed())
+
+			log := readRecorder(wineserver)
+			Expect(log).To(ContainSubstring("args:-w"))
+			Expect(log).To(ContainSubstring("WINEPREFIX=" + prefix.WinePrefix()))
+		})
+	})
+
+	Describe("WineBinary", func() {
+		It("should find Wine in dist for older Proton versions", func() {
+			wine := filepath.Join(prefix.ProtonPath, "dist", "bin", "wine")
+			writeRecorder(wine)
+
+			path, err := runner.WineBinary(prefix.ProtonPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(wine))
+		})
+	})
+})
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/jslay88/zladxhd-installer/internal/protontricks"
+)
+
+// Protontricks runs programs through protontricks and protontricks-launch.
+type Protontricks struct {
+	Runner *protontricks.Runner
+	Prefix Prefix
+}
+
+// NewProtontricks creates a protontricks-backed runner for the prefix's AppID.
+func NewProtontricks(r *protontricks.Runner, prefix Prefix) *Protontricks {
+	return &Protontricks{Runner: r, Prefix: prefix}
+}
+
+// Name returns the backend name.
+func (p *Protontricks) Name() string {
+	return string(KindProtontricks)
+}
+
+// LaunchInDir launches an executable with protontricks-launch.
+func (p *Protontricks) LaunchInDir(exePath string, workDir string, opts LaunchOptions) error {
+	return p.Runner.LaunchInDir(p.Prefix.AppID, exePath, workDir, protontricks.LaunchOptions{
+		SuppressOutput: opts.SuppressOutput,
+		Args:           opts.Args,
+	})
+}
+
+// WaitForWineserver runs "wineserver -w" in the prefix with protontricks -c.
+func (p *Protontricks) WaitForWineserver() error {
+	if p.Prefix.AppID == 0 {
+		return fmt.Errorf("no AppID set for protontricks")
+	}
+	return p.Runner.WaitForWineserver(p.Prefix.AppID)
+}
+
+// InstallVerb installs a winetricks verb with protontricks.
+func (p *Protontricks) InstallVerb(verb string, opts VerbOptions) error {
+	if p.Prefix.AppID == 0 {
+		return fmt.Errorf("no AppID set for protontricks")
+	}
+	if verbSatisfied(p.Prefix, verb, opts) {
+		return nil
+	}
+	return p.Runner.InstallVerb(p.Prefix.AppID, verb, protontricks.InstallVerbOptions{
+		Quiet:          opts.Quiet,
+		SuppressOutput: opts.SuppressOutput,
+	})
+}
@@ -0,0 +1,157 @@
+// Package runner runs Windows executables and winetricks verbs in a game's
+// Wine prefix using protontricks, Proton directly, or umu-launcher.
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
+	"github.com/jslay88/zladxhd-installer/internal/protontricks"
+)
+
+// Kind identifies a runner backend.
+type Kind string
+
+const (
+	KindAuto         Kind = "auto"
+	KindProtontricks Kind = "protontricks"
+	KindProton       Kind = "proton"
+	KindUmu          Kind = "umu"
+)
+
+// Runner runs programs inside a game's Wine prefix.
+type Runner interface {
+	// Name returns a human-readable name of the backend.
+	Name() string
+	// LaunchInDir launches a Windows executable with workDir as the working directory.
+	LaunchInDir(exePath string, workDir string, opts LaunchOptions) error
+	// InstallVerb installs a winetricks verb into the prefix.
+	// Verbs that are already satisfied are skipped unless opts.Force is set.
+	InstallVerb(verb string, opts VerbOptions) error
+	// WaitForWineserver waits until the prefix's wineserver has exited and
+	// flushed its registry to disk, so the .reg files can be edited.
+	WaitForWineserver() error
+}
+
+// LaunchOptions configures executable launch.
+type LaunchOptions struct {
+	SuppressOutput bool     // Suppress stdout/stderr (Wine debug output)
+	Args           []string // Additional arguments to pass to the executable
+}
+
+// VerbOptions configures verb installation.
+type VerbOptions struct {
+	Quiet          bool // Pass -q to winetricks
+	SuppressOutput bool // Suppress stdout/stderr (for cleaner CLI output)
+	Force          bool // Reinstall even if the verb is already satisfied
+}
+
+// Prefix identifies the Wine prefix a runner operates on.
+type Prefix struct {
+	// AppID is the Steam AppID owning the prefix.
+	AppID uint32
+	// SteamPath is the Steam installation directory.
+	SteamPath string
+	// CompatDataPath is the app's compatdata directory (the prefix is its pfx subdirectory).
+	CompatDataPath string
+	// ProtonPath is the directory of the Proton version to run with.
+	ProtonPath string
+}
+
+// WinePrefix returns the path of the Wine prefix.
+func (p Prefix) WinePrefix() string {
+	return filepath.Join(p.CompatDataPath, "pfx")
+}
+
+// ParseKind parses a backend name from the command line.
+func ParseKind(s string) (Kind, error) {
+	switch Kind(s) {
+	case KindAuto, KindProtontricks, KindProton, KindUmu:
+		return Kind(s), nil
+	default:
+		return "", fmt.Errorf("unknown runner %q: must be auto, protontricks, proton, or umu", s)
+	}
+}
+
+// Detect picks the backend to use when none was requested.
+// protontricks is preferred when installed, then umu-launcher, then running
+// Proton directly if winetricks is available for verbs. If none of them are
+// usable, protontricks is returned so it can be installed.
+func Detect() Kind {
+	if _, err := protontricks.Detect(); err == nil {
+		return KindProtontricks
+	}
+	if _, err := exec.LookPath(DefaultUmuCommand); err == nil {
+		return KindUmu
+	}
+	if _, err := exec.LookPath(DefaultWinetricksCommand); err == nil {
+		return KindProton
+	}
+	return KindProtontricks
+}
+
+// New creates a runner of the given kind for prefix.
+// ptInstall is only used by the protontricks backend.
+func New(kind Kind, prefix Prefix, ptInstall *protontricks.Installation) (Runner, error) {
+	switch kind {
+	case KindProtontricks:
+		if ptInstall == nil {
+			return nil, fmt.Errorf("protontricks is not installed")
+		}
+		return NewProtontricks(protontricks.NewRunner(ptInstall), prefix), nil
+	case KindProton:
+		if prefix.ProtonPath == "" {
+			return nil, fmt.Errorf("no Proton version configured")
+		}
+		return NewProton(prefix), nil
+	case KindUmu:
+		return NewUmu(prefix), nil
+	default:
+		return nil, fmt.Errorf("unknown runner %q", kind)
+	}
+}
+
+// InstallDotNetDesktop6 installs the .NET Desktop Runtime 6 into the prefix.
+func InstallDotNetDesktop6(r Runner, suppressOutput bool, force bool) error {
+	return r.InstallVerb("dotnetdesktop6", VerbOptions{
+		Quiet:          true,
+		SuppressOutput: suppressOutput,
+		Force:          force,
+	})
+}
+
+// verbSatisfied checks if verb can be skipped for the prefix.
+func verbSatisfied(prefix Prefix, verb string, opts VerbOptions) bool {
+	if opts.Force || prefix.CompatDataPath == "" {
+		return false
+	}
+	return proton.VerbInstalled(prefix.WinePrefix(), verb)
+}
+
+// run executes cmd, capturing its output when suppressOutput is set and
+// dumping it to stderr if the command fails.
+func run(cmd *exec.Cmd, suppressOutput bool) error {
+	var outputBuf bytes.Buffer
+	if suppressOutput {
+		// Capture output to buffer - dump on error
+		cmd.Stdout = &outputBuf
+		cmd.Stderr = &outputBuf
+	} else {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		if suppressOutput && outputBuf.Len() > 0 {
+			fmt.Fprintf(os.Stderr, "\n--- Command output (on error) ---\n%s\n--- End output ---\n", outputBuf.String())
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package runner_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/protontricks"
+	"github.com/jslay88/zladxhd-installer/internal/runner"
+)
+
+// writeRecorder writes a shell script that records its arguments, working
+// directory and environment to <path>.log.
+func writeRecorder(path string) {
+	script := "#!/bin/sh\n" +
+		"{ echo \"args:$*\"; echo \"pwd:$(pwd)\"; env; } > \"" + path + ".log\"\n"
+	Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+	Expect(os.WriteFile(path, []byte(script), 0755)).To(Succeed())
+}
+
+// readRecorder returns the log written by a recorder script.
+func readRecorder(path string) string {
+	data, err := os.ReadFile(path + ".log")
+	Expect(err).NotTo(HaveOccurred())
+	return string(data)
+}
+
+var _ = Describe("Runner", func() {
+	Describe("ParseKind", func() {
+		It("should accept every backend", func() {
+			for _, name := range []string{"auto", "protontricks", "proton", "umu"} {
+				kind, err := runner.ParseKind(name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(kind)).To(Equal(name))
+			}
+		})
+
+		It("should reject unknown backends", func() {
+			_, err := runner.ParseKind("wine")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("New", func() {
+		prefix := runner.Prefix{
+			AppID:          12345,
+			SteamPath:      "/steam",
+			CompatDataPath: "/steam/steamapps/compatdata/12345",
+			ProtonPath:     "/steam/steamapps/common/Proton 9.0",
+		}
+
+		It("should create each backend", func() {
+			r, err := runner.New(runner.KindProtontricks, prefix, &protontricks.Installation{Method: protontricks.InstallNative})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.Name()).To(Equal("protontricks"))
+
+			r, err = runner.New(runner.KindProton, prefix, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.Name()).To(Equal("proton"))
+
+			r, err = runner.New(runner.KindUmu, prefix, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.Name()).To(Equal("umu"))
+		})
+
+		It("should require protontricks for the protontricks backend", func() {
+			_, err := runner.New(runner.KindProtontricks, prefix, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should require a Proton path for the proton backend", func() {
+			_, err := runner.New(runner.KindProton, runner.Prefix{AppID: 1}, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should not create an unresolved auto backend", func() {
+			_, err := runner.New(runner.KindAuto, prefix, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Prefix", func() {
+		It("should place the Wine prefix in pfx", func() {
+			prefix := runner.Prefix{CompatDataPath: "/compat/123"}
+			Expect(prefix.WinePrefix()).To(Equal("/compat/123/pfx"))
+		})
+	})
+})
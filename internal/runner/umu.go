@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// DefaultUmuCommand is the umu-launcher executable looked up in PATH.
+const DefaultUmuCommand = "umu-run"
+
+// Umu runs programs with umu-launcher, which provides the Steam Runtime
+// container Proton normally runs in.
+type Umu struct {
+	Prefix Prefix
+	// Command is the umu-run command.
+	Command string
+	// GameID is passed as GAMEID; "0" applies no protonfixes.
+	GameID string
+}
+
+// NewUmu creates a umu-launcher runner for prefix.
+func NewUmu(prefix Prefix) *Umu {
+	return &Umu{
+		Prefix:  prefix,
+		Command: DefaultUmuCommand,
+		GameID:  "0",
+	}
+}
+
+// Name returns the backend name.
+func (u *Umu) Name() string {
+	return string(KindUmu)
+}
+
+// LaunchInDir launches an executable with umu-run.
+func (u *Umu) LaunchInDir(exePath string, workDir string, opts LaunchOptions) error {
+	args := append([]string{exePath}, opts.Args...)
+
+	cmd := exec.Command(u.Command, args...)
+	cmd.Env = append(os.Environ(), u.env()...)
+	cmd.Dir = workDir
+
+	if err := run(cmd, opts.SuppressOutput); err != nil {
+		return fmt.Errorf("failed to launch %s: %w", exePath, err)
+	}
+	return nil
+}
+
+// InstallVerb installs a winetricks verb with "umu-run winetricks".
+func (u *Umu) InstallVerb(verb string, opts VerbOptions) error {
+	if verbSatisfied(u.Prefix, verb, opts) {
+		return nil
+	}
+
+	args := []string{"winetricks"}
+	if opts.Quiet {
+		args = append(args, "-q")
+	}
+	args = append(args, verb)
+
+	cmd := exec.Command(u.Command, args...)
+	cmd.Env = append(os.Environ(), u.env()...)
+
+	if err := run(cmd, opts.SuppressOutput); err != nil {
+		return fmt.Errorf("failed to install %s: %w", verb, err)
+	}
+	return nil
+}
+
+// WaitForWineserver runs the Proton's "wineserver -w" for the prefix.
+// umu-run has no way to run it, so a Proton path is required.
+func (u *Umu) WaitForWineserver() error {
+	if u.Prefix.ProtonPath == "" {
+		return fmt.Errorf("no Proton version configured to wait for its wineserver")
+	}
+	return waitForWineserver(u.Prefix.ProtonPath, u.Prefix.WinePrefix())
+}
+
+// env returns the umu-launcher environment.
+// umu passes WINEPREFIX to Proton as STEAM_COMPAT_DATA_PATH, so it points at
+// the compatdata directory to share the pfx Steam uses for the shortcut.
+func (u *Umu) env() []string {
+	env := []string{
+		fmt.Sprintf("WINEPREFIX=%s", u.Prefix.CompatDataPath),
+		fmt.Sprintf("GAMEID=%s", u.GameID),
+		fmt.Sprintf("STEAM_COMPAT_CLIENT_INSTALL_PATH=%s", u.Prefix.SteamPath),
+	}
+	if u.Prefix.ProtonPath != "" {
+		env = append(env, fmt.Sprintf("PROTONPATH=%s", u.Prefix.ProtonPath))
+	}
+	return env
+}
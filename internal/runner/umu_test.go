@@ -0,0 +1,81 @@
+package runner_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/runner"
+)
+
+var _ = Describe("Umu", func() {
+	var tmpDir string
+	var umuRun string
+	var prefix runner.Prefix
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "runner-umu-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		umuRun = filepath.Join(tmpDir, "bin", "umu-run")
+		writeRecorder(umuRun)
+
+		prefix = runner.Prefix{
+			AppID:          12345,
+			SteamPath:      filepath.Join(tmpDir, "steam"),
+			CompatDataPath: filepath.Join(tmpDir, "compatdata", "12345"),
+			ProtonPath:     filepath.Join(tmpDir, "proton"),
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("should launch executables with umu-run", func() {
+		r := runner.NewUmu(prefix)
+		r.Command = umuRun
+
+		err := r.LaunchInDir("/game/Patcher.exe", tmpDir, runner.LaunchOptions{
+			SuppressOutput: true,
+			Args:           []string{"--silent"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		log := readRecorder(umuRun)
+		Expect(log).To(ContainSubstring("args:/game/Patcher.exe --silent"))
+		Expect(log).To(ContainSubstring("WINEPREFIX=" + prefix.CompatDataPath))
+		Expect(log).To(ContainSubstring("PROTONPATH=" + prefix.ProtonPath))
+		Expect(log).To(ContainSubstring("GAMEID=0"))
+	})
+
+	It("should install verbs with umu-run winetricks", func() {
+		r := runner.NewUmu(prefix)
+		r.Command = umuRun
+
+		Expect(r.InstallVerb("dotnetdesktop6", runner.VerbOptions{Quiet: true, SuppressOutput: true})).To(Succeed())
+		Expect(readRecorder(umuRun)).To(ContainSubstring("args:winetricks -q dotnetdesktop6"))
+	})
+
+	It("should wait for the wineserver of the Proton umu runs", func() {
+		writeRecorder(filepath.Join(prefix.ProtonPath, "files", "bin", "wine"))
+		wineserver := filepath.Join(prefix.ProtonPath, "files", "bin", "wineserver")
+		writeRecorder(wineserver)
+
+		Expect(runner.NewUmu(prefix).WaitForWineserver()).To(Succeed())
+
+		log := readRecorder(wineserver)
+		Expect(log).To(ContainSubstring("args:-w"))
+		Expect(log).To(ContainSubstring("WINEPREFIX=" + prefix.WinePrefix()))
+	})
+
+	It("should report command failures", func() {
+		r := runner.NewUmu(prefix)
+		r.Command = filepath.Join(tmpDir, "missing")
+
+		Expect(r.InstallVerb("corefonts", runner.VerbOptions{})).To(HaveOccurred())
+	})
+})
@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/jslay88/zladxhd-installer/internal/proton"
 )
 
 const (
@@ -15,6 +17,10 @@ const (
 	configFile   = "config.json"
 	stateFile    = "state.json"
 	cacheDirName = "cache"
+	snapshotsDir = "snapshots"
+	prefixesDir  = "prefix-snapshots"
+	backupsDir   = "steam-backups"
+	tarballsDir  = "backups"
 	archiveFile  = "ZLADXHD.zip"
 )
 
@@ -23,6 +29,19 @@ type Config struct {
 	LastInstallDir string `json:"last_install_dir,omitempty"`
 	LastProton     string `json:"last_proton,omitempty"`
 	LastSteamUser  string `json:"last_steam_user,omitempty"`
+	LastSteamPath  string `json:"last_steam_path,omitempty"`
+	LastAppID      uint32 `json:"last_app_id,omitempty"`
+	// BackupDir is where full Steam backups are written, instead of
+	// Manager.BackupDir().
+	BackupDir string `json:"backup_dir,omitempty"`
+	// BackupKeep is how many backups of each kind are kept; 0 uses the
+	// default and a negative number keeps all of them.
+	BackupKeep int `json:"backup_keep,omitempty"`
+	// BackupMaxAge removes backups older than this, e.g. "30d" or "72h".
+	BackupMaxAge string `json:"backup_max_age,omitempty"`
+	// Prefix holds user additions to the game's prefix recipe, e.g. DLL
+	// overrides or env vars working around a GPU driver issue.
+	Prefix *proton.Recipe `json:"prefix,omitempty"`
 }
 
 // InstallState tracks the installation progress for resume/repair.
@@ -33,6 +52,11 @@ type InstallState struct {
 	InstallDir      string     `json:"install_dir,omitempty"`
 	SteamUserID     string     `json:"steam_user_id,omitempty"`
 	AppID           uint32     `json:"app_id,omitempty"`
+	GameVersion     string     `json:"game_version,omitempty"`
+	PatcherVersion  string     `json:"patcher_version,omitempty"`
+	PatchedAt       *time.Time `json:"patched_at,omitempty"`
+	ProtonName      string     `json:"proton_name,omitempty"`
+	ProtonVersion   string     `json:"proton_version,omitempty"`
 	Steps           []Step     `json:"steps"`
 }
 
@@ -121,6 +145,27 @@ func (m *Manager) CacheDir() string {
 	return m.cacheDir
 }
 
+// SnapshotDir returns the directory where game snapshots are stored.
+func (m *Manager) SnapshotDir() string {
+	return filepath.Join(m.baseDir, snapshotsDir)
+}
+
+// PrefixSnapshotDir returns the directory where Wine prefix snapshots are stored.
+func (m *Manager) PrefixSnapshotDir() string {
+	return filepath.Join(m.baseDir, prefixesDir)
+}
+
+// BackupDir returns the default directory for full Steam backups.
+func (m *Manager) BackupDir() string {
+	return filepath.Join(m.baseDir, tarballsDir)
+}
+
+// SteamBackupDir returns the directory where minimal backups of the Steam
+// files the installer modifies are stored.
+func (m *Manager) SteamBackupDir() string {
+	return filepath.Join(m.baseDir, backupsDir)
+}
+
 // CachedArchivePath returns the path to the cached game archive.
 func (m *Manager) CachedArchivePath() string {
 	return filepath.Join(m.cacheDir, archiveFile)
@@ -202,6 +247,14 @@ func (m *Manager) NewInstallState() *InstallState {
 	return m.state
 }
 
+// EnsureState returns the current installation state, creating it if needed.
+func (m *Manager) EnsureState() *InstallState {
+	if m.state == nil {
+		return m.NewInstallState()
+	}
+	return m.state
+}
+
 // ClearState removes the installation state.
 func (m *Manager) ClearState() error {
 	m.state = nil
@@ -81,6 +81,26 @@ var _ = Describe("Manager", func() {
 			Expect(cfg.LastProton).To(Equal("Proton 8.0"))
 			Expect(cfg.LastSteamUser).To(Equal("12345"))
 		})
+
+		It("should load prefix overrides written by the user", func() {
+			baseDir := filepath.Join(tmpDir, "zladxhd-installer")
+			Expect(os.MkdirAll(baseDir, 0755)).To(Succeed())
+			err := os.WriteFile(filepath.Join(baseDir, "config.json"), []byte(`{
+  "prefix": {
+    "dll_overrides": {"dxgi": "builtin"},
+    "env": {"PROTON_USE_WINED3D": "1"}
+  }
+}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			mgr, err := state.NewManager()
+			Expect(err).NotTo(HaveOccurred())
+
+			prefix := mgr.Config().Prefix
+			Expect(prefix).NotTo(BeNil())
+			Expect(prefix.DLLOverrides).To(HaveKeyWithValue("dxgi", "builtin"))
+			Expect(prefix.Env).To(HaveKeyWithValue("PROTON_USE_WINED3D", "1"))
+		})
 	})
 
 	Describe("CachedArchivePath", func() {
@@ -102,6 +122,36 @@ var _ = Describe("Manager", func() {
 			Expect(mgr.CacheDir()).To(Equal(expectedDir))
 		})
 	})
+
+	Describe("PrefixSnapshotDir", func() {
+		It("should return the prefix snapshot directory path", func() {
+			mgr, err := state.NewManager()
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedDir := filepath.Join(tmpDir, "zladxhd-installer", "prefix-snapshots")
+			Expect(mgr.PrefixSnapshotDir()).To(Equal(expectedDir))
+		})
+	})
+
+	Describe("BackupDir", func() {
+		It("should return the full backup directory path", func() {
+			mgr, err := state.NewManager()
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedDir := filepath.Join(tmpDir, "zladxhd-installer", "backups")
+			Expect(mgr.BackupDir()).To(Equal(expectedDir))
+		})
+	})
+
+	Describe("SteamBackupDir", func() {
+		It("should return the Steam file backup directory path", func() {
+			mgr, err := state.NewManager()
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedDir := filepath.Join(tmpDir, "zladxhd-installer", "steam-backups")
+			Expect(mgr.SteamBackupDir()).To(Equal(expectedDir))
+		})
+	})
 })
 
 var _ = Describe("InstallState", func() {
@@ -174,6 +224,27 @@ var _ = Describe("InstallState", func() {
 		})
 	})
 
+	Describe("EnsureState", func() {
+		It("should create state when none exists", func() {
+			mgr, err := state.NewManager()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mgr.State()).To(BeNil())
+
+			installState := mgr.EnsureState()
+			Expect(installState).NotTo(BeNil())
+			Expect(mgr.State()).To(Equal(installState))
+		})
+
+		It("should return existing state", func() {
+			mgr, err := state.NewManager()
+			Expect(err).NotTo(HaveOccurred())
+
+			existing := mgr.NewInstallState()
+			existing.GameVersion = "1.1.0"
+			Expect(mgr.EnsureState()).To(BeIdenticalTo(existing))
+		})
+	})
+
 	Describe("ClearState", func() {
 		It("should remove state file and clear memory", func() {
 			mgr, err := state.NewManager()
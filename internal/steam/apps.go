@@ -0,0 +1,84 @@
+package steam
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jslay88/vdf"
+)
+
+// AppManifest holds the fields of an appmanifest_<appid>.acf.
+type AppManifest struct {
+	AppID      uint32
+	Name       string
+	InstallDir string
+	Path       string
+}
+
+// ParseAppManifest parses an appmanifest_<appid>.acf file.
+func ParseAppManifest(path string) (*AppManifest, error) {
+	doc, err := vdf.ParseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	appState := doc.Get("AppState")
+	if appState == nil {
+		return nil, fmt.Errorf("%s has no AppState section", path)
+	}
+	appID, err := strconv.ParseUint(appState.GetString("appid"), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%s has an invalid appid: %w", path, err)
+	}
+
+	return &AppManifest{
+		AppID:      uint32(appID),
+		Name:       appState.GetString("name"),
+		InstallDir: appState.GetString("installdir"),
+		Path:       path,
+	}, nil
+}
+
+// GetAppManifests returns the manifests of the apps installed in all mounted
+// libraries. Unparsable manifests are skipped.
+func (s *Steam) GetAppManifests() ([]AppManifest, error) {
+	var manifests []AppManifest
+	for _, appsPath := range s.appsPaths() {
+		entries, err := os.ReadDir(appsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read steamapps directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasPrefix(name, "appmanifest_") || !strings.HasSuffix(name, ".acf") {
+				continue
+			}
+			manifest, err := ParseAppManifest(filepath.Join(appsPath, name))
+			if err != nil {
+				continue
+			}
+			manifests = append(manifests, *manifest)
+		}
+	}
+	return manifests, nil
+}
+
+// FindAppByInstallDir finds the app installed in a library's
+// steamapps/common/<installDir>.
+// Returns nil if no manifest matches.
+func (s *Steam) FindAppByInstallDir(installDir string) (*AppManifest, error) {
+	manifests, err := s.GetAppManifests()
+	if err != nil {
+		return nil, err
+	}
+	for i := range manifests {
+		if manifests[i].InstallDir == installDir {
+			return &manifests[i], nil
+		}
+	}
+	return nil, nil
+}
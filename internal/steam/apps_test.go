@@ -0,0 +1,78 @@
+package steam_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("Apps", func() {
+	var tmpDir string
+	var mockSteam *steam.Steam
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "apps-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		mockSteam = &steam.Steam{AppsPath: filepath.Join(tmpDir, "steamapps")}
+		Expect(os.MkdirAll(mockSteam.AppsPath, 0755)).To(Succeed())
+
+		manifest := `"AppState"
+{
+	"appid"		"2805730"
+	"Universe"		"1"
+	"name"		"Proton 9.0"
+	"installdir"		"Proton 9.0 (Beta)"
+}
+`
+		Expect(os.WriteFile(filepath.Join(mockSteam.AppsPath, "appmanifest_2805730.acf"), []byte(manifest), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(mockSteam.AppsPath, "appmanifest_1.acf"), []byte(`"AppState" {`), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("ParseAppManifest", func() {
+		It("should parse the AppID, name and install dir", func() {
+			manifest, err := steam.ParseAppManifest(filepath.Join(mockSteam.AppsPath, "appmanifest_2805730.acf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest.AppID).To(Equal(uint32(2805730)))
+			Expect(manifest.Name).To(Equal("Proton 9.0"))
+			Expect(manifest.InstallDir).To(Equal("Proton 9.0 (Beta)"))
+		})
+
+		It("should fail for a broken manifest", func() {
+			_, err := steam.ParseAppManifest(filepath.Join(mockSteam.AppsPath, "appmanifest_1.acf"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetAppManifests", func() {
+		It("should skip broken manifests", func() {
+			manifests, err := mockSteam.GetAppManifests()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifests).To(HaveLen(1))
+		})
+	})
+
+	Describe("FindAppByInstallDir", func() {
+		It("should find the app by install dir", func() {
+			manifest, err := mockSteam.FindAppByInstallDir("Proton 9.0 (Beta)")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest).NotTo(BeNil())
+			Expect(manifest.AppID).To(Equal(uint32(2805730)))
+		})
+
+		It("should return nil when no app matches", func() {
+			manifest, err := mockSteam.FindAppByInstallDir("Proton 8.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,247 @@
+package steam
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Binary VDF value types, as used in shortcuts.vdf.
+const (
+	binaryMap     byte = 0x00
+	binaryString  byte = 0x01
+	binaryInt32   byte = 0x02
+	binaryFloat32 byte = 0x03
+	binaryPointer byte = 0x04
+	binaryWString byte = 0x05
+	binaryColor   byte = 0x06
+	binaryUint64  byte = 0x07
+	binaryEnd     byte = 0x08
+	binaryInt64   byte = 0x0A
+	binaryEndAlt  byte = 0x0B
+)
+
+// binaryNode is an entry of a binary VDF file. Entries keep their order and
+// values keep their raw bytes, so that keys and types this package doesn't
+// interpret are written back exactly as they were read.
+type binaryNode struct {
+	typ      byte
+	key      string
+	value    []byte        // raw value; strings without their terminator
+	children []*binaryNode // entries of a map
+	end      byte          // terminator of a map
+}
+
+// newBinaryMap returns an empty map node.
+func newBinaryMap(key string) *binaryNode {
+	return &binaryNode{typ: binaryMap, key: key, end: binaryEnd}
+}
+
+// parseBinaryVDF parses a binary VDF file into a map node holding its
+// top-level entries. Bytes after the final terminator are kept in the
+// node's value.
+func parseBinaryVDF(data []byte) (*binaryNode, error) {
+	r := &binaryReader{data: data}
+	root := newBinaryMap("")
+	if err := r.readMap(root); err != nil {
+		return nil, err
+	}
+	root.value = data[r.pos:]
+	return root, nil
+}
+
+// encode returns the binary VDF encoding of a node parsed by parseBinaryVDF.
+func (n *binaryNode) encode() []byte {
+	var buf bytes.Buffer
+	n.writeChildren(&buf)
+	buf.Write(n.value)
+	return buf.Bytes()
+}
+
+// writeChildren writes a map's entries and its terminator.
+func (n *binaryNode) writeChildren(buf *bytes.Buffer) {
+	for _, child := range n.children {
+		buf.WriteByte(child.typ)
+		buf.WriteString(child.key)
+		buf.WriteByte(0)
+		switch child.typ {
+		case binaryMap:
+			child.writeChildren(buf)
+		case binaryString:
+			buf.Write(child.value)
+			buf.WriteByte(0)
+		case binaryWString:
+			buf.Write(child.value)
+			buf.Write([]byte{0, 0})
+		default:
+			buf.Write(child.value)
+		}
+	}
+	buf.WriteByte(n.end)
+}
+
+// clone returns a deep copy of the node.
+func (n *binaryNode) clone() *binaryNode {
+	c := &binaryNode{typ: n.typ, key: n.key, end: n.end}
+	if n.value != nil {
+		c.value = append([]byte{}, n.value...)
+	}
+	for _, child := range n.children {
+		c.children = append(c.children, child.clone())
+	}
+	return c
+}
+
+// child returns the entry with key, ignoring case as older Steam versions
+// wrote some keys in lowercase. Returns nil if there is none.
+func (n *binaryNode) child(key string) *binaryNode {
+	for _, child := range n.children {
+		if strings.EqualFold(child.key, key) {
+			return child
+		}
+	}
+	return nil
+}
+
+// getString returns the value of a string entry.
+func (n *binaryNode) getString(key string) (string, bool) {
+	child := n.child(key)
+	if child == nil || child.typ != binaryString {
+		return "", false
+	}
+	return string(child.value), true
+}
+
+// getUint32 returns the value of a 32-bit integer entry.
+func (n *binaryNode) getUint32(key string) (uint32, bool) {
+	child := n.child(key)
+	if child == nil || child.typ != binaryInt32 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(child.value), true
+}
+
+// set replaces the type and value of the entry with key, keeping its
+// position, or appends a new entry.
+func (n *binaryNode) set(key string, typ byte, value []byte) {
+	if child := n.child(key); child != nil {
+		child.typ, child.value, child.children = typ, value, nil
+		return
+	}
+	n.children = append(n.children, &binaryNode{typ: typ, key: key, value: value})
+}
+
+// setString sets a string entry.
+func (n *binaryNode) setString(key, value string) {
+	n.set(key, binaryString, []byte(value))
+}
+
+// setUint32 sets a 32-bit integer entry.
+func (n *binaryNode) setUint32(key string, value uint32) {
+	raw := make([]byte, 4)
+	binary.LittleEndian.PutUint32(raw, value)
+	n.set(key, binaryInt32, raw)
+}
+
+// setMap replaces the entry with key by a map, keeping its position.
+func (n *binaryNode) setMap(key string, m *binaryNode) {
+	for i, child := range n.children {
+		if strings.EqualFold(child.key, key) {
+			m.key = child.key
+			n.children[i] = m
+			return
+		}
+	}
+	m.key = key
+	n.children = append(n.children, m)
+}
+
+// binaryReader reads binary VDF data.
+type binaryReader struct {
+	data []byte
+	pos  int
+}
+
+// readMap reads entries into m until its terminator.
+func (r *binaryReader) readMap(m *binaryNode) error {
+	for {
+		if r.pos >= len(r.data) {
+			return fmt.Errorf("unexpected end of data at offset %d", r.pos)
+		}
+		typ := r.data[r.pos]
+		r.pos++
+		if typ == binaryEnd || typ == binaryEndAlt {
+			m.end = typ
+			return nil
+		}
+
+		key, err := r.readString()
+		if err != nil {
+			return err
+		}
+		node := &binaryNode{typ: typ, key: key}
+
+		switch typ {
+		case binaryMap:
+			if err := r.readMap(node); err != nil {
+				return err
+			}
+		case binaryString:
+			value, err := r.readString()
+			if err != nil {
+				return err
+			}
+			node.value = []byte(value)
+		case binaryWString:
+			if node.value, err = r.readWString(); err != nil {
+				return err
+			}
+		case binaryInt32, binaryFloat32, binaryPointer, binaryColor:
+			if node.value, err = r.readBytes(4); err != nil {
+				return err
+			}
+		case binaryUint64, binaryInt64:
+			if node.value, err = r.readBytes(8); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown value type 0x%02x for %q at offset %d", typ, key, r.pos)
+		}
+		m.children = append(m.children, node)
+	}
+}
+
+// readString reads a null-terminated string.
+func (r *binaryReader) readString() (string, error) {
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string at offset %d", r.pos)
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return s, nil
+}
+
+// readWString reads a UTF-16 string terminated by two null bytes, returning
+// its raw bytes.
+func (r *binaryReader) readWString() ([]byte, error) {
+	for i := r.pos; i+1 < len(r.data); i += 2 {
+		if r.data[i] == 0 && r.data[i+1] == 0 {
+			value := append([]byte{}, r.data[r.pos:i]...)
+			r.pos = i + 2
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("unterminated wide string at offset %d", r.pos)
+}
+
+// readBytes reads n raw bytes.
+func (r *binaryReader) readBytes(n int) ([]byte, error) {
+	if r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("unexpected end of data at offset %d", r.pos)
+	}
+	value := append([]byte{}, r.data[r.pos:r.pos+n]...)
+	r.pos += n
+	return value, nil
+}
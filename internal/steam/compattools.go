@@ -0,0 +1,179 @@
+package steam
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jslay88/vdf"
+)
+
+// CompatToolManifest is the file describing a custom compatibility tool.
+const CompatToolManifest = "compatibilitytool.vdf"
+
+// SystemCompatToolsPaths are the system-wide compatibilitytools.d directories
+// Steam searches for custom compatibility tools.
+var SystemCompatToolsPaths = []string{
+	"/usr/share/steam/compatibilitytools.d",
+	"/usr/local/share/steam/compatibilitytools.d",
+}
+
+// CompatTool is a custom compatibility tool such as GE-Proton.
+type CompatTool struct {
+	// Name is the internal name Steam uses in CompatToolMapping.
+	Name string
+	// DisplayName is the name shown in Steam's compatibility menu.
+	DisplayName string
+	// Path is the tool's install directory.
+	Path string
+	// ManifestPath is the path to the tool's compatibilitytool.vdf.
+	ManifestPath string
+}
+
+// Label returns the name to show for the tool, preferring the display name.
+func (t CompatTool) Label() string {
+	if t.DisplayName != "" {
+		return t.DisplayName
+	}
+	return t.Name
+}
+
+// IsProton checks if the tool is Proton-based, i.e. has a proton script.
+func (t CompatTool) IsProton() bool {
+	info, err := os.Stat(filepath.Join(t.Path, "proton"))
+	return err == nil && !info.IsDir()
+}
+
+// UserCompatToolsPath returns the compatibilitytools.d directory of the Steam
+// installation, where tools are installed.
+func (s *Steam) UserCompatToolsPath() string {
+	return filepath.Join(s.Path, "compatibilitytools.d")
+}
+
+// CompatToolsPaths returns the existing compatibilitytools.d directories in
+// search order: STEAM_EXTRA_COMPAT_TOOLS_PATHS, the Steam installation, the
+// ~/.steam links and the system directories. Duplicates are removed.
+func (s *Steam) CompatToolsPaths() []string {
+	var candidates []string
+	for _, path := range filepath.SplitList(os.Getenv("STEAM_EXTRA_COMPAT_TOOLS_PATHS")) {
+		if path != "" {
+			candidates = append(candidates, path)
+		}
+	}
+	candidates = append(candidates, s.UserCompatToolsPath())
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates,
+			filepath.Join(home, ".steam", "root", "compatibilitytools.d"),
+			filepath.Join(home, ".steam", "steam", "compatibilitytools.d"),
+		)
+	}
+	candidates = append(candidates, SystemCompatToolsPaths...)
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, path := range candidates {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+			continue
+		}
+		if seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// GetCompatTools returns the custom compatibility tools found in all
+// compatibilitytools.d directories. If several directories provide a tool
+// with the same internal name, the first one in search order wins.
+// Unreadable directories and tools with a missing or unparsable manifest are
+// skipped.
+func (s *Steam) GetCompatTools() ([]CompatTool, error) {
+	var tools []CompatTool
+	seen := make(map[string]bool)
+	for _, dir := range s.CompatToolsPaths() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			manifestPath := filepath.Join(dir, entry.Name(), CompatToolManifest)
+			found, err := ParseCompatToolManifest(manifestPath)
+			if err != nil {
+				continue
+			}
+			for _, tool := range found {
+				if seen[tool.Name] {
+					continue
+				}
+				seen[tool.Name] = true
+				tools = append(tools, tool)
+			}
+		}
+	}
+	return tools, nil
+}
+
+// ParseCompatToolManifest parses a compatibilitytool.vdf. A manifest may
+// declare several tools; install paths are relative to the manifest.
+func ParseCompatToolManifest(path string) ([]CompatTool, error) {
+	doc, err := vdf.ParseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	root := doc.Get("compatibilitytools")
+	if root == nil {
+		return nil, fmt.Errorf("%s has no compatibilitytools section", path)
+	}
+	compatTools := root.GetObject("compat_tools")
+	if compatTools == nil {
+		return nil, fmt.Errorf("%s has no compat_tools section", path)
+	}
+
+	dir := filepath.Dir(path)
+	var tools []CompatTool
+	for _, node := range compatTools.Children {
+		if !node.IsObject || node.Key == "" {
+			continue
+		}
+		installPath := node.GetString("install_path")
+		if installPath == "" {
+			installPath = "."
+		}
+		if !filepath.IsAbs(installPath) {
+			installPath = filepath.Join(dir, installPath)
+		}
+		tools = append(tools, CompatTool{
+			Name:         node.Key,
+			DisplayName:  node.GetString("display_name"),
+			Path:         filepath.Clean(installPath),
+			ManifestPath: path,
+		})
+	}
+	if len(tools) == 0 {
+		return nil, fmt.Errorf("%s declares no compatibility tools", path)
+	}
+	return tools, nil
+}
+
+// FindCompatTool finds a custom compatibility tool by internal or display
+// name, ignoring case. Returns nil if no tool matches.
+func (s *Steam) FindCompatTool(name string) (*CompatTool, error) {
+	tools, err := s.GetCompatTools()
+	if err != nil {
+		return nil, err
+	}
+	for i := range tools {
+		if strings.EqualFold(tools[i].Name, name) || strings.EqualFold(tools[i].DisplayName, name) {
+			return &tools[i], nil
+		}
+	}
+	return nil, nil
+}
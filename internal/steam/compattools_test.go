@@ -0,0 +1,210 @@
+package steam_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+// writeCompatTool creates a custom compatibility tool with a manifest and
+// a proton script in dir/folder.
+func writeCompatTool(dir, folder, name, displayName string) string {
+	toolPath := filepath.Join(dir, folder)
+	Expect(os.MkdirAll(toolPath, 0755)).To(Succeed())
+	manifest := `"compatibilitytools"
+{
+  "compat_tools"
+  {
+    "` + name + `" // Internal name of this tool
+    {
+      "install_path" "."
+      "display_name" "` + displayName + `"
+      "from_oslist"  "windows"
+      "to_oslist"    "linux"
+    }
+  }
+}
+`
+	Expect(os.WriteFile(filepath.Join(toolPath, steam.CompatToolManifest), []byte(manifest), 0644)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(toolPath, "proton"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+	return toolPath
+}
+
+var _ = Describe("CompatTools", func() {
+	var (
+		tmpDir        string
+		mockSteam     *steam.Steam
+		systemDir     string
+		originalHome  string
+		originalExtra string
+		originalPaths []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "compattools-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		steamPath := filepath.Join(tmpDir, "Steam")
+		Expect(os.MkdirAll(filepath.Join(steamPath, "steamapps", "common"), 0755)).To(Succeed())
+		mockSteam = &steam.Steam{
+			Path:       steamPath,
+			AppsPath:   filepath.Join(steamPath, "steamapps"),
+			CompatPath: filepath.Join(steamPath, "steamapps", "compatdata"),
+		}
+
+		systemDir = filepath.Join(tmpDir, "usr", "share", "steam", "compatibilitytools.d")
+		Expect(os.MkdirAll(systemDir, 0755)).To(Succeed())
+
+		originalHome = os.Getenv("HOME")
+		originalExtra = os.Getenv("STEAM_EXTRA_COMPAT_TOOLS_PATHS")
+		originalPaths = steam.SystemCompatToolsPaths
+		_ = os.Setenv("HOME", filepath.Join(tmpDir, "home"))
+		_ = os.Unsetenv("STEAM_EXTRA_COMPAT_TOOLS_PATHS")
+		steam.SystemCompatToolsPaths = []string{systemDir}
+	})
+
+	AfterEach(func() {
+		_ = os.Setenv("HOME", originalHome)
+		if originalExtra != "" {
+			_ = os.Setenv("STEAM_EXTRA_COMPAT_TOOLS_PATHS", originalExtra)
+		}
+		steam.SystemCompatToolsPaths = originalPaths
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("CompatToolsPaths", func() {
+		It("should only return existing directories", func() {
+			Expect(mockSteam.CompatToolsPaths()).To(Equal([]string{systemDir}))
+		})
+
+		It("should search extra paths first and skip duplicate links", func() {
+			userDir := filepath.Join(mockSteam.Path, "compatibilitytools.d")
+			Expect(os.MkdirAll(userDir, 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(tmpDir, "home", ".steam"), 0755)).To(Succeed())
+			Expect(os.Symlink(mockSteam.Path, filepath.Join(tmpDir, "home", ".steam", "root"))).To(Succeed())
+
+			extraDir := filepath.Join(tmpDir, "extra")
+			Expect(os.MkdirAll(extraDir, 0755)).To(Succeed())
+			_ = os.Setenv("STEAM_EXTRA_COMPAT_TOOLS_PATHS", extraDir+":"+filepath.Join(tmpDir, "missing"))
+
+			Expect(mockSteam.CompatToolsPaths()).To(Equal([]string{extraDir, userDir, systemDir}))
+		})
+	})
+
+	Describe("ParseCompatToolManifest", func() {
+		It("should read the internal name, display name and install path", func() {
+			toolPath := writeCompatTool(systemDir, "proton-custom", "proton_custom", "Proton Custom 1.2")
+
+			tools, err := steam.ParseCompatToolManifest(filepath.Join(toolPath, steam.CompatToolManifest))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tools).To(HaveLen(1))
+			Expect(tools[0].Name).To(Equal("proton_custom"))
+			Expect(tools[0].DisplayName).To(Equal("Proton Custom 1.2"))
+			Expect(tools[0].Path).To(Equal(toolPath))
+		})
+
+		It("should fail for a manifest without tools", func() {
+			path := filepath.Join(tmpDir, steam.CompatToolManifest)
+			Expect(os.WriteFile(path, []byte(`"compatibilitytools" { "compat_tools" { } }`), 0644)).To(Succeed())
+
+			_, err := steam.ParseCompatToolManifest(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetCompatTools", func() {
+		It("should find tools in the Steam and system directories", func() {
+			userDir := filepath.Join(mockSteam.Path, "compatibilitytools.d")
+			writeCompatTool(userDir, "GE-Proton9-20", "GE-Proton9-20", "GE-Proton9-20")
+			writeCompatTool(systemDir, "proton-cachyos", "proton-cachyos", "Proton CachyOS")
+
+			tools, err := mockSteam.GetCompatTools()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tools).To(HaveLen(2))
+			Expect(tools[0].Name).To(Equal("GE-Proton9-20"))
+			Expect(tools[1].Name).To(Equal("proton-cachyos"))
+		})
+
+		It("should prefer the first directory for duplicate names", func() {
+			userDir := filepath.Join(mockSteam.Path, "compatibilitytools.d")
+			userTool := writeCompatTool(userDir, "GE-Proton9-20", "GE-Proton9-20", "GE-Proton9-20")
+			writeCompatTool(systemDir, "GE-Proton9-20", "GE-Proton9-20", "GE-Proton9-20")
+
+			tools, err := mockSteam.GetCompatTools()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tools).To(HaveLen(1))
+			Expect(tools[0].Path).To(Equal(userTool))
+		})
+
+		It("should skip unreadable directories", func() {
+			if os.Getuid() == 0 {
+				Skip("Cannot test permissions as root")
+			}
+			userDir := filepath.Join(mockSteam.Path, "compatibilitytools.d")
+			writeCompatTool(userDir, "GE-Proton9-20", "GE-Proton9-20", "GE-Proton9-20")
+			writeCompatTool(systemDir, "proton-cachyos", "proton-cachyos", "Proton CachyOS")
+			Expect(os.Chmod(userDir, 0000)).To(Succeed())
+			defer func() { _ = os.Chmod(userDir, 0755) }()
+
+			tools, err := mockSteam.GetCompatTools()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tools).To(HaveLen(1))
+			Expect(tools[0].Name).To(Equal("proton-cachyos"))
+		})
+
+		It("should skip folders without a manifest", func() {
+			Expect(os.MkdirAll(filepath.Join(systemDir, "broken"), 0755)).To(Succeed())
+
+			tools, err := mockSteam.GetCompatTools()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tools).To(BeEmpty())
+		})
+	})
+
+	Describe("GetProtonVersions", func() {
+		It("should list Proton-based tools by display name", func() {
+			writeCompatTool(systemDir, "proton-cachyos", "proton-cachyos", "Proton CachyOS")
+			Expect(os.MkdirAll(filepath.Join(mockSteam.CommonPath(), "Proton 9.0"), 0755)).To(Succeed())
+
+			versions, err := mockSteam.GetProtonVersions()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]string{"Proton 9.0", "Proton CachyOS"}))
+		})
+
+		It("should skip tools that aren't Proton", func() {
+			toolPath := writeCompatTool(systemDir, "boxtron", "boxtron", "Boxtron")
+			Expect(os.Remove(filepath.Join(toolPath, "proton"))).To(Succeed())
+
+			versions, err := mockSteam.GetProtonVersions()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(BeEmpty())
+		})
+	})
+
+	Describe("GetProtonPath", func() {
+		It("should resolve tools by display or internal name", func() {
+			toolPath := writeCompatTool(systemDir, "proton-cachyos", "proton-cachyos", "Proton CachyOS")
+
+			path, err := mockSteam.GetProtonPath("Proton CachyOS")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(toolPath))
+
+			path, err = mockSteam.GetProtonPath("proton-cachyos")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(toolPath))
+		})
+	})
+
+	Describe("FindCompatTool", func() {
+		It("should return nil when no tool matches", func() {
+			tool, err := mockSteam.FindCompatTool("GE-Proton9-20")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tool).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,239 @@
+package steam
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// InstallType represents how Steam is installed.
+type InstallType string
+
+const (
+	InstallNative  InstallType = "native"
+	InstallFlatpak InstallType = "flatpak"
+	InstallSnap    InstallType = "snap"
+)
+
+// FlatpakAppID is the Flathub app ID of Steam.
+const FlatpakAppID = "com.valvesoftware.Steam"
+
+// FlatpakDataPath is the Flatpak app's home directory, relative to the user's.
+// Steam's files below it are reachable at the same path inside the sandbox.
+const FlatpakDataPath = ".var/app/" + FlatpakAppID
+
+// SnapDataPath is the Snap's home directory, relative to the user's.
+const SnapDataPath = "snap/steam/common"
+
+// snapRemovableMedia are the directories a snap can only access with the
+// removable-media interface connected.
+var snapRemovableMedia = []string{"/media", "/mnt", "/run/media"}
+
+// installTypeOf determines the install type from Steam's path.
+func installTypeOf(steamPath string) InstallType {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return InstallNative
+	}
+	switch {
+	case isWithin(steamPath, filepath.Join(home, FlatpakDataPath)):
+		return InstallFlatpak
+	case isWithin(steamPath, filepath.Join(home, filepath.Dir(SnapDataPath))):
+		return InstallSnap
+	default:
+		return InstallNative
+	}
+}
+
+// FindInstall finds a Steam installation by install type or path.
+func FindInstall(installs []*Steam, name string) (*Steam, error) {
+	for _, s := range installs {
+		if strings.EqualFold(string(s.Type), name) || samePath(s.Path, name) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("Steam installation not found: %s", name)
+}
+
+// Label returns a description of the installation for display.
+func (s *Steam) Label() string {
+	return fmt.Sprintf("%s (%s)", s.Path, s.Type)
+}
+
+// SandboxPath translates a host path to the path the Steam client sees. Only
+// Flatpak Steam sees its app directory as the home directory; other paths are
+// the same on both sides.
+func (s *Steam) SandboxPath(path string) string {
+	if s.Type != InstallFlatpak {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	if rel, ok := relativeTo(path, filepath.Join(home, FlatpakDataPath)); ok {
+		return filepath.Join(home, rel)
+	}
+	return path
+}
+
+// HostPath translates a path written by the Steam client, such as a library
+// in libraryfolders.vdf, to the host. It is the inverse of SandboxPath.
+func (s *Steam) HostPath(path string) string {
+	if s.Type != InstallFlatpak {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	appHome := filepath.Join(home, FlatpakDataPath)
+	if isWithin(path, appHome) {
+		return path
+	}
+	// A path below home is only real if Steam was given access to it
+	if rel, ok := relativeTo(path, home); ok {
+		candidate := filepath.Join(appHome, rel)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path
+}
+
+// NeedsFilesystemAccess checks if the sandbox must be granted access to path
+// before Steam can launch a game from it.
+func (s *Steam) NeedsFilesystemAccess(path string) bool {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+
+	switch s.Type {
+	case InstallFlatpak:
+		if isWithin(path, filepath.Join(home, FlatpakDataPath)) {
+			return false
+		}
+		for _, granted := range flatpakFilesystems(home) {
+			if isWithin(path, granted) {
+				return false
+			}
+		}
+		return true
+	case InstallSnap:
+		if isWithin(path, filepath.Join(home, "snap", "steam")) {
+			return false
+		}
+		// The home interface excludes hidden files and directories
+		if rel, ok := relativeTo(path, home); ok {
+			return rel != "." && strings.HasPrefix(rel, ".")
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// GrantFilesystemAccess makes path reachable from inside the sandbox. For
+// Flatpak Steam a user override is added; Snap permissions can't be changed
+// without root, so an error explains what to do instead.
+func (s *Steam) GrantFilesystemAccess(path string) error {
+	switch s.Type {
+	case InstallFlatpak:
+		cmd := exec.Command("flatpak", "override", "--user", "--filesystem="+path, FlatpakAppID)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to add flatpak override for %s: %w\n%s", path, err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	case InstallSnap:
+		for _, media := range snapRemovableMedia {
+			if isWithin(path, media) {
+				return fmt.Errorf("snap Steam can't access %s; run `sudo snap connect steam:removable-media`", path)
+			}
+		}
+		return fmt.Errorf("snap Steam can't access %s; install the game outside hidden directories in your home directory", path)
+	default:
+		return nil
+	}
+}
+
+// flatpakFilesystems returns the directories granted to Flatpak Steam by the
+// user's and the system's overrides.
+func flatpakFilesystems(home string) []string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	var dirs []string
+	for _, overrides := range []string{
+		filepath.Join(dataHome, "flatpak", "overrides", FlatpakAppID),
+		filepath.Join("/var/lib/flatpak/overrides", FlatpakAppID),
+	} {
+		for _, fs := range parseFlatpakFilesystems(overrides) {
+			// Drop access modes such as ":ro"; read-only access is enough to launch
+			fs, _, _ = strings.Cut(fs, ":")
+			switch {
+			case fs == "host" || fs == "home":
+				dirs = append(dirs, home)
+				if fs == "host" {
+					dirs = append(dirs, "/")
+				}
+			case strings.HasPrefix(fs, "~/"):
+				dirs = append(dirs, filepath.Join(home, fs[2:]))
+			case filepath.IsAbs(fs):
+				dirs = append(dirs, fs)
+			}
+		}
+	}
+	return dirs
+}
+
+// parseFlatpakFilesystems reads the granted filesystems from the [Context]
+// section of a flatpak override file. Revoked ("!") entries are skipped.
+func parseFlatpakFilesystems(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var filesystems []string
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section != "Context" || strings.TrimSpace(key) != "filesystems" {
+			continue
+		}
+		for _, fs := range strings.Split(value, ";") {
+			if fs = strings.TrimSpace(fs); fs != "" && !strings.HasPrefix(fs, "!") {
+				filesystems = append(filesystems, fs)
+			}
+		}
+	}
+	return filesystems
+}
+
+// isWithin checks if path is base or inside it.
+func isWithin(path, base string) bool {
+	_, ok := relativeTo(path, base)
+	return ok
+}
+
+// relativeTo returns path relative to base, if path is base or inside it.
+func relativeTo(path, base string) (string, bool) {
+	rel, err := filepath.Rel(filepath.Clean(base), filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return rel, true
+}
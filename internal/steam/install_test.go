@@ -0,0 +1,191 @@
+package steam_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+// makeSteamDir creates the directories of a Steam installation at path.
+func makeSteamDir(path string) {
+	for _, dir := range []string{"userdata", "config", "steamapps"} {
+		Expect(os.MkdirAll(filepath.Join(path, dir), 0755)).To(Succeed())
+	}
+}
+
+var _ = Describe("Install", func() {
+	var (
+		tmpDir           string
+		home             string
+		originalHome     string
+		originalSteamDir string
+		originalDataHome string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "install-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		home, err = filepath.EvalSymlinks(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+		home = filepath.Join(home, "home")
+		Expect(os.MkdirAll(home, 0755)).To(Succeed())
+
+		originalHome = os.Getenv("HOME")
+		originalSteamDir = os.Getenv("STEAM_DIR")
+		originalDataHome = os.Getenv("XDG_DATA_HOME")
+		_ = os.Setenv("HOME", home)
+		_ = os.Unsetenv("STEAM_DIR")
+		_ = os.Unsetenv("XDG_DATA_HOME")
+	})
+
+	AfterEach(func() {
+		_ = os.Setenv("HOME", originalHome)
+		_ = os.Setenv("STEAM_DIR", originalSteamDir)
+		if originalSteamDir == "" {
+			_ = os.Unsetenv("STEAM_DIR")
+		}
+		_ = os.Setenv("XDG_DATA_HOME", originalDataHome)
+		if originalDataHome == "" {
+			_ = os.Unsetenv("XDG_DATA_HOME")
+		}
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("DiscoverAll", func() {
+		It("should find native, Flatpak and Snap installations", func() {
+			makeSteamDir(filepath.Join(home, ".local", "share", "Steam"))
+			makeSteamDir(filepath.Join(home, steam.FlatpakDataPath, ".local", "share", "Steam"))
+			makeSteamDir(filepath.Join(home, steam.SnapDataPath, ".local", "share", "Steam"))
+
+			installs, err := steam.DiscoverAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installs).To(HaveLen(3))
+			Expect(installs[0].Type).To(Equal(steam.InstallNative))
+			Expect(installs[1].Type).To(Equal(steam.InstallFlatpak))
+			Expect(installs[1].Path).To(Equal(filepath.Join(home, steam.FlatpakDataPath, ".local", "share", "Steam")))
+			Expect(installs[2].Type).To(Equal(steam.InstallSnap))
+		})
+
+		It("should list an installation reached through a symlink once", func() {
+			steamPath := filepath.Join(home, steam.FlatpakDataPath, ".local", "share", "Steam")
+			makeSteamDir(steamPath)
+			Expect(os.MkdirAll(filepath.Join(home, ".steam"), 0755)).To(Succeed())
+			Expect(os.Symlink(steamPath, filepath.Join(home, ".steam", "steam"))).To(Succeed())
+
+			installs, err := steam.DiscoverAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installs).To(HaveLen(1))
+			Expect(installs[0].Type).To(Equal(steam.InstallFlatpak))
+		})
+
+		It("should fail when no installation exists", func() {
+			_, err := steam.DiscoverAll()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not found"))
+		})
+
+		It("should return the first installation from Discover", func() {
+			makeSteamDir(filepath.Join(home, steam.FlatpakDataPath, ".local", "share", "Steam"))
+
+			s, err := steam.Discover()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Type).To(Equal(steam.InstallFlatpak))
+		})
+	})
+
+	Describe("FindInstall", func() {
+		It("should find installations by type or path", func() {
+			native := &steam.Steam{Path: filepath.Join(home, "native"), Type: steam.InstallNative}
+			flatpak := &steam.Steam{Path: filepath.Join(home, "flatpak"), Type: steam.InstallFlatpak}
+			installs := []*steam.Steam{native, flatpak}
+
+			Expect(steam.FindInstall(installs, "Flatpak")).To(Equal(flatpak))
+			Expect(steam.FindInstall(installs, filepath.Join(home, "native"))).To(Equal(native))
+			_, err := steam.FindInstall(installs, "snap")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Flatpak paths", func() {
+		var s *steam.Steam
+		var appHome string
+
+		BeforeEach(func() {
+			appHome = filepath.Join(home, steam.FlatpakDataPath)
+			steamPath := filepath.Join(appHome, ".local", "share", "Steam")
+			makeSteamDir(steamPath)
+			s = &steam.Steam{Path: steamPath, AppsPath: filepath.Join(steamPath, "steamapps"), Type: steam.InstallFlatpak}
+		})
+
+		It("should translate paths in the app's home to the sandbox", func() {
+			exe := filepath.Join(appHome, ".local", "share", "Steam", "steamapps", "common", "ZLADXHD", "game.exe")
+			Expect(s.SandboxPath(exe)).To(Equal(filepath.Join(home, ".local", "share", "Steam", "steamapps", "common", "ZLADXHD", "game.exe")))
+			Expect(s.SandboxPath("/games/ZLADXHD/game.exe")).To(Equal("/games/ZLADXHD/game.exe"))
+		})
+
+		It("should translate sandbox paths back to the host", func() {
+			Expect(s.HostPath(filepath.Join(home, ".local", "share", "Steam"))).To(Equal(s.Path))
+			Expect(s.HostPath("/games")).To(Equal("/games"))
+		})
+
+		It("should not translate paths for native Steam", func() {
+			native := &steam.Steam{Path: filepath.Join(home, ".local", "share", "Steam"), Type: steam.InstallNative}
+			path := filepath.Join(appHome, "game.exe")
+			Expect(native.SandboxPath(path)).To(Equal(path))
+			Expect(native.NeedsFilesystemAccess("/games")).To(BeFalse())
+		})
+
+		It("should need access to directories outside the app's home", func() {
+			Expect(s.NeedsFilesystemAccess(filepath.Join(s.Path, "steamapps", "common", "ZLADXHD"))).To(BeFalse())
+			Expect(s.NeedsFilesystemAccess("/games/ZLADXHD")).To(BeTrue())
+			Expect(s.NeedsFilesystemAccess(filepath.Join(home, "Games", "ZLADXHD"))).To(BeTrue())
+		})
+
+		It("should honor filesystem overrides", func() {
+			overridesDir := filepath.Join(home, ".local", "share", "flatpak", "overrides")
+			Expect(os.MkdirAll(overridesDir, 0755)).To(Succeed())
+			overrides := "[Context]\nfilesystems=/games;~/Games:ro;!/mnt;\n"
+			Expect(os.WriteFile(filepath.Join(overridesDir, steam.FlatpakAppID), []byte(overrides), 0644)).To(Succeed())
+
+			Expect(s.NeedsFilesystemAccess("/games/ZLADXHD")).To(BeFalse())
+			Expect(s.NeedsFilesystemAccess(filepath.Join(home, "Games", "ZLADXHD"))).To(BeFalse())
+			Expect(s.NeedsFilesystemAccess("/mnt/games/ZLADXHD")).To(BeTrue())
+		})
+
+		It("should read libraries as seen from the sandbox", func() {
+			Expect(os.WriteFile(s.LibraryFoldersPath(), []byte(`"libraryfolders"
+{
+	"0"
+	{
+		"path"		"`+filepath.Join(home, ".local", "share", "Steam")+`"
+	}
+}
+`), 0644)).To(Succeed())
+
+			libraries, err := s.GetLibraries()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(libraries).To(HaveLen(1))
+			Expect(libraries[0].Path).To(Equal(s.Path))
+		})
+	})
+
+	Describe("Snap paths", func() {
+		It("should need access to hidden and outside directories", func() {
+			s := &steam.Steam{Path: filepath.Join(home, steam.SnapDataPath, ".local", "share", "Steam"), Type: steam.InstallSnap}
+
+			Expect(s.NeedsFilesystemAccess(filepath.Join(s.Path, "steamapps", "common", "ZLADXHD"))).To(BeFalse())
+			Expect(s.NeedsFilesystemAccess(filepath.Join(home, "Games", "ZLADXHD"))).To(BeFalse())
+			Expect(s.NeedsFilesystemAccess(filepath.Join(home, ".games", "ZLADXHD"))).To(BeTrue())
+			Expect(s.NeedsFilesystemAccess("/mnt/games")).To(BeTrue())
+
+			err := s.GrantFilesystemAccess("/mnt/games")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("removable-media"))
+		})
+	})
+})
@@ -0,0 +1,94 @@
+package steam
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// ModeArgs are the command line options that select how the Steam client
+// runs: in Big Picture, minimized to the tray, or with the Steam Deck UI.
+var ModeArgs = []string{"-bigpicture", "-tenfoot", "-silent", "-gamepadui", "-steamdeck", "-steamos3"}
+
+// shortcutGameIDFlag marks a 64-bit game ID as a non-Steam shortcut.
+const shortcutGameIDFlag = 0x02000000
+
+// ShortcutGameID returns the 64-bit game ID Steam uses for a non-Steam
+// shortcut in steam://rungameid links.
+func ShortcutGameID(appID uint32) uint64 {
+	return uint64(appID)<<32 | shortcutGameIDFlag
+}
+
+// ShortAppID returns the 32-bit AppID of a shortcut's 64-bit game ID. It names
+// the shortcut's artwork in the grid directory.
+func ShortAppID(gameID uint64) uint32 {
+	return uint32(gameID >> 32)
+}
+
+// RunGameURL returns the URL that launches a game.
+func RunGameURL(gameID uint64) string {
+	return fmt.Sprintf("steam://rungameid/%d", gameID)
+}
+
+// LibraryURL returns the URL that opens a game's page in the library.
+func LibraryURL(gameID uint64) string {
+	return fmt.Sprintf("steam://nav/games/details/%d", gameID)
+}
+
+// RunningModeArgs returns the ModeArgs the running client was started with,
+// so it can be restarted the same way. Returns nil if Steam isn't running.
+func (s *Steam) RunningModeArgs() []string {
+	processes, err := s.Processes()
+	if err != nil {
+		return nil
+	}
+
+	var args []string
+	for _, p := range processes {
+		// The wrapper scripts are passed the user's options; the client gets them too
+		if p.Name != "steam" && p.Name != "steam.sh" && p.Name != "steam-wrapper" {
+			continue
+		}
+		for _, arg := range p.Cmdline {
+			if containsFold(ModeArgs, arg) && !containsFold(args, arg) {
+				args = append(args, arg)
+			}
+		}
+	}
+	return args
+}
+
+// Launch starts this Steam installation detached from the installer, so it
+// keeps running after the installer exits. args are passed to the client,
+// e.g. ModeArgs or a steam:// URL.
+func (s *Steam) Launch(args ...string) error {
+	cmd, err := s.launchCommand(args...)
+	if err != nil {
+		return err
+	}
+
+	// A new session keeps Steam alive when the terminal is closed
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start Steam: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+// launchCommand returns the command starting this installation's client.
+func (s *Steam) launchCommand(args ...string) (*exec.Cmd, error) {
+	switch s.Type {
+	case InstallFlatpak:
+		return exec.Command("flatpak", append([]string{"run", FlatpakAppID}, args...)...), nil
+	case InstallSnap:
+		return exec.Command("snap", append([]string{"run", "steam"}, args...)...), nil
+	}
+	if path, err := exec.LookPath("steam"); err == nil {
+		return exec.Command(path, args...), nil
+	}
+	if script := filepath.Join(s.Path, "steam.sh"); fileExists(script) {
+		return exec.Command(script, args...), nil
+	}
+	return nil, fmt.Errorf("steam command not found")
+}
@@ -0,0 +1,71 @@
+package steam_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("Launch", func() {
+	Describe("ShortcutGameID", func() {
+		It("should put the AppID in the upper 32 bits and mark it as a shortcut", func() {
+			Expect(steam.ShortcutGameID(0xC0000001)).To(Equal(uint64(0xC000000102000000)))
+			Expect(steam.ShortcutGameID(0xFF000001)).To(Equal(uint64(18374686484000145408)))
+		})
+	})
+
+	Describe("URLs", func() {
+		It("should build steam:// links from the game ID", func() {
+			gameID := steam.ShortcutGameID(0xC0000001)
+			Expect(steam.RunGameURL(gameID)).To(Equal("steam://rungameid/13835058059610685440"))
+			Expect(steam.LibraryURL(gameID)).To(Equal("steam://nav/games/details/13835058059610685440"))
+		})
+	})
+
+	Describe("RunningModeArgs", func() {
+		var (
+			tmpDir           string
+			originalHome     string
+			originalProcRoot string
+			procRoot         string
+			s                *steam.Steam
+		)
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "launch-test-*")
+			Expect(err).NotTo(HaveOccurred())
+			procRoot = filepath.Join(tmpDir, "proc")
+			Expect(os.MkdirAll(procRoot, 0755)).To(Succeed())
+
+			originalHome = os.Getenv("HOME")
+			originalProcRoot = steam.ProcRoot
+			_ = os.Setenv("HOME", filepath.Join(tmpDir, "home"))
+			steam.ProcRoot = procRoot
+
+			s = &steam.Steam{Path: filepath.Join(tmpDir, "home", ".local", "share", "Steam"), Type: steam.InstallNative}
+		})
+
+		AfterEach(func() {
+			_ = os.Setenv("HOME", originalHome)
+			steam.ProcRoot = originalProcRoot
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		It("should return nil when Steam isn't running", func() {
+			Expect(s.RunningModeArgs()).To(BeEmpty())
+		})
+
+		It("should collect the mode options of the client and its script", func() {
+			writeProc(procRoot, 100, 1, "steam.sh", "/bin/bash", s.Path+"/steam.sh", "-silent")
+			writeProc(procRoot, 101, 100, "steam", s.Path+"/ubuntu12_32/steam", "-silent", "-gamepadui", "-console")
+			writeProc(procRoot, 102, 101, "steamwebhelper", s.Path+"/ubuntu12_64/steamwebhelper", "-bigpicture")
+
+			Expect(s.RunningModeArgs()).To(Equal([]string{"-silent", "-gamepadui"}))
+		})
+	})
+})
@@ -0,0 +1,196 @@
+package steam
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/jslay88/vdf"
+)
+
+// Library is a Steam library folder.
+type Library struct {
+	// Path is the library's root, containing steamapps.
+	Path string
+	// Label is the name given to the library in Steam, if any.
+	Label string
+	// Mounted is false if the library's steamapps directory can't be found,
+	// e.g. because its drive isn't mounted.
+	Mounted bool
+	// FreeSpace is the space available to the user in bytes; 0 if unmounted.
+	FreeSpace uint64
+}
+
+// AppsPath returns the path to the library's steamapps directory.
+func (l Library) AppsPath() string {
+	return filepath.Join(l.Path, "steamapps")
+}
+
+// CommonPath returns the path to the library's common games directory.
+func (l Library) CommonPath() string {
+	return filepath.Join(l.AppsPath(), "common")
+}
+
+// LibraryFoldersPath returns the path to libraryfolders.vdf.
+func (s *Steam) LibraryFoldersPath() string {
+	return filepath.Join(s.AppsPath, "libraryfolders.vdf")
+}
+
+// GetLibraries returns the Steam library folders listed in
+// libraryfolders.vdf, starting with the Steam installation's own library,
+// which is included even if the file is missing.
+func (s *Steam) GetLibraries() ([]Library, error) {
+	paths := []string{filepath.Dir(s.AppsPath)}
+	labels := map[string]string{}
+
+	if _, err := os.Stat(s.LibraryFoldersPath()); err == nil {
+		folders, err := parseLibraryFolders(s.LibraryFoldersPath())
+		if err != nil {
+			return nil, err
+		}
+		for _, folder := range folders {
+			// Flatpak Steam writes the paths as seen from its sandbox
+			path := s.HostPath(folder.path)
+			paths = append(paths, path)
+			labels[path] = folder.label
+		}
+	}
+
+	seen := make(map[string]bool)
+	var libraries []Library
+	for _, path := range paths {
+		key := filepath.Clean(path)
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			key = resolved
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		lib := Library{Path: filepath.Clean(path), Label: labels[path]}
+		if info, err := os.Stat(lib.AppsPath()); err == nil && info.IsDir() {
+			lib.Mounted = true
+			lib.FreeSpace = freeSpace(lib.Path)
+		}
+		libraries = append(libraries, lib)
+	}
+	return libraries, nil
+}
+
+// MountedLibraries returns the libraries whose steamapps directory exists.
+func (s *Steam) MountedLibraries() ([]Library, error) {
+	libraries, err := s.GetLibraries()
+	if err != nil {
+		return nil, err
+	}
+
+	var mounted []Library
+	for _, lib := range libraries {
+		if lib.Mounted {
+			mounted = append(mounted, lib)
+		}
+	}
+	return mounted, nil
+}
+
+// FindLibrary finds a library by path, label or index in libraries.
+func FindLibrary(libraries []Library, name string) (*Library, error) {
+	for i := range libraries {
+		if samePath(libraries[i].Path, name) || (libraries[i].Label != "" && strings.EqualFold(libraries[i].Label, name)) {
+			return &libraries[i], nil
+		}
+	}
+	if idx, err := strconv.Atoi(name); err == nil && idx >= 0 && idx < len(libraries) {
+		return &libraries[idx], nil
+	}
+	return nil, fmt.Errorf("Steam library not found: %s", name)
+}
+
+// samePath checks if two paths refer to the same directory, resolving symlinks.
+func samePath(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	ra, errA := filepath.EvalSymlinks(a)
+	rb, errB := filepath.EvalSymlinks(b)
+	return errA == nil && errB == nil && ra == rb
+}
+
+// appsPaths returns the steamapps directories of all mounted libraries,
+// falling back to the Steam installation's own.
+func (s *Steam) appsPaths() []string {
+	libraries, err := s.MountedLibraries()
+	if err != nil || len(libraries) == 0 {
+		return []string{s.AppsPath}
+	}
+
+	paths := make([]string, 0, len(libraries))
+	for _, lib := range libraries {
+		paths = append(paths, lib.AppsPath())
+	}
+	return paths
+}
+
+// libraryFolder is an entry in libraryfolders.vdf.
+type libraryFolder struct {
+	path  string
+	label string
+}
+
+// parseLibraryFolders reads the library entries of a libraryfolders.vdf in
+// the current format ("<n>" { "path" "..." }) or the old one ("<n>" "path").
+func parseLibraryFolders(path string) ([]libraryFolder, error) {
+	doc, err := vdf.ParseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse libraryfolders.vdf: %w", err)
+	}
+
+	root := doc.Get("libraryfolders")
+	if root == nil {
+		root = doc.Get("LibraryFolders")
+	}
+	if root == nil || !root.IsObject {
+		return nil, fmt.Errorf("libraryfolders.vdf has no libraryfolders section")
+	}
+
+	type indexed struct {
+		index int
+		libraryFolder
+	}
+	var entries []indexed
+	for _, node := range root.Children {
+		index, err := strconv.Atoi(node.Key)
+		if err != nil {
+			continue
+		}
+		folder := libraryFolder{path: node.Value}
+		if node.IsObject {
+			folder = libraryFolder{path: node.GetString("path"), label: node.GetString("label")}
+		}
+		if folder.path == "" {
+			continue
+		}
+		entries = append(entries, indexed{index, folder})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].index < entries[j].index })
+
+	folders := make([]libraryFolder, 0, len(entries))
+	for _, entry := range entries {
+		folders = append(folders, entry.libraryFolder)
+	}
+	return folders, nil
+}
+
+// freeSpace returns the space available to the user on path's filesystem.
+func freeSpace(path string) uint64 {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0
+	}
+	return stat.Bavail * uint64(stat.Bsize)
+}
@@ -0,0 +1,174 @@
+package steam_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("Libraries", func() {
+	var tmpDir string
+	var mockSteam *steam.Steam
+	var secondLibrary string
+
+	writeLibraryFolders := func(content string) {
+		Expect(os.WriteFile(mockSteam.LibraryFoldersPath(), []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "libraries-test-*")
+		Expect(err).NotTo(HaveOccurred())
+
+		steamPath := filepath.Join(tmpDir, "Steam")
+		Expect(os.MkdirAll(filepath.Join(steamPath, "steamapps", "common"), 0755)).To(Succeed())
+		mockSteam = &steam.Steam{
+			Path:     steamPath,
+			AppsPath: filepath.Join(steamPath, "steamapps"),
+		}
+
+		secondLibrary = filepath.Join(tmpDir, "ssd", "SteamLibrary")
+		Expect(os.MkdirAll(filepath.Join(secondLibrary, "steamapps", "common"), 0755)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("GetLibraries", func() {
+		It("should return the Steam library without libraryfolders.vdf", func() {
+			libraries, err := mockSteam.GetLibraries()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(libraries).To(HaveLen(1))
+			Expect(libraries[0].Path).To(Equal(mockSteam.Path))
+			Expect(libraries[0].Mounted).To(BeTrue())
+			Expect(libraries[0].FreeSpace).To(BeNumerically(">", 0))
+		})
+
+		It("should parse the current format", func() {
+			writeLibraryFolders(`"libraryfolders"
+{
+	"0"
+	{
+		"path"		"` + mockSteam.Path + `"
+		"label"		""
+		"apps" { "228980" "123" }
+	}
+	"1"
+	{
+		"path"		"` + secondLibrary + `"
+		"label"		"SSD"
+	}
+	"2"
+	{
+		"path"		"` + filepath.Join(tmpDir, "usb", "SteamLibrary") + `"
+	}
+}
+`)
+
+			libraries, err := mockSteam.GetLibraries()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(libraries).To(HaveLen(3))
+			Expect(libraries[0].Path).To(Equal(mockSteam.Path))
+			Expect(libraries[1].Path).To(Equal(secondLibrary))
+			Expect(libraries[1].Label).To(Equal("SSD"))
+			Expect(libraries[1].Mounted).To(BeTrue())
+			Expect(libraries[2].Mounted).To(BeFalse())
+			Expect(libraries[2].FreeSpace).To(BeZero())
+
+			mounted, err := mockSteam.MountedLibraries()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounted).To(HaveLen(2))
+		})
+
+		It("should parse the old format", func() {
+			writeLibraryFolders(`"LibraryFolders"
+{
+	"TimeNextStatsReport"		"1700000000"
+	"ContentStatsID"		"-123"
+	"1"		"` + secondLibrary + `"
+}
+`)
+
+			libraries, err := mockSteam.GetLibraries()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(libraries).To(HaveLen(2))
+			Expect(libraries[1].Path).To(Equal(secondLibrary))
+		})
+
+		It("should fail for a broken libraryfolders.vdf", func() {
+			writeLibraryFolders(`"something" { }`)
+
+			_, err := mockSteam.GetLibraries()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("FindLibrary", func() {
+		var libraries []steam.Library
+
+		BeforeEach(func() {
+			libraries = []steam.Library{
+				{Path: mockSteam.Path},
+				{Path: secondLibrary, Label: "SSD"},
+			}
+		})
+
+		It("should find a library by path", func() {
+			library, err := steam.FindLibrary(libraries, secondLibrary+"/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(library.Path).To(Equal(secondLibrary))
+		})
+
+		It("should find a library by label", func() {
+			library, err := steam.FindLibrary(libraries, "ssd")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(library.Path).To(Equal(secondLibrary))
+		})
+
+		It("should find a library by index", func() {
+			library, err := steam.FindLibrary(libraries, "0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(library.Path).To(Equal(mockSteam.Path))
+		})
+
+		It("should fail for an unknown library", func() {
+			_, err := steam.FindLibrary(libraries, "/nowhere")
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+		})
+	})
+
+	Describe("Proton in other libraries", func() {
+		BeforeEach(func() {
+			writeLibraryFolders(`"libraryfolders" { "0" { "path" "` + mockSteam.Path + `" } "1" { "path" "` + secondLibrary + `" } }`)
+			Expect(os.MkdirAll(filepath.Join(mockSteam.CommonPath(), "Proton 9.0"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(secondLibrary, "steamapps", "common", "Proton 10.0"), 0755)).To(Succeed())
+		})
+
+		It("should list Proton versions from all libraries", func() {
+			versions, err := mockSteam.GetProtonVersions()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(ConsistOf("Proton 9.0", "Proton 10.0"))
+		})
+
+		It("should resolve the path of a Proton in another library", func() {
+			path, err := mockSteam.GetProtonPath("Proton 10.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(secondLibrary, "steamapps", "common", "Proton 10.0")))
+		})
+
+		It("should read app manifests from all libraries", func() {
+			manifest := `"AppState" { "appid" "3658110" "name" "Proton 10.0" "installdir" "Proton 10.0" }`
+			Expect(os.WriteFile(filepath.Join(secondLibrary, "steamapps", "appmanifest_3658110.acf"), []byte(manifest), 0644)).To(Succeed())
+
+			app, err := mockSteam.FindAppByInstallDir("Proton 10.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(app).NotTo(BeNil())
+			Expect(app.AppID).To(Equal(uint32(3658110)))
+		})
+	})
+})
@@ -0,0 +1,311 @@
+package steam
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ProcRoot is the root of the proc filesystem processes are read from.
+var ProcRoot = "/proc"
+
+// ProcessNames are the names of the processes that make up a running Steam:
+// the client and its launch script, the Flatpak wrapper, the web helper that
+// draws its UI and the reaper that supervises games.
+var ProcessNames = []string{"steam", "steam.sh", "steam-wrapper", "steamwebhelper", "reaper"}
+
+// Process is a running process.
+type Process struct {
+	PID     int
+	PPID    int
+	Name    string
+	Cmdline []string
+	// Type is the kind of Steam installation the process belongs to.
+	Type InstallType
+}
+
+// ShutdownOptions configures how long Shutdown waits at each step.
+type ShutdownOptions struct {
+	// Graceful is how long Steam gets to exit after `steam -shutdown`.
+	Graceful time.Duration
+	// Terminate is how long processes get to exit after SIGTERM.
+	Terminate time.Duration
+}
+
+// DefaultShutdownOptions returns the default shutdown timeouts.
+func DefaultShutdownOptions() ShutdownOptions {
+	return ShutdownOptions{
+		Graceful:  30 * time.Second,
+		Terminate: 10 * time.Second,
+	}
+}
+
+// PIDFilePath returns the path to the steam.pid file Steam writes on start.
+// Sandboxed Steam writes it below its own home directory.
+func (s *Steam) PIDFilePath() string {
+	home, _ := os.UserHomeDir()
+	switch s.Type {
+	case InstallFlatpak:
+		home = filepath.Join(home, FlatpakDataPath)
+	case InstallSnap:
+		home = filepath.Join(home, SnapDataPath)
+	}
+	return filepath.Join(home, ".steam", "steam.pid")
+}
+
+// Processes returns the running processes of this Steam installation: every
+// process with one of ProcessNames and all descendants of the PID in
+// steam.pid, such as running games. Sorted by PID.
+func (s *Steam) Processes() ([]Process, error) {
+	all, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	byPID := make(map[int]Process, len(all))
+	children := make(map[int][]int)
+	for _, p := range all {
+		byPID[p.PID] = p
+		children[p.PPID] = append(children[p.PPID], p.PID)
+	}
+
+	// Never count the installer itself, e.g. when started from a game
+	selected := map[int]bool{os.Getpid(): true}
+	var visit func(pid int)
+	visit = func(pid int) {
+		if selected[pid] {
+			return
+		}
+		selected[pid] = true
+		for _, child := range children[pid] {
+			visit(child)
+		}
+	}
+
+	for _, p := range all {
+		if p.Type == s.Type && containsFold(ProcessNames, p.Name) {
+			visit(p.PID)
+		}
+	}
+	if pid, err := s.readPIDFile(); err == nil {
+		// A stale steam.pid may name an unrelated process by now
+		if p, ok := byPID[pid]; ok && p.Type == s.Type && (containsFold(ProcessNames, p.Name) || s.ownsProcess(p)) {
+			visit(pid)
+		}
+	}
+
+	delete(selected, os.Getpid())
+	processes := make([]Process, 0, len(selected))
+	for pid := range selected {
+		if p, ok := byPID[pid]; ok {
+			processes = append(processes, p)
+		}
+	}
+	sort.Slice(processes, func(i, j int) bool { return processes[i].PID < processes[j].PID })
+	return processes, nil
+}
+
+// IsRunning checks if this Steam installation is currently running.
+func (s *Steam) IsRunning() bool {
+	processes, err := s.Processes()
+	return err == nil && len(processes) > 0
+}
+
+// GetPID returns the PID of the running Steam client. steam.pid is used if it
+// names a live Steam process; otherwise the oldest process named "steam".
+func (s *Steam) GetPID() (int, error) {
+	processes, err := s.Processes()
+	if err != nil {
+		return 0, err
+	}
+
+	if pid, err := s.readPIDFile(); err == nil {
+		for _, p := range processes {
+			if p.PID == pid {
+				return pid, nil
+			}
+		}
+	}
+	for _, p := range processes {
+		if p.Name == "steam" {
+			return p.PID, nil
+		}
+	}
+	return 0, fmt.Errorf("Steam is not running")
+}
+
+// Kill shuts Steam down with the default timeouts. See Shutdown.
+func (s *Steam) Kill() error {
+	return s.Shutdown(DefaultShutdownOptions())
+}
+
+// Shutdown stops Steam and waits until all of its processes have exited, so
+// its files can be modified safely. Steam is first asked to exit with
+// `steam -shutdown`, which lets it save its state. Processes still running
+// after that are sent SIGTERM and, finally, SIGKILL.
+func (s *Steam) Shutdown(opts ShutdownOptions) error {
+	if !s.IsRunning() {
+		return nil
+	}
+
+	if cmd, err := s.launchCommand("-shutdown"); err == nil && cmd.Start() == nil {
+		// The command only passes the request to the running client
+		go func() { _ = cmd.Wait() }()
+		if s.WaitForExit(opts.Graceful) == nil {
+			return nil
+		}
+	}
+
+	for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGKILL} {
+		processes, err := s.Processes()
+		if err != nil {
+			return err
+		}
+		for _, p := range processes {
+			if err := syscall.Kill(p.PID, sig); err != nil && err != syscall.ESRCH {
+				return fmt.Errorf("failed to send %s to %s (%d): %w", sig, p.Name, p.PID, err)
+			}
+		}
+		if s.WaitForExit(opts.Terminate) == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed to stop Steam: processes still running")
+}
+
+// WaitForExit waits for all of this installation's processes to exit, with a
+// timeout.
+func (s *Steam) WaitForExit(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if !s.IsRunning() {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for Steam to exit")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// ownsProcess checks if p runs an executable from this installation.
+func (s *Steam) ownsProcess(p Process) bool {
+	return len(p.Cmdline) > 0 && isWithin(p.Cmdline[0], s.Path)
+}
+
+// readPIDFile returns the PID in steam.pid.
+func (s *Steam) readPIDFile() (int, error) {
+	data, err := os.ReadFile(s.PIDFilePath())
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid steam.pid: %w", err)
+	}
+	return pid, nil
+}
+
+// listProcesses reads the current user's processes from ProcRoot. Processes
+// of other users, whose Steam isn't ours to stop, and processes that exit
+// while being read are skipped.
+func listProcesses() ([]Process, error) {
+	entries, err := os.ReadDir(ProcRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ProcRoot, err)
+	}
+
+	uid := uint32(os.Getuid())
+	var processes []Process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		if owner, ok := processOwner(pid); !ok || owner != uid {
+			continue
+		}
+		p, err := readProcess(pid)
+		if err != nil {
+			continue
+		}
+		processes = append(processes, *p)
+	}
+	return processes, nil
+}
+
+// processOwner returns the effective UID of a process, which owns its
+// ProcRoot/<pid> directory.
+func processOwner(pid int) (uint32, bool) {
+	info, err := os.Stat(filepath.Join(ProcRoot, strconv.Itoa(pid)))
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return stat.Uid, true
+}
+
+// readProcess reads a process's name, parent and command line from
+// ProcRoot/<pid>.
+func readProcess(pid int) (*Process, error) {
+	dir := filepath.Join(ProcRoot, strconv.Itoa(pid))
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The name is in parentheses and may itself contain spaces and parentheses:
+	// "1234 (steam) S 1 ..."
+	data := string(stat)
+	open, end := strings.Index(data, "("), strings.LastIndex(data, ")")
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("invalid stat for process %d", pid)
+	}
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid stat for process %d", pid)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat for process %d: %w", pid, err)
+	}
+
+	p := &Process{PID: pid, PPID: ppid, Name: data[open+1 : end], Type: InstallNative}
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		p.Cmdline = strings.FieldsFunc(string(cmdline), func(r rune) bool { return r == 0 })
+	}
+
+	// Processes in the Flatpak sandbox see its metadata at their root
+	if fileExists(filepath.Join(dir, "root", ".flatpak-info")) {
+		p.Type = InstallFlatpak
+	} else if len(p.Cmdline) > 0 && isSnapPath(p.Cmdline[0]) {
+		p.Type = InstallSnap
+	} else if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil && isSnapPath(exe) {
+		p.Type = InstallSnap
+	}
+	return p, nil
+}
+
+// isSnapPath checks if path is inside the Steam snap or its home directory.
+func isSnapPath(path string) bool {
+	if isWithin(path, "/snap/steam") {
+		return true
+	}
+	home, err := os.UserHomeDir()
+	return err == nil && isWithin(path, filepath.Join(home, filepath.Dir(SnapDataPath)))
+}
+
+// fileExists checks if a file exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,161 @@
+package steam_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+// writeProc creates a fake /proc entry for a process.
+func writeProc(procRoot string, pid, ppid int, name string, cmdline ...string) string {
+	dir := filepath.Join(procRoot, fmt.Sprintf("%d", pid))
+	Expect(os.MkdirAll(filepath.Join(dir, "root"), 0755)).To(Succeed())
+	stat := fmt.Sprintf("%d (%s) S %d %d 0 0 -1\n", pid, name, ppid, pid)
+	Expect(os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.Join(cmdline, "\x00")+"\x00"), 0644)).To(Succeed())
+	return dir
+}
+
+var _ = Describe("Process", func() {
+	var (
+		tmpDir           string
+		home             string
+		procRoot         string
+		originalHome     string
+		originalProcRoot string
+		native           *steam.Steam
+		flatpak          *steam.Steam
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "process-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		home = filepath.Join(tmpDir, "home")
+		procRoot = filepath.Join(tmpDir, "proc")
+		Expect(os.MkdirAll(procRoot, 0755)).To(Succeed())
+
+		originalHome = os.Getenv("HOME")
+		originalProcRoot = steam.ProcRoot
+		_ = os.Setenv("HOME", home)
+		steam.ProcRoot = procRoot
+
+		native = &steam.Steam{Path: filepath.Join(home, ".local", "share", "Steam"), Type: steam.InstallNative}
+		flatpak = &steam.Steam{Path: filepath.Join(home, steam.FlatpakDataPath, ".local", "share", "Steam"), Type: steam.InstallFlatpak}
+	})
+
+	AfterEach(func() {
+		_ = os.Setenv("HOME", originalHome)
+		steam.ProcRoot = originalProcRoot
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("should not be running without Steam processes", func() {
+		writeProc(procRoot, 1, 0, "systemd", "/sbin/init")
+		writeProc(procRoot, 200, 1, "bash", "/bin/bash")
+
+		Expect(native.IsRunning()).To(BeFalse())
+		_, err := native.GetPID()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should find the client, its helpers and their children", func() {
+		writeProc(procRoot, 1, 0, "systemd", "/sbin/init")
+		writeProc(procRoot, 100, 1, "steam.sh", "/bin/bash", native.Path+"/steam.sh")
+		writeProc(procRoot, 101, 100, "steam", native.Path+"/ubuntu12_32/steam")
+		writeProc(procRoot, 102, 101, "steamwebhelper", native.Path+"/ubuntu12_64/steamwebhelper")
+		writeProc(procRoot, 103, 101, "reaper", native.Path+"/ubuntu12_32/reaper")
+		writeProc(procRoot, 104, 103, "Game.exe", "Z:\\Game.exe")
+		writeProc(procRoot, 200, 1, "bash", "/bin/bash")
+
+		processes, err := native.Processes()
+		Expect(err).NotTo(HaveOccurred())
+		var pids []int
+		for _, p := range processes {
+			pids = append(pids, p.PID)
+		}
+		Expect(pids).To(Equal([]int{100, 101, 102, 103, 104}))
+		Expect(native.IsRunning()).To(BeTrue())
+		Expect(native.GetPID()).To(Equal(101))
+	})
+
+	It("should parse names containing spaces and parentheses", func() {
+		writeProc(procRoot, 300, 1, "steam) (x", "/usr/bin/steam")
+
+		Expect(native.IsRunning()).To(BeFalse())
+	})
+
+	It("should prefer the PID in steam.pid", func() {
+		writeProc(procRoot, 101, 1, "steam", native.Path+"/ubuntu12_32/steam")
+		writeProc(procRoot, 150, 101, "steam", native.Path+"/ubuntu12_32/steam")
+		Expect(os.MkdirAll(filepath.Join(home, ".steam"), 0755)).To(Succeed())
+		Expect(os.WriteFile(native.PIDFilePath(), []byte("150\n"), 0644)).To(Succeed())
+
+		Expect(native.GetPID()).To(Equal(150))
+	})
+
+	It("should follow steam.pid to a client started under another name", func() {
+		writeProc(procRoot, 400, 1, "steam-runtime", native.Path+"/ubuntu12_32/steam-runtime")
+		writeProc(procRoot, 401, 400, "wineserver", "wineserver")
+		Expect(os.MkdirAll(filepath.Join(home, ".steam"), 0755)).To(Succeed())
+		Expect(os.WriteFile(native.PIDFilePath(), []byte("400"), 0644)).To(Succeed())
+
+		processes, err := native.Processes()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(processes).To(HaveLen(2))
+	})
+
+	It("should ignore a stale steam.pid naming an unrelated process", func() {
+		writeProc(procRoot, 500, 1, "firefox", "/usr/lib/firefox/firefox")
+		Expect(os.MkdirAll(filepath.Join(home, ".steam"), 0755)).To(Succeed())
+		Expect(os.WriteFile(native.PIDFilePath(), []byte("500"), 0644)).To(Succeed())
+
+		Expect(native.IsRunning()).To(BeFalse())
+	})
+
+	It("should ignore other users' processes", func() {
+		if os.Getuid() != 0 {
+			Skip("Changing ownership requires root")
+		}
+		writeProc(procRoot, 101, 1, "steam", native.Path+"/ubuntu12_32/steam")
+		other := writeProc(procRoot, 700, 1, "steam", "/home/other/.local/share/Steam/ubuntu12_32/steam")
+		Expect(os.Chown(other, 65534, 65534)).To(Succeed())
+
+		processes, err := native.Processes()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(processes).To(HaveLen(1))
+		Expect(processes[0].PID).To(Equal(101))
+	})
+
+	It("should tell Flatpak processes from native ones", func() {
+		dir := writeProc(procRoot, 600, 1, "steam-wrapper", "/usr/bin/python3", "/app/bin/steam-wrapper")
+		Expect(os.WriteFile(filepath.Join(dir, "root", ".flatpak-info"), []byte("[Application]\n"), 0644)).To(Succeed())
+		dir = writeProc(procRoot, 601, 600, "steam", "/home/user/.local/share/Steam/ubuntu12_32/steam")
+		Expect(os.WriteFile(filepath.Join(dir, "root", ".flatpak-info"), []byte("[Application]\n"), 0644)).To(Succeed())
+
+		Expect(flatpak.IsRunning()).To(BeTrue())
+		Expect(flatpak.GetPID()).To(Equal(601))
+		Expect(native.IsRunning()).To(BeFalse())
+		Expect(flatpak.PIDFilePath()).To(Equal(filepath.Join(home, steam.FlatpakDataPath, ".steam", "steam.pid")))
+	})
+
+	It("should return immediately when Steam is not running", func() {
+		Expect(native.WaitForExit(time.Second)).To(Succeed())
+		Expect(native.Shutdown(steam.DefaultShutdownOptions())).To(Succeed())
+	})
+
+	It("should time out while Steam keeps running", func() {
+		writeProc(procRoot, 101, 1, "steam", native.Path+"/ubuntu12_32/steam")
+
+		err := native.WaitForExit(10 * time.Millisecond)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("timeout"))
+	})
+})
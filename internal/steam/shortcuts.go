@@ -3,11 +3,12 @@ package steam
 import (
 	"crypto/rand"
 	"fmt"
+	"hash/crc32"
+	"maps"
 	"math/big"
-	"os"
 	"path/filepath"
-
-	"github.com/jslay88/vdf"
+	"sort"
+	"strconv"
 )
 
 // Shortcut represents a non-Steam game shortcut.
@@ -29,6 +30,9 @@ type Shortcut struct {
 	LastPlayTime        uint32
 	FlatpakAppID        string
 	Tags                map[string]string
+
+	// raw is the entry the shortcut was read from, if any
+	raw *binaryNode
 }
 
 // NewShortcut creates a new shortcut with default values.
@@ -55,6 +59,19 @@ func NewShortcut(appName, exePath string) *Shortcut {
 	}
 }
 
+// ShortcutAppID returns the AppID Steam assigns to a non-Steam shortcut: the
+// CRC32 of its Exe, as written in shortcuts.vdf, and name, with the high bit
+// set. Artwork, steam:// links and tools like Steam ROM Manager rely on it.
+func ShortcutAppID(exe, appName string) uint32 {
+	return crc32.ChecksumIEEE([]byte(exe+appName)) | 0x80000000
+}
+
+// HasSteamAppID checks if the shortcut's AppID is the one Steam derives from
+// its Exe and name.
+func (s *Shortcut) HasSteamAppID() bool {
+	return s.AppID == ShortcutAppID(s.Exe, s.AppName)
+}
+
 // GenerateAppID generates a random AppID in the non-Steam game range.
 // Steam uses the range 0xFF000000 to 0xFFFFFFFF for non-Steam games.
 func GenerateAppID() (uint32, error) {
@@ -71,167 +88,173 @@ func GenerateAppID() (uint32, error) {
 	return minID + uint32(n.Int64()), nil
 }
 
-// ReadShortcuts reads shortcuts from a shortcuts.vdf file.
+// ReadShortcuts reads shortcuts from a shortcuts.vdf file, in file order.
 func ReadShortcuts(path string) ([]Shortcut, error) {
-	data, err := os.ReadFile(path)
+	shortcuts, _, err := readShortcuts(path)
+	return shortcuts, err
+}
+
+// readShortcuts reads shortcuts and the state of the file, for writing them
+// back with writeShortcuts.
+func readShortcuts(path string) ([]Shortcut, *FileState, error) {
+	data, state, err := ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []Shortcut{}, nil
-		}
-		return nil, fmt.Errorf("failed to read shortcuts file: %w", err)
+		return nil, nil, fmt.Errorf("failed to read shortcuts file: %w", err)
 	}
 
 	if len(data) == 0 {
-		return []Shortcut{}, nil
+		return []Shortcut{}, state, nil
 	}
 
-	vdfMap, err := vdf.ReadBinary(data)
+	root, err := parseBinaryVDF(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse shortcuts VDF: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse shortcuts VDF: %w", err)
 	}
 
-	shortcutsObj, ok := vdfMap["shortcuts"]
-	if !ok {
-		return []Shortcut{}, nil
+	shortcutsNode := root.child("shortcuts")
+	if shortcutsNode == nil || shortcutsNode.typ != binaryMap {
+		return []Shortcut{}, state, nil
 	}
 
-	shortcutsMap, ok := shortcutsObj.(vdf.Map)
-	if !ok {
-		return []Shortcut{}, nil
-	}
-
-	var shortcuts []Shortcut
-	for _, v := range shortcutsMap {
-		entryMap, ok := v.(vdf.Map)
-		if !ok {
+	shortcuts := []Shortcut{}
+	for _, entry := range shortcutsNode.children {
+		if entry.typ != binaryMap {
 			continue
 		}
-
-		shortcut := parseShortcutEntry(entryMap)
-		shortcuts = append(shortcuts, shortcut)
+		shortcuts = append(shortcuts, parseShortcutEntry(entry))
 	}
 
-	return shortcuts, nil
+	return shortcuts, state, nil
 }
 
-// parseShortcutEntry parses a single shortcut entry from a VDF map.
-func parseShortcutEntry(m vdf.Map) Shortcut {
+// parseShortcutEntry parses a single shortcut entry. The entry is kept so
+// that keys Shortcut has no field for survive a rewrite.
+func parseShortcutEntry(n *binaryNode) Shortcut {
 	s := Shortcut{
 		Tags: make(map[string]string),
-	}
+		raw:  n,
+	}
+
+	s.AppID, _ = n.getUint32("appid")
+	s.AppName, _ = n.getString("AppName")
+	s.Exe, _ = n.getString("Exe")
+	s.StartDir, _ = n.getString("StartDir")
+	s.Icon, _ = n.getString("icon")
+	s.ShortcutPath, _ = n.getString("ShortcutPath")
+	s.LaunchOptions, _ = n.getString("LaunchOptions")
+	s.IsHidden, _ = n.getUint32("IsHidden")
+	s.AllowDesktopConfig, _ = n.getUint32("AllowDesktopConfig")
+	s.AllowOverlay, _ = n.getUint32("AllowOverlay")
+	s.OpenVR, _ = n.getUint32("OpenVR")
+	s.Devkit, _ = n.getUint32("Devkit")
+	s.DevkitGameID, _ = n.getString("DevkitGameID")
+	s.DevkitOverrideAppID, _ = n.getUint32("DevkitOverrideAppID")
+	s.LastPlayTime, _ = n.getUint32("LastPlayTime")
+	s.FlatpakAppID, _ = n.getString("FlatpakAppID")
+	s.Tags = parseTags(n)
 
-	if v, ok := m["appid"].(uint32); ok {
-		s.AppID = v
-	}
-	if v, ok := m["AppName"].(string); ok {
-		s.AppName = v
-	}
-	if v, ok := m["Exe"].(string); ok {
-		s.Exe = v
-	}
-	if v, ok := m["StartDir"].(string); ok {
-		s.StartDir = v
-	}
-	if v, ok := m["icon"].(string); ok {
-		s.Icon = v
-	}
-	if v, ok := m["ShortcutPath"].(string); ok {
-		s.ShortcutPath = v
-	}
-	if v, ok := m["LaunchOptions"].(string); ok {
-		s.LaunchOptions = v
-	}
-	if v, ok := m["IsHidden"].(uint32); ok {
-		s.IsHidden = v
-	}
-	if v, ok := m["AllowDesktopConfig"].(uint32); ok {
-		s.AllowDesktopConfig = v
-	}
-	if v, ok := m["AllowOverlay"].(uint32); ok {
-		s.AllowOverlay = v
-	}
-	if v, ok := m["OpenVR"].(uint32); ok {
-		s.OpenVR = v
-	}
-	if v, ok := m["Devkit"].(uint32); ok {
-		s.Devkit = v
-	}
-	if v, ok := m["DevkitGameID"].(string); ok {
-		s.DevkitGameID = v
-	}
-	if v, ok := m["DevkitOverrideAppID"].(uint32); ok {
-		s.DevkitOverrideAppID = v
-	}
-	if v, ok := m["LastPlayTime"].(uint32); ok {
-		s.LastPlayTime = v
-	}
-	if v, ok := m["FlatpakAppID"].(string); ok {
-		s.FlatpakAppID = v
-	}
-	if tags, ok := m["tags"].(vdf.Map); ok {
-		for k, v := range tags {
-			if str, ok := v.(string); ok {
-				s.Tags[k] = str
+	return s
+}
+
+// parseTags returns the string entries of a shortcut's tags.
+func parseTags(n *binaryNode) map[string]string {
+	tags := make(map[string]string)
+	if node := n.child("tags"); node != nil && node.typ == binaryMap {
+		for _, tag := range node.children {
+			if tag.typ == binaryString {
+				tags[tag.key] = string(tag.value)
 			}
 		}
 	}
-
-	return s
+	return tags
 }
 
-// toVDFMap converts a Shortcut to a VDF map.
-func (s *Shortcut) toVDFMap() vdf.Map {
-	m := vdf.Map{
-		"appid":               s.AppID,
-		"AppName":             s.AppName,
-		"Exe":                 s.Exe,
-		"StartDir":            s.StartDir,
-		"icon":                s.Icon,
-		"ShortcutPath":        s.ShortcutPath,
-		"LaunchOptions":       s.LaunchOptions,
-		"IsHidden":            s.IsHidden,
-		"AllowDesktopConfig":  s.AllowDesktopConfig,
-		"AllowOverlay":        s.AllowOverlay,
-		"OpenVR":              s.OpenVR,
-		"Devkit":              s.Devkit,
-		"DevkitGameID":        s.DevkitGameID,
-		"DevkitOverrideAppID": s.DevkitOverrideAppID,
-		"LastPlayTime":        s.LastPlayTime,
-		"FlatpakAppID":        s.FlatpakAppID,
+// toBinaryNode converts a Shortcut to a shortcuts.vdf entry. The entry it was
+// read from is updated in place, keeping its key order, key case and any
+// unknown keys.
+func (s *Shortcut) toBinaryNode(key string) *binaryNode {
+	n := newBinaryMap(key)
+	if s.raw != nil {
+		n = s.raw.clone()
+		n.key = key
 	}
 
-	tags := vdf.Map{}
-	for k, v := range s.Tags {
-		tags[k] = v
+	setString := func(key, value string) {
+		// Keys missing from a read entry are only added when needed
+		if s.raw == nil || value != "" || n.child(key) != nil {
+			n.setString(key, value)
+		}
+	}
+	setUint32 := func(key string, value uint32) {
+		if s.raw == nil || value != 0 || n.child(key) != nil {
+			n.setUint32(key, value)
+		}
 	}
-	m["tags"] = tags
 
-	return m
+	setUint32("appid", s.AppID)
+	setString("AppName", s.AppName)
+	setString("Exe", s.Exe)
+	setString("StartDir", s.StartDir)
+	setString("icon", s.Icon)
+	setString("ShortcutPath", s.ShortcutPath)
+	setString("LaunchOptions", s.LaunchOptions)
+	setUint32("IsHidden", s.IsHidden)
+	setUint32("AllowDesktopConfig", s.AllowDesktopConfig)
+	setUint32("AllowOverlay", s.AllowOverlay)
+	setUint32("OpenVR", s.OpenVR)
+	setUint32("Devkit", s.Devkit)
+	setString("DevkitGameID", s.DevkitGameID)
+	setUint32("DevkitOverrideAppID", s.DevkitOverrideAppID)
+	setUint32("LastPlayTime", s.LastPlayTime)
+	setString("FlatpakAppID", s.FlatpakAppID)
+
+	// Tags are only rebuilt if they changed, as the map loses their order
+	if s.raw == nil || !maps.Equal(parseTags(s.raw), s.Tags) {
+		tags := newBinaryMap("tags")
+		keys := make([]string, 0, len(s.Tags))
+		for k := range s.Tags {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return tagLess(keys[i], keys[j]) })
+		for _, k := range keys {
+			tags.setString(k, s.Tags[k])
+		}
+		n.setMap("tags", tags)
+	}
+
+	return n
 }
 
-// WriteShortcuts writes shortcuts to a shortcuts.vdf file.
-func WriteShortcuts(path string, shortcuts []Shortcut) error {
-	shortcutsMap := vdf.Map{}
-	for i, s := range shortcuts {
-		shortcutsMap[fmt.Sprintf("%d", i)] = s.toVDFMap()
+// tagLess orders tag keys, which are indices, numerically.
+func tagLess(a, b string) bool {
+	ia, errA := strconv.Atoi(a)
+	ib, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return ia < ib
 	}
+	return a < b
+}
 
-	vdfMap := vdf.Map{
-		"shortcuts": shortcutsMap,
-	}
+// WriteShortcuts writes shortcuts to a shortcuts.vdf file, numbering the
+// entries in order. Shortcuts read by ReadShortcuts are written back with
+// all of their original keys. The file is replaced atomically, see
+// WriteFileAtomic.
+func WriteShortcuts(path string, shortcuts []Shortcut) error {
+	return writeShortcuts(path, shortcuts, nil)
+}
 
-	data, err := vdf.WriteBinary(vdfMap)
-	if err != nil {
-		return fmt.Errorf("failed to write shortcuts VDF: %w", err)
+// writeShortcuts writes shortcuts, aborting with ErrFileChanged if the file
+// changed since state was recorded.
+func writeShortcuts(path string, shortcuts []Shortcut, state *FileState) error {
+	shortcutsNode := newBinaryMap("shortcuts")
+	for i := range shortcuts {
+		shortcutsNode.children = append(shortcutsNode.children, shortcuts[i].toBinaryNode(strconv.Itoa(i)))
 	}
 
-	// Ensure directory exists
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
+	root := newBinaryMap("")
+	root.children = []*binaryNode{shortcutsNode}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := WriteFileAtomic(path, root.encode(), state); err != nil {
 		return fmt.Errorf("failed to write shortcuts file: %w", err)
 	}
 
@@ -242,7 +265,7 @@ func WriteShortcuts(path string, shortcuts []Shortcut) error {
 // If a shortcut with the same name already exists, it returns the existing AppID.
 // Returns the AppID and a boolean indicating if it was newly created.
 func AddShortcut(user *User, shortcut *Shortcut) (uint32, bool, error) {
-	shortcuts, err := ReadShortcuts(user.ShortcutsPath())
+	shortcuts, state, err := readShortcuts(user.ShortcutsPath())
 	if err != nil {
 		return 0, false, err
 	}
@@ -255,12 +278,10 @@ func AddShortcut(user *User, shortcut *Shortcut) (uint32, bool, error) {
 		}
 	}
 
-	// Generate AppID if not set
+	// Use Steam's AppID if not set, or a random one in the unlikely case it
+	// conflicts with another shortcut
 	if shortcut.AppID == 0 {
-		appID, err := GenerateAppID()
-		if err != nil {
-			return 0, false, err
-		}
+		appID := ShortcutAppID(shortcut.Exe, shortcut.AppName)
 
 		// Make sure it doesn't conflict with existing shortcuts
 		for {
@@ -285,7 +306,7 @@ func AddShortcut(user *User, shortcut *Shortcut) (uint32, bool, error) {
 
 	shortcuts = append(shortcuts, *shortcut)
 
-	if err := WriteShortcuts(user.ShortcutsPath(), shortcuts); err != nil {
+	if err := writeShortcuts(user.ShortcutsPath(), shortcuts, state); err != nil {
 		return 0, false, err
 	}
 
@@ -295,7 +316,7 @@ func AddShortcut(user *User, shortcut *Shortcut) (uint32, bool, error) {
 // UpdateShortcut updates an existing shortcut by AppID.
 // If the shortcut doesn't exist, it returns an error.
 func UpdateShortcut(user *User, shortcut *Shortcut) error {
-	shortcuts, err := ReadShortcuts(user.ShortcutsPath())
+	shortcuts, state, err := readShortcuts(user.ShortcutsPath())
 	if err != nil {
 		return err
 	}
@@ -313,7 +334,31 @@ func UpdateShortcut(user *User, shortcut *Shortcut) error {
 		return fmt.Errorf("shortcut with AppID %d not found", shortcut.AppID)
 	}
 
-	return WriteShortcuts(user.ShortcutsPath(), shortcuts)
+	return writeShortcuts(user.ShortcutsPath(), shortcuts, state)
+}
+
+// ChangeShortcutAppID changes the AppID of the shortcut with oldID.
+func ChangeShortcutAppID(user *User, oldID, newID uint32) error {
+	shortcuts, state, err := readShortcuts(user.ShortcutsPath())
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range shortcuts {
+		switch shortcuts[i].AppID {
+		case newID:
+			return fmt.Errorf("a shortcut with AppID %d already exists", newID)
+		case oldID:
+			shortcuts[i].AppID = newID
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("shortcut with AppID %d not found", oldID)
+	}
+
+	return writeShortcuts(user.ShortcutsPath(), shortcuts, state)
 }
 
 // FindShortcutByName finds a shortcut by app name.
@@ -334,7 +379,7 @@ func FindShortcutByName(user *User, appName string) (*Shortcut, error) {
 
 // RemoveShortcut removes a shortcut by AppID.
 func RemoveShortcut(user *User, appID uint32) error {
-	shortcuts, err := ReadShortcuts(user.ShortcutsPath())
+	shortcuts, state, err := readShortcuts(user.ShortcutsPath())
 	if err != nil {
 		return err
 	}
@@ -346,5 +391,5 @@ func RemoveShortcut(user *User, appID uint32) error {
 		}
 	}
 
-	return WriteShortcuts(user.ShortcutsPath(), filtered)
+	return writeShortcuts(user.ShortcutsPath(), filtered, state)
 }
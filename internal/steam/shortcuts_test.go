@@ -68,6 +68,54 @@ var _ = Describe("Shortcuts", func() {
 		})
 	})
 
+	Describe("ShortcutAppID", func() {
+		It("should match Steam's CRC32-based AppID", func() {
+			appID := steam.ShortcutAppID(`"/games/ZLADXHD/Link's Awakening DX HD.exe"`, "Zelda: Link's Awakening DX HD")
+			Expect(appID).To(Equal(uint32(3737720550)))
+			Expect(steam.ShortAppID(steam.ShortcutGameID(appID))).To(Equal(appID))
+		})
+
+		It("should always set the high bit", func() {
+			Expect(steam.ShortcutAppID("", "")).To(BeNumerically(">=", uint32(0x80000000)))
+		})
+
+		It("should tell Steam's AppIDs from random ones", func() {
+			shortcut := steam.NewShortcut("Game", "/path/to/game.exe")
+			shortcut.AppID = 0xFF000001
+			Expect(shortcut.HasSteamAppID()).To(BeFalse())
+			shortcut.AppID = steam.ShortcutAppID(shortcut.Exe, shortcut.AppName)
+			Expect(shortcut.HasSteamAppID()).To(BeTrue())
+		})
+	})
+
+	Describe("ChangeShortcutAppID", func() {
+		var mockUser *steam.User
+
+		BeforeEach(func() {
+			mockUser = &steam.User{ID: "12345", ConfigPath: filepath.Join(tmpDir, "config")}
+			Expect(steam.WriteShortcuts(mockUser.ShortcutsPath(), []steam.Shortcut{
+				{AppID: 0xFF000001, AppName: "Game 1", Tags: map[string]string{}},
+				{AppID: 0xFF000002, AppName: "Game 2", Tags: map[string]string{}},
+			})).To(Succeed())
+		})
+
+		It("should change the AppID", func() {
+			Expect(steam.ChangeShortcutAppID(mockUser, 0xFF000001, 0x80000001)).To(Succeed())
+
+			shortcut, err := steam.FindShortcutByName(mockUser, "Game 1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(shortcut.AppID).To(Equal(uint32(0x80000001)))
+		})
+
+		It("should refuse to take another shortcut's AppID", func() {
+			Expect(steam.ChangeShortcutAppID(mockUser, 0xFF000001, 0xFF000002)).NotTo(Succeed())
+		})
+
+		It("should fail for an unknown AppID", func() {
+			Expect(steam.ChangeShortcutAppID(mockUser, 0xFF000003, 0x80000001)).NotTo(Succeed())
+		})
+	})
+
 	Describe("ReadShortcuts", func() {
 		It("should return empty slice for non-existent file", func() {
 			shortcuts, err := steam.ReadShortcuts(filepath.Join(tmpDir, "nonexistent.vdf"))
@@ -153,7 +201,7 @@ var _ = Describe("Shortcuts", func() {
 			appID, isNew, err := steam.AddShortcut(mockUser, shortcut)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(isNew).To(BeTrue())
-			Expect(appID).To(BeNumerically(">=", uint32(0xFF000000)))
+			Expect(appID).To(Equal(steam.ShortcutAppID(`"/path/to/game.exe"`, "New Game")))
 
 			// Verify it was added
 			shortcuts, err := steam.ReadShortcuts(mockUser.ShortcutsPath())
@@ -318,6 +366,143 @@ var _ = Describe("Shortcuts", func() {
 		})
 	})
 
+	Describe("Golden files", func() {
+		var mockUser *steam.User
+
+		// useSample copies a testdata shortcuts.vdf to the user's config.
+		useSample := func(name string) {
+			data, err := os.ReadFile(filepath.Join("testdata", "shortcuts", name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(mockUser.ShortcutsPath(), data, 0644)).To(Succeed())
+		}
+
+		// expectGolden checks the user's shortcuts.vdf against a testdata file.
+		expectGolden := func(name string) {
+			want, err := os.ReadFile(filepath.Join("testdata", "shortcuts", name))
+			Expect(err).NotTo(HaveOccurred())
+			got, err := os.ReadFile(mockUser.ShortcutsPath())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(want), "shortcuts.vdf differs from %s", name)
+		}
+
+		BeforeEach(func() {
+			configPath := filepath.Join(tmpDir, "golden")
+			Expect(os.MkdirAll(configPath, 0755)).To(Succeed())
+			mockUser = &steam.User{ID: "12345", ConfigPath: configPath}
+		})
+
+		DescribeTable("should write unchanged shortcuts back byte for byte",
+			func(name string) {
+				useSample(name)
+				shortcuts, err := steam.ReadShortcuts(mockUser.ShortcutsPath())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(steam.WriteShortcuts(mockUser.ShortcutsPath(), shortcuts)).To(Succeed())
+				expectGolden(name)
+			},
+			Entry("current Steam client", "steam.vdf"),
+			Entry("every key the client writes", "client.vdf"),
+			Entry("old Steam client with unknown value types", "legacy.vdf"),
+			Entry("Steam ROM Manager", "srm.vdf"),
+		)
+
+		It("should read shortcuts in file order", func() {
+			useSample("steam.vdf")
+			shortcuts, err := steam.ReadShortcuts(mockUser.ShortcutsPath())
+			Expect(err).NotTo(HaveOccurred())
+			var names []string
+			for _, s := range shortcuts {
+				names = append(names, s.AppName)
+			}
+			Expect(names).To(Equal([]string{"RetroArch", "Heroic Games Launcher", "セレステ"}))
+			Expect(shortcuts[1].FlatpakAppID).To(Equal("com.heroicgameslauncher.hgl"))
+			Expect(shortcuts[0].Tags).To(Equal(map[string]string{"0": "Emulators", "1": "favorite"}))
+		})
+
+		// client.vdf has every key the Steam client writes when adding a
+		// non-Steam game, laid out and typed as the client writes them. The
+		// names and paths are placeholders.
+		It("should read every key the client writes", func() {
+			useSample("client.vdf")
+			shortcuts, err := steam.ReadShortcuts(mockUser.ShortcutsPath())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(shortcuts).To(HaveLen(3))
+			for _, s := range shortcuts {
+				Expect(s.HasSteamAppID()).To(BeTrue(), "%s has a random AppID", s.AppName)
+			}
+
+			Expect(shortcuts[0].AppName).To(Equal("Firefox"))
+			Expect(shortcuts[0].ShortcutPath).To(Equal("/usr/share/applications/firefox.desktop"))
+			Expect(shortcuts[0].Tags).To(BeEmpty())
+
+			game := shortcuts[1]
+			Expect(game.Exe).To(Equal(`"/home/user/Games/Hollow Knight/hollow_knight.exe"`))
+			Expect(game.StartDir).To(Equal(`"/home/user/Games/Hollow Knight/"`))
+			Expect(game.LaunchOptions).To(Equal("PROTON_USE_WINED3D=1 %command%"))
+			Expect(game.AllowDesktopConfig).To(Equal(uint32(1)))
+			Expect(game.LastPlayTime).To(Equal(uint32(1728000000)))
+			Expect(game.Tags).To(Equal(map[string]string{"0": "favorite", "1": "Metroidvania"}))
+
+			Expect(shortcuts[2].IsHidden).To(Equal(uint32(1)))
+			Expect(shortcuts[2].AllowOverlay).To(BeZero())
+			Expect(shortcuts[2].FlatpakAppID).To(Equal("com.discordapp.Discord"))
+		})
+
+		It("should keep every key when updating a shortcut", func() {
+			useSample("client.vdf")
+			shortcut, err := steam.FindShortcutByName(mockUser, "Hollow Knight")
+			Expect(err).NotTo(HaveOccurred())
+			shortcut.LaunchOptions = "%command% -windowed"
+			Expect(steam.UpdateShortcut(mockUser, shortcut)).To(Succeed())
+			shortcut.LaunchOptions = "PROTON_USE_WINED3D=1 %command%"
+			Expect(steam.UpdateShortcut(mockUser, shortcut)).To(Succeed())
+			expectGolden("client.vdf")
+		})
+
+		It("should read keys written in lowercase by old clients", func() {
+			useSample("legacy.vdf")
+			shortcuts, err := steam.ReadShortcuts(mockUser.ShortcutsPath())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(shortcuts).To(HaveLen(2))
+			Expect(shortcuts[0].AppName).To(Equal("Minecraft"))
+			Expect(shortcuts[0].Exe).To(Equal(`"/usr/bin/minecraft-launcher"`))
+			Expect(shortcuts[0].LastPlayTime).To(Equal(uint32(1500000000)))
+		})
+
+		It("should keep other shortcuts intact when adding one", func() {
+			useSample("steam.vdf")
+			shortcut := steam.NewShortcut("Zelda: Link's Awakening DX HD", "/games/ZLADXHD/Link's Awakening DX HD.exe")
+			_, isNew, err := steam.AddShortcut(mockUser, shortcut)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isNew).To(BeTrue())
+			expectGolden("steam.added.vdf")
+		})
+
+		It("should renumber the remaining shortcuts when removing one", func() {
+			useSample("steam.vdf")
+			shortcut, err := steam.FindShortcutByName(mockUser, "Heroic Games Launcher")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(steam.RemoveShortcut(mockUser, shortcut.AppID)).To(Succeed())
+			expectGolden("steam.removed.vdf")
+		})
+
+		It("should only change the updated field", func() {
+			useSample("srm.vdf")
+			shortcut, err := steam.FindShortcutByName(mockUser, "Pokémon Emerald")
+			Expect(err).NotTo(HaveOccurred())
+			shortcut.LaunchOptions = "--fullscreen"
+			Expect(steam.UpdateShortcut(mockUser, shortcut)).To(Succeed())
+			expectGolden("srm.updated.vdf")
+		})
+
+		It("should reject truncated files", func() {
+			data, err := os.ReadFile(filepath.Join("testdata", "shortcuts", "steam.vdf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(mockUser.ShortcutsPath(), data[:len(data)/2], 0644)).To(Succeed())
+			_, err = steam.ReadShortcuts(mockUser.ShortcutsPath())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("Shortcut struct", func() {
 		Describe("toVDFMap", func() {
 			It("should convert shortcut to VDF map with all fields", func() {
@@ -5,12 +5,8 @@ package steam
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
-	"syscall"
-	"time"
 )
 
 // DefaultSteamPath is the default Steam installation path on Linux.
@@ -23,36 +19,75 @@ type Steam struct {
 	ConfigPath   string
 	AppsPath     string
 	CompatPath   string
+	// Type is how Steam was installed; paths and processes differ between them.
+	Type InstallType
 }
 
-// Discover finds the Steam installation on the system.
+// Discover finds the Steam installation on the system. If there are several,
+// the first one found is returned (see DiscoverAll).
 func Discover() (*Steam, error) {
-	steamPath, err := findSteamPath()
+	installs, err := DiscoverAll()
 	if err != nil {
 		return nil, err
 	}
+	return installs[0], nil
+}
+
+// DiscoverAll finds every valid Steam installation on the system: native,
+// Flatpak and Snap. STEAM_DIR, if set, is checked first.
+func DiscoverAll() ([]*Steam, error) {
+	paths, err := candidatePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var installs []*Steam
+	var verifyErr error
+	for _, path := range paths {
+		steamPath, ok := resolveSteamPath(path)
+		if !ok || seen[steamPath] {
+			continue
+		}
+		seen[steamPath] = true
+
+		s := newSteam(steamPath)
+		// Verify the installation
+		if err := s.verify(); err != nil {
+			if verifyErr == nil {
+				verifyErr = err
+			}
+			continue
+		}
+		installs = append(installs, s)
+	}
+
+	if len(installs) == 0 {
+		if verifyErr != nil {
+			return nil, verifyErr
+		}
+		return nil, fmt.Errorf("Steam installation not found. Checked paths: %v", paths)
+	}
+	return installs, nil
+}
 
-	s := &Steam{
+// newSteam returns the Steam installation at steamPath.
+func newSteam(steamPath string) *Steam {
+	return &Steam{
 		Path:         steamPath,
 		UserDataPath: filepath.Join(steamPath, "userdata"),
 		ConfigPath:   filepath.Join(steamPath, "config"),
 		AppsPath:     filepath.Join(steamPath, "steamapps"),
 		CompatPath:   filepath.Join(steamPath, "steamapps", "compatdata"),
+		Type:         installTypeOf(steamPath),
 	}
-
-	// Verify the installation
-	if err := s.verify(); err != nil {
-		return nil, err
-	}
-
-	return s, nil
 }
 
-// findSteamPath looks for the Steam installation directory.
-func findSteamPath() (string, error) {
+// candidatePaths returns the directories Steam may be installed in.
+func candidatePaths() ([]string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
 	// Check common Steam paths
@@ -74,25 +109,35 @@ func findSteamPath() (string, error) {
 	}
 	paths = append(paths, filepath.Join(dataHome, "Steam"))
 
-	for _, path := range paths {
-		// Resolve symlinks
-		resolved, err := filepath.EvalSymlinks(path)
-		if err != nil {
-			continue
-		}
+	// Sandboxed installs keep their data below the app's own home directory
+	paths = append(paths,
+		filepath.Join(home, FlatpakDataPath, DefaultSteamPath),
+		filepath.Join(home, FlatpakDataPath, "data", "Steam"),
+		filepath.Join(home, SnapDataPath, DefaultSteamPath),
+	)
 
-		if info, err := os.Stat(resolved); err == nil && info.IsDir() {
-			// Check for steam.sh or steamapps directory
-			if _, err := os.Stat(filepath.Join(resolved, "steamapps")); err == nil {
-				return resolved, nil
-			}
-			if _, err := os.Stat(filepath.Join(resolved, "steam.sh")); err == nil {
-				return resolved, nil
-			}
-		}
+	return paths, nil
+}
+
+// resolveSteamPath resolves symlinks in path and checks that it looks like a
+// Steam installation.
+func resolveSteamPath(path string) (string, bool) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
 	}
 
-	return "", fmt.Errorf("Steam installation not found. Checked paths: %v", paths)
+	if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+		return "", false
+	}
+	// Check for steam.sh or steamapps directory
+	if _, err := os.Stat(filepath.Join(resolved, "steamapps")); err == nil {
+		return resolved, true
+	}
+	if _, err := os.Stat(filepath.Join(resolved, "steam.sh")); err == nil {
+		return resolved, true
+	}
+	return "", false
 }
 
 // verify checks that the Steam installation is valid.
@@ -111,115 +156,72 @@ func (s *Steam) CommonPath() string {
 	return filepath.Join(s.AppsPath, "common")
 }
 
-// GetProtonVersions returns a list of installed Proton versions.
+// GetProtonVersions returns a list of installed Proton versions from all
+// mounted libraries, including Proton-based tools from compatibilitytools.d
+// listed by display name.
 func (s *Steam) GetProtonVersions() ([]string, error) {
-	commonPath := s.CommonPath()
-	entries, err := os.ReadDir(commonPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read common directory: %w", err)
-	}
-
 	var versions []string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+	for _, appsPath := range s.appsPaths() {
+		entries, err := os.ReadDir(filepath.Join(appsPath, "common"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read common directory: %w", err)
 		}
-		name := entry.Name()
-		// Check for Proton directories
-		if strings.HasPrefix(name, "Proton") || strings.HasPrefix(name, "GE-Proton") {
-			versions = append(versions, name)
-		}
-	}
 
-	return versions, nil
-}
-
-// GetProtonPath returns the path to a specific Proton version.
-func (s *Steam) GetProtonPath(version string) (string, error) {
-	protonPath := filepath.Join(s.CommonPath(), version)
-	if info, err := os.Stat(protonPath); err != nil || !info.IsDir() {
-		return "", fmt.Errorf("proton version not found: %s", version)
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			name := entry.Name()
+			// Check for Proton directories
+			if (strings.HasPrefix(name, "Proton") || strings.HasPrefix(name, "GE-Proton")) && !containsFold(versions, name) {
+				versions = append(versions, name)
+			}
+		}
 	}
-	return protonPath, nil
-}
 
-// IsRunning checks if Steam is currently running.
-func IsRunning() bool {
-	cmd := exec.Command("pgrep", "-x", "steam")
-	err := cmd.Run()
-	return err == nil
-}
-
-// GetPID returns the PID of the running Steam process.
-func GetPID() (int, error) {
-	cmd := exec.Command("pgrep", "-x", "steam")
-	output, err := cmd.Output()
+	tools, err := s.GetCompatTools()
 	if err != nil {
-		return 0, fmt.Errorf("Steam is not running")
-	}
-
-	pidStr := strings.TrimSpace(string(output))
-	// pgrep might return multiple PIDs, take the first one
-	if idx := strings.Index(pidStr, "\n"); idx != -1 {
-		pidStr = pidStr[:idx]
+		return nil, err
 	}
-
-	pid, err := strconv.Atoi(pidStr)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse PID: %w", err)
+	for _, tool := range tools {
+		if !tool.IsProton() || containsFold(versions, tool.Label()) {
+			continue
+		}
+		versions = append(versions, tool.Label())
 	}
 
-	return pid, nil
+	return versions, nil
 }
 
-// Kill terminates the Steam process gracefully, with fallback to SIGKILL.
-func Kill() error {
-	pid, err := GetPID()
-	if err != nil {
-		// Steam not running, nothing to do
-		return nil
+// GetProtonPath returns the path to a specific Proton version. Versions in a
+// library's steamapps/common are matched by directory name, custom tools by
+// internal or display name.
+func (s *Steam) GetProtonPath(version string) (string, error) {
+	for _, appsPath := range s.appsPaths() {
+		protonPath := filepath.Join(appsPath, "common", version)
+		if info, err := os.Stat(protonPath); err == nil && info.IsDir() {
+			return protonPath, nil
+		}
 	}
 
-	process, err := os.FindProcess(pid)
+	tool, err := s.FindCompatTool(version)
 	if err != nil {
-		return fmt.Errorf("failed to find process: %w", err)
+		return "", err
 	}
-
-	// Try SIGTERM first
-	if err := process.Signal(syscall.SIGTERM); err != nil {
-		return fmt.Errorf("failed to send SIGTERM: %w", err)
-	}
-
-	// Wait for process to exit (up to 10 seconds)
-	for i := 0; i < 20; i++ {
-		time.Sleep(500 * time.Millisecond)
-		if !IsRunning() {
-			return nil
+	if tool != nil {
+		if info, err := os.Stat(tool.Path); err == nil && info.IsDir() {
+			return tool.Path, nil
 		}
 	}
-
-	// If still running, use SIGKILL
-	if err := process.Signal(syscall.SIGKILL); err != nil {
-		return fmt.Errorf("failed to send SIGKILL: %w", err)
-	}
-
-	// Wait a bit more for the process to die
-	time.Sleep(1 * time.Second)
-	if IsRunning() {
-		return fmt.Errorf("failed to kill Steam process")
-	}
-
-	return nil
+	return "", fmt.Errorf("proton version not found: %s", version)
 }
 
-// WaitForExit waits for Steam to exit, with a timeout.
-func WaitForExit(timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		if !IsRunning() {
-			return nil
+// containsFold checks if list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
 		}
-		time.Sleep(500 * time.Millisecond)
 	}
-	return fmt.Errorf("timeout waiting for Steam to exit")
+	return false
 }
@@ -123,7 +123,7 @@ var _ = Describe("Steam", func() {
 		})
 	})
 
-	// Note: Discover(), IsRunning(), GetPID(), Kill(), WaitForExit() are hard to test
-	// They rely on actual file system paths and process management
+	// Note: IsRunning(), GetPID(), Kill(), WaitForExit() are hard to test
+	// They rely on process management
 	// These would typically be tested via integration tests
 })
@@ -140,6 +140,11 @@ func (u *User) LocalConfigPath() string {
 	return filepath.Join(u.ConfigPath, "localconfig.vdf")
 }
 
+// GridPath returns the path to the user's custom artwork directory.
+func (u *User) GridPath() string {
+	return filepath.Join(u.ConfigPath, "grid")
+}
+
 // HasShortcuts checks if the user has a shortcuts.vdf file.
 func (u *User) HasShortcuts() bool {
 	_, err := os.Stat(u.ShortcutsPath())
@@ -0,0 +1,292 @@
+package steam
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jslay88/vdf"
+)
+
+// BackupTimeFormat is the timestamp format of the backups WriteFileAtomic
+// keeps next to the files it replaces.
+const BackupTimeFormat = "20060102-150405"
+
+// FileBackupKeep is how many backups WriteFileAtomic keeps per file; older
+// ones are removed when a new one is made.
+var FileBackupKeep = 5
+
+// ErrFileChanged is returned by WriteFileAtomic when a file was modified
+// since it was read, e.g. because Steam rewrote it.
+var ErrFileChanged = errors.New("file was modified since it was read")
+
+// FileState records a file as it was read, so WriteFileAtomic can tell
+// whether something else changed it in the meantime.
+type FileState struct {
+	Path    string
+	Exists  bool
+	ModTime time.Time
+	Size    int64
+	Hash    [sha256.Size]byte
+}
+
+// ReadFile reads a file Steam owns, such as config.vdf or shortcuts.vdf, and
+// records its state. A missing file returns no data and a state with Exists
+// false.
+func ReadFile(path string) ([]byte, *FileState, error) {
+	state := &FileState{Path: path}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, state, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	state.Exists = true
+	state.ModTime = info.ModTime()
+	state.Size = info.Size()
+	state.Hash = sha256.Sum256(data)
+	return data, state, nil
+}
+
+// Changed checks if the file differs from when it was read. A file whose
+// modification time changed but whose contents didn't is not considered
+// changed.
+func (st *FileState) Changed() (bool, error) {
+	info, err := os.Stat(st.Path)
+	if os.IsNotExist(err) {
+		return st.Exists, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %s: %w", st.Path, err)
+	}
+	if !st.Exists {
+		return true, nil
+	}
+	if info.Size() != st.Size {
+		return true, nil
+	}
+	if info.ModTime().Equal(st.ModTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(st.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", st.Path, err)
+	}
+	return sha256.Sum256(data) != st.Hash, nil
+}
+
+// WriteFileAtomic replaces a file Steam owns without risking a truncated
+// file: data is written to a temporary file in the same directory, synced
+// to disk and renamed over path. The previous contents are kept in a
+// timestamped "<path>.<time>.bak", of which the newest FileBackupKeep are kept.
+//
+// If state is not nil, the write is aborted with ErrFileChanged when the
+// file was modified since state was recorded by ReadFile. Writing the
+// contents the file already has does nothing.
+func WriteFileAtomic(path string, data []byte, state *FileState) error {
+	if state != nil {
+		changed, err := state.Changed()
+		if err != nil {
+			return err
+		}
+		if changed {
+			return fmt.Errorf("%s: %w", path, ErrFileChanged)
+		}
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	mode := os.FileMode(0644)
+	current, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if bytes.Equal(current, data) {
+			return nil
+		}
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if _, err := backupFile(path, current, mode); err != nil {
+			return err
+		}
+		pruneFileBackups(path, FileBackupKeep)
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return syncDir(dir)
+}
+
+// ReadVDF parses a text VDF file and records its state for WriteVDF. A
+// missing file returns a nil document.
+func ReadVDF(path string) (*vdf.Document, *FileState, error) {
+	data, state, err := ReadFile(path)
+	if err != nil || !state.Exists {
+		return nil, state, err
+	}
+	doc, err := vdf.ParseString(string(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return doc, state, nil
+}
+
+// WriteVDF writes a text VDF document with WriteFileAtomic.
+func WriteVDF(path string, doc *vdf.Document, state *FileState) error {
+	data, err := vdf.WriteString(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	return WriteFileAtomic(path, []byte(data), state)
+}
+
+// backupFile writes data to a new timestamped backup of path and returns its
+// path. A number is appended if a backup with the same time exists, counting
+// up from the newest one so backups always sort in the order they were made.
+func backupFile(path string, data []byte, mode os.FileMode) (string, error) {
+	stamp := time.Now().Format(BackupTimeFormat)
+	backupPath := path + "." + stamp + ".bak"
+	if existing, err := fileBackups(path); err == nil && len(existing) > 0 {
+		if newest := existing[len(existing)-1]; newest.time == stamp {
+			backupPath = fmt.Sprintf("%s.%s-%d.bak", path, stamp, newest.seq+1)
+		}
+	}
+
+	f, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to sync backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to close backup: %w", err)
+	}
+	return backupPath, nil
+}
+
+// fileBackup is a backup WriteFileAtomic made.
+type fileBackup struct {
+	path string
+	// time is the backup's timestamp in BackupTimeFormat.
+	time string
+	// seq orders backups made within the same second.
+	seq int
+}
+
+// fileBackups returns the backups WriteFileAtomic made of path, oldest first.
+func fileBackups(path string) ([]fileBackup, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var backups []fileBackup
+	prefix := filepath.Base(path) + "."
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if stamp, ok = strings.CutSuffix(stamp, ".bak"); !ok {
+			continue
+		}
+		b := fileBackup{path: filepath.Join(filepath.Dir(path), entry.Name()), time: stamp}
+		// "<date>-<time>-<n>" for backups made within the same second
+		if i := strings.LastIndex(stamp, "-"); i > len("20060102") {
+			if seq, err := strconv.Atoi(stamp[i+1:]); err == nil {
+				b.time, b.seq = stamp[:i], seq
+			}
+		}
+		if _, err := time.Parse(BackupTimeFormat, b.time); err != nil {
+			continue
+		}
+		backups = append(backups, b)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].time != backups[j].time {
+			return backups[i].time < backups[j].time
+		}
+		return backups[i].seq < backups[j].seq
+	})
+	return backups, nil
+}
+
+// pruneFileBackups removes all but the newest keep backups of path. Keep 0
+// or less keeps every backup.
+func pruneFileBackups(path string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	backups, err := fileBackups(path)
+	if err != nil {
+		return
+	}
+	for i := 0; i < len(backups)-keep; i++ {
+		_ = os.Remove(backups[i].path)
+	}
+}
+
+// syncDir flushes a directory, making a rename in it durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory: %w", err)
+	}
+	return nil
+}
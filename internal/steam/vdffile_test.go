@@ -0,0 +1,185 @@
+package steam_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/steam"
+)
+
+var _ = Describe("VDF files", func() {
+	var (
+		tmpDir string
+		path   string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "vdffile-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(tmpDir, "config", "config.vdf")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	backups := func() []string {
+		matches, err := filepath.Glob(path + ".*.bak")
+		Expect(err).NotTo(HaveOccurred())
+		return matches
+	}
+
+	Describe("WriteFileAtomic", func() {
+		It("should create a missing file without a backup", func() {
+			Expect(steam.WriteFileAtomic(path, []byte("new"), nil)).To(Succeed())
+
+			Expect(os.ReadFile(path)).To(Equal([]byte("new")))
+			Expect(backups()).To(BeEmpty())
+		})
+
+		It("should keep the previous contents in a timestamped backup", func() {
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte("old"), 0600)).To(Succeed())
+
+			Expect(steam.WriteFileAtomic(path, []byte("new"), nil)).To(Succeed())
+			Expect(steam.WriteFileAtomic(path, []byte("newer"), nil)).To(Succeed())
+
+			Expect(os.ReadFile(path)).To(Equal([]byte("newer")))
+			info, err := os.Stat(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+			Expect(backups()).To(HaveLen(2))
+			var contents []string
+			for _, backup := range backups() {
+				data, err := os.ReadFile(backup)
+				Expect(err).NotTo(HaveOccurred())
+				contents = append(contents, string(data))
+			}
+			Expect(contents).To(ConsistOf("old", "new"))
+		})
+
+		It("should keep only the newest backups", func() {
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte("v0"), 0644)).To(Succeed())
+			// An old backup from a previous run and an unrelated file
+			Expect(os.WriteFile(path+".20200101-000000.bak", []byte("ancient"), 0644)).To(Succeed())
+			Expect(os.WriteFile(path+".notes.bak", []byte("mine"), 0644)).To(Succeed())
+
+			for i := 1; i <= steam.FileBackupKeep+2; i++ {
+				Expect(steam.WriteFileAtomic(path, []byte(fmt.Sprintf("v%d", i)), nil)).To(Succeed())
+			}
+
+			Expect(path + ".20200101-000000.bak").NotTo(BeAnExistingFile())
+			Expect(path + ".notes.bak").To(BeARegularFile())
+			Expect(backups()).To(HaveLen(steam.FileBackupKeep + 1))
+
+			var contents []string
+			for _, backup := range backups() {
+				data, err := os.ReadFile(backup)
+				Expect(err).NotTo(HaveOccurred())
+				contents = append(contents, string(data))
+			}
+			Expect(contents).To(ConsistOf("v2", "v3", "v4", "v5", "v6", "mine"))
+		})
+
+		It("should leave no temporary files behind", func() {
+			Expect(steam.WriteFileAtomic(path, []byte("new"), nil)).To(Succeed())
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("should not rewrite unchanged contents", func() {
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte("same"), 0644)).To(Succeed())
+
+			Expect(steam.WriteFileAtomic(path, []byte("same"), nil)).To(Succeed())
+			Expect(backups()).To(BeEmpty())
+		})
+	})
+
+	Describe("change detection", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte("original"), 0644)).To(Succeed())
+		})
+
+		It("should write a file that wasn't changed since it was read", func() {
+			data, state, err := steam.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(Equal([]byte("original")))
+
+			Expect(steam.WriteFileAtomic(path, []byte("updated"), state)).To(Succeed())
+			Expect(os.ReadFile(path)).To(Equal([]byte("updated")))
+		})
+
+		It("should abort if the file was changed since it was read", func() {
+			_, state, err := steam.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(path, []byte("from Steam"), 0644)).To(Succeed())
+
+			err = steam.WriteFileAtomic(path, []byte("updated"), state)
+			Expect(err).To(MatchError(steam.ErrFileChanged))
+			Expect(os.ReadFile(path)).To(Equal([]byte("from Steam")))
+			Expect(backups()).To(BeEmpty())
+		})
+
+		It("should detect same-size changes by their contents", func() {
+			_, state, err := steam.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(path, []byte("ORIGINAL"), 0644)).To(Succeed())
+			Expect(os.Chtimes(path, state.ModTime, state.ModTime.Add(time.Second))).To(Succeed())
+
+			Expect(steam.WriteFileAtomic(path, []byte("updated"), state)).To(MatchError(steam.ErrFileChanged))
+		})
+
+		It("should ignore a touched but unchanged file", func() {
+			_, state, err := steam.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Chtimes(path, state.ModTime, state.ModTime.Add(time.Second))).To(Succeed())
+
+			Expect(steam.WriteFileAtomic(path, []byte("updated"), state)).To(Succeed())
+		})
+
+		It("should abort if a missing file was created since it was read", func() {
+			_, state, err := steam.ReadFile(filepath.Join(tmpDir, "missing.vdf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.Exists).To(BeFalse())
+			Expect(os.WriteFile(state.Path, []byte("from Steam"), 0644)).To(Succeed())
+
+			Expect(steam.WriteFileAtomic(state.Path, []byte("updated"), state)).To(MatchError(steam.ErrFileChanged))
+		})
+	})
+
+	Describe("ReadVDF", func() {
+		It("should return a nil document for a missing file", func() {
+			doc, state, err := steam.ReadVDF(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(doc).To(BeNil())
+			Expect(state.Exists).To(BeFalse())
+		})
+
+		It("should write back a modified document", func() {
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte("\"Root\"\n{\n\t\"key\"\t\t\"value\"\n}\n"), 0644)).To(Succeed())
+
+			doc, state, err := steam.ReadVDF(path)
+			Expect(err).NotTo(HaveOccurred())
+			doc.Get("Root").Set("key", "changed")
+			Expect(steam.WriteVDF(path, doc, state)).To(Succeed())
+
+			doc, _, err = steam.ReadVDF(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(doc.Get("Root").GetString("key")).To(Equal("changed"))
+			Expect(backups()).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package winereg_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestWinereg(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Winereg Suite")
+}
@@ -0,0 +1,532 @@
+// Package winereg parses and writes Wine registry files (system.reg,
+// user.reg and userdef.reg) without running Wine.
+package winereg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Header is the first line of every Wine registry file.
+const Header = "WINE REGISTRY Version 2"
+
+// ValueType is a Windows registry value type.
+type ValueType uint32
+
+// Registry value types.
+const (
+	TypeNone     ValueType = 0
+	TypeSZ       ValueType = 1
+	TypeExpandSZ ValueType = 2
+	TypeBinary   ValueType = 3
+	TypeDword    ValueType = 4
+	TypeMultiSZ  ValueType = 7
+	TypeQword    ValueType = 11
+)
+
+// File is a parsed Wine registry file.
+type File struct {
+	// Relative is the root the keys are relative to, e.g. `\\Machine` or `\\User\\S-1-5-21-0-0-0-1000`.
+	Relative string
+	// Arch is the prefix architecture from the "#arch=" line (win32 or win64).
+	Arch string
+	// Keys are the keys in file order.
+	Keys []*Key
+}
+
+// Key is a registry key and its values.
+type Key struct {
+	// Name is the unescaped key path, e.g. `Software\Wine\DllOverrides`.
+	Name string
+	// Modified is the last write time stored on the key line.
+	Modified time.Time
+	// Options holds "#" option lines other than #time (e.g. #class, #link) verbatim.
+	Options []string
+	// Values are the key's values in file order.
+	Values []*Value
+
+	time    string // raw #time value
+	rawName string // escaped name as read, used when Name is unchanged
+}
+
+// Value is a registry value.
+type Value struct {
+	// Name is the value name; empty for the default value (@).
+	Name string
+	// Type is the registry value type.
+	Type ValueType
+	// Data is the value data as Windows stores it: UTF-16LE with a
+	// terminating null for string types, little-endian for dwords.
+	Data []byte
+
+	raw     string // original text after "=", used until the value is changed
+	rawName string // escaped name as read, used when Name is unchanged
+}
+
+// ParseFile parses a Wine registry file.
+func ParseFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	reg, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return reg, nil
+}
+
+// Parse parses a Wine registry file.
+func Parse(r io.Reader) (*File, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	lineNum := 0
+	next := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		lineNum++
+		return strings.TrimSuffix(scanner.Text(), "\r"), true
+	}
+
+	header, ok := next()
+	if !ok || header != Header {
+		return nil, fmt.Errorf("not a Wine registry file")
+	}
+
+	file := &File{}
+	var key *Key
+
+	for {
+		line, ok := next()
+		if !ok {
+			break
+		}
+
+		// Values continue onto the next line after a trailing backslash
+		for strings.HasSuffix(line, "\\") && key != nil && !strings.HasPrefix(line, "[") && isContinued(line) {
+			cont, ok := next()
+			if !ok {
+				break
+			}
+			line = line[:len(line)-1] + "\\\n" + cont
+		}
+
+		switch {
+		case line == "":
+			continue
+
+		case strings.HasPrefix(line, ";; All keys relative to "):
+			file.Relative = strings.TrimPrefix(line, ";; All keys relative to ")
+
+		case strings.HasPrefix(line, ";"):
+			continue
+
+		case strings.HasPrefix(line, "#arch=") && key == nil:
+			file.Arch = strings.TrimPrefix(line, "#arch=")
+
+		case strings.HasPrefix(line, "["):
+			k, err := parseKeyLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			key = k
+			file.Keys = append(file.Keys, key)
+
+		case strings.HasPrefix(line, "#"):
+			if key == nil {
+				continue
+			}
+			if strings.HasPrefix(line, "#time=") {
+				key.time = strings.TrimPrefix(line, "#time=")
+			} else {
+				key.Options = append(key.Options, line)
+			}
+
+		default:
+			if key == nil {
+				return nil, fmt.Errorf("line %d: value outside of a key", lineNum)
+			}
+			v, err := parseValueLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			key.Values = append(key.Values, v)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// isContinued reports whether a trailing backslash continues a hex value
+// rather than ending an escaped string.
+func isContinued(line string) bool {
+	eq := valueSeparator(line)
+	if eq == -1 {
+		return false
+	}
+	rest := line[eq+1:]
+	return strings.HasPrefix(rest, "hex")
+}
+
+// parseKeyLine parses "[name] modtime".
+func parseKeyLine(line string) (*Key, error) {
+	end := -1
+	for i := 1; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == ']' {
+			end = i
+			break
+		}
+	}
+	if end == -1 {
+		return nil, fmt.Errorf("unterminated key name")
+	}
+
+	rawName := line[1:end]
+	name, err := unescape(rawName)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{Name: name, rawName: rawName}
+	if rest := strings.TrimSpace(line[end+1:]); rest != "" {
+		secs, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key timestamp %q", rest)
+		}
+		key.Modified = time.Unix(secs, 0)
+	}
+
+	return key, nil
+}
+
+// valueSeparator returns the index of the "=" separating a value's name from its data.
+func valueSeparator(line string) int {
+	if strings.HasPrefix(line, "@=") {
+		return 1
+	}
+	if !strings.HasPrefix(line, "\"") {
+		return -1
+	}
+	for i := 1; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == '"' {
+			if i+1 < len(line) && line[i+1] == '=' {
+				return i + 1
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// parseValueLine parses a `"name"=data` or `@=data` line.
+func parseValueLine(line string) (*Value, error) {
+	eq := valueSeparator(line)
+	if eq == -1 {
+		return nil, fmt.Errorf("invalid value line %q", line)
+	}
+
+	v := &Value{raw: line[eq+1:]}
+	if line[0] == '"' {
+		v.rawName = line[1 : eq-1]
+		name, err := unescape(v.rawName)
+		if err != nil {
+			return nil, err
+		}
+		v.Name = name
+	}
+
+	if err := v.decode(v.raw); err != nil {
+		return nil, fmt.Errorf("value %q: %w", v.Name, err)
+	}
+	return v, nil
+}
+
+// decode parses the data part of a value line.
+func (v *Value) decode(data string) error {
+	switch {
+	case strings.HasPrefix(data, "\""):
+		return v.decodeString(TypeSZ, data)
+
+	case strings.HasPrefix(data, "str("):
+		end := strings.Index(data, "):")
+		if end == -1 {
+			return fmt.Errorf("invalid string type")
+		}
+		t, err := strconv.ParseUint(data[4:end], 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid string type: %w", err)
+		}
+		return v.decodeString(ValueType(t), data[end+2:])
+
+	case strings.HasPrefix(data, "dword:"):
+		n, err := strconv.ParseUint(data[6:], 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid dword: %w", err)
+		}
+		v.Type = TypeDword
+		v.Data = binary.LittleEndian.AppendUint32(nil, uint32(n))
+		return nil
+
+	case strings.HasPrefix(data, "hex:"):
+		v.Type = TypeBinary
+		return v.decodeHex(data[4:])
+
+	case strings.HasPrefix(data, "hex("):
+		end := strings.Index(data, "):")
+		if end == -1 {
+			return fmt.Errorf("invalid hex type")
+		}
+		t, err := strconv.ParseUint(data[4:end], 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid hex type: %w", err)
+		}
+		v.Type = ValueType(t)
+		return v.decodeHex(data[end+2:])
+
+	default:
+		return fmt.Errorf("unknown value format %q", data)
+	}
+}
+
+// decodeString parses a quoted, escaped string value.
+func (v *Value) decodeString(t ValueType, data string) error {
+	if len(data) < 2 || !strings.HasPrefix(data, "\"") || !strings.HasSuffix(data, "\"") {
+		return fmt.Errorf("invalid string %q", data)
+	}
+	s, err := unescape(data[1 : len(data)-1])
+	if err != nil {
+		return err
+	}
+	v.Type = t
+	v.Data = encodeUTF16(s)
+	return nil
+}
+
+// decodeHex parses comma-separated hex bytes, possibly spanning lines.
+func (v *Value) decodeHex(data string) error {
+	data = strings.NewReplacer("\\\n", "", " ", "", "\t", "").Replace(data)
+	v.Data = nil
+	if data == "" {
+		return nil
+	}
+	for _, part := range strings.Split(data, ",") {
+		if part == "" {
+			continue
+		}
+		b, err := strconv.ParseUint(part, 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid hex byte %q", part)
+		}
+		v.Data = append(v.Data, byte(b))
+	}
+	return nil
+}
+
+// String returns the value as a string. String types are decoded from
+// UTF-16 (REG_MULTI_SZ entries are separated by "\x00"), dwords are
+// formatted in decimal, and other types are returned as hex.
+func (v *Value) String() string {
+	switch v.Type {
+	case TypeSZ, TypeExpandSZ, TypeMultiSZ:
+		return strings.TrimRight(decodeUTF16(v.Data), "\x00")
+	case TypeDword:
+		return strconv.FormatUint(uint64(v.Dword()), 10)
+	default:
+		return fmt.Sprintf("%x", v.Data)
+	}
+}
+
+// Dword returns the value as a 32-bit integer.
+func (v *Value) Dword() uint32 {
+	if len(v.Data) < 4 {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(v.Data)
+}
+
+// Strings returns the entries of a REG_MULTI_SZ value.
+func (v *Value) Strings() []string {
+	s := v.String()
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\x00")
+}
+
+// Key returns the key with the given name (case-insensitive), or nil.
+func (f *File) Key(name string) *Key {
+	for _, k := range f.Keys {
+		if strings.EqualFold(k.Name, name) {
+			return k
+		}
+	}
+	return nil
+}
+
+// CreateKey returns the named key, creating it in sorted position if needed.
+func (f *File) CreateKey(name string) *Key {
+	if k := f.Key(name); k != nil {
+		return k
+	}
+
+	k := &Key{Name: name}
+	k.touch()
+
+	i := sort.Search(len(f.Keys), func(i int) bool {
+		return compareKeyNames(f.Keys[i].Name, name) > 0
+	})
+	f.Keys = append(f.Keys, nil)
+	copy(f.Keys[i+1:], f.Keys[i:])
+	f.Keys[i] = k
+
+	return k
+}
+
+// DeleteKey removes the named key and all of its subkeys.
+// Returns true if anything was removed.
+func (f *File) DeleteKey(name string) bool {
+	prefix := strings.ToLower(name) + `\`
+	kept := f.Keys[:0]
+	removed := false
+	for _, k := range f.Keys {
+		lower := strings.ToLower(k.Name)
+		if lower == strings.ToLower(name) || strings.HasPrefix(lower, prefix) {
+			removed = true
+			continue
+		}
+		kept = append(kept, k)
+	}
+	f.Keys = kept
+	return removed
+}
+
+// Subkeys returns the keys directly below parent.
+func (f *File) Subkeys(parent string) []*Key {
+	prefix := strings.ToLower(parent) + `\`
+	var subkeys []*Key
+	for _, k := range f.Keys {
+		lower := strings.ToLower(k.Name)
+		if strings.HasPrefix(lower, prefix) && !strings.Contains(lower[len(prefix):], `\`) {
+			subkeys = append(subkeys, k)
+		}
+	}
+	return subkeys
+}
+
+// Value returns the named value (case-insensitive), or nil.
+func (k *Key) Value(name string) *Value {
+	for _, v := range k.Values {
+		if strings.EqualFold(v.Name, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// SetString sets a REG_SZ value.
+func (k *Key) SetString(name string, value string) {
+	k.set(name, TypeSZ, encodeUTF16(value))
+}
+
+// SetExpandString sets a REG_EXPAND_SZ value.
+func (k *Key) SetExpandString(name string, value string) {
+	k.set(name, TypeExpandSZ, encodeUTF16(value))
+}
+
+// SetDword sets a REG_DWORD value.
+func (k *Key) SetDword(name string, value uint32) {
+	k.set(name, TypeDword, binary.LittleEndian.AppendUint32(nil, value))
+}
+
+// SetBinary sets a value of any type from raw data.
+func (k *Key) SetBinary(name string, t ValueType, data []byte) {
+	k.set(name, t, append([]byte(nil), data...))
+}
+
+// DeleteValue removes the named value. Returns true if it existed.
+func (k *Key) DeleteValue(name string) bool {
+	for i, v := range k.Values {
+		if strings.EqualFold(v.Name, name) {
+			k.Values = append(k.Values[:i], k.Values[i+1:]...)
+			k.touch()
+			return true
+		}
+	}
+	return false
+}
+
+// set adds or replaces a value and updates the key's timestamps.
+func (k *Key) set(name string, t ValueType, data []byte) {
+	k.touch()
+	if v := k.Value(name); v != nil {
+		v.Type = t
+		v.Data = data
+		v.raw = ""
+		return
+	}
+	k.Values = append(k.Values, &Value{Name: name, Type: t, Data: data})
+}
+
+// touch updates the key's modification timestamps to now.
+func (k *Key) touch() {
+	now := time.Now()
+	k.Modified = time.Unix(now.Unix(), 0)
+	// #time is a Windows FILETIME: 100ns intervals since 1601-01-01
+	k.time = strconv.FormatUint(uint64(now.UnixNano()/100)+116444736000000000, 16)
+}
+
+// compareKeyNames orders key paths component by component, case-insensitively,
+// matching the order Wine saves keys in.
+func compareKeyNames(a, b string) int {
+	ap := strings.Split(strings.ToLower(a), `\`)
+	bp := strings.Split(strings.ToLower(b), `\`)
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		if c := strings.Compare(ap[i], bp[i]); c != 0 {
+			return c
+		}
+	}
+	return len(ap) - len(bp)
+}
+
+// encodeUTF16 encodes s as null-terminated UTF-16LE.
+func encodeUTF16(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	data := make([]byte, 0, len(units)*2+2)
+	for _, u := range units {
+		data = binary.LittleEndian.AppendUint16(data, u)
+	}
+	return append(data, 0, 0)
+}
+
+// decodeUTF16 decodes UTF-16LE data, keeping embedded nulls.
+func decodeUTF16(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, binary.LittleEndian.Uint16(data[i:]))
+	}
+	return string(utf16.Decode(units))
+}
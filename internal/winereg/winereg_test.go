@@ -0,0 +1,150 @@
+package winereg_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/winereg"
+)
+
+// sampleRegistry mirrors the layout Wine writes, including escapes,
+// typed strings and wrapped hex values.
+const sampleRegistry = `WINE REGISTRY Version 2
+;; All keys relative to \\Machine
+
+#arch=win64
+
+[Software\\Microsoft\\Windows NT\\CurrentVersion] 1700000000
+#time=1da1a2b3c4d5e6f
+"CurrentVersion"="6.3"
+"ProductName"="Microsoft Windows 10"
+"SystemRoot"=str(2):"C:\\windows"
+@="default"
+
+[Software\\Wine\\Test] 1700000001
+#time=1da1a2b3c4d5e70
+#class="Test"
+"Binary"=hex:00,01,02,03,04,05,06,07,08,09,0a,0b,0c,0d,0e,0f,10,11,12,13,14,15,\
+  16,17,18,19
+"Dword"=dword:0000002a
+"Multi"=str(7):"one\0two"
+"Path \"quoted\""="tab\there"
+"Unicode"="caf\xe9"
+"Qword"=hex(b):01,00,00,00,00,00,00,00
+`
+
+var _ = Describe("Winereg", func() {
+	var reg *winereg.File
+
+	BeforeEach(func() {
+		var err error
+		reg, err = winereg.Parse(strings.NewReader(sampleRegistry))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("Parse", func() {
+		It("should read the header lines", func() {
+			Expect(reg.Relative).To(Equal(`\\Machine`))
+			Expect(reg.Arch).To(Equal("win64"))
+			Expect(reg.Keys).To(HaveLen(2))
+		})
+
+		It("should unescape key names and read timestamps", func() {
+			key := reg.Keys[0]
+			Expect(key.Name).To(Equal(`Software\Microsoft\Windows NT\CurrentVersion`))
+			Expect(key.Modified).To(Equal(time.Unix(1700000000, 0)))
+			Expect(reg.Keys[1].Options).To(Equal([]string{`#class="Test"`}))
+		})
+
+		It("should decode string values", func() {
+			key := reg.Key(`software\microsoft\windows nt\currentversion`)
+			Expect(key).NotTo(BeNil())
+			Expect(key.Value("ProductName").String()).To(Equal("Microsoft Windows 10"))
+			Expect(key.Value("SystemRoot").Type).To(Equal(winereg.TypeExpandSZ))
+			Expect(key.Value("SystemRoot").String()).To(Equal(`C:\windows`))
+			Expect(key.Value("").String()).To(Equal("default"))
+		})
+
+		It("should decode escapes", func() {
+			key := reg.Key(`Software\Wine\Test`)
+			Expect(key.Value(`Path "quoted"`).String()).To(Equal("tab\there"))
+			Expect(key.Value("Unicode").String()).To(Equal("café"))
+		})
+
+		It("should decode typed values", func() {
+			key := reg.Key(`Software\Wine\Test`)
+			Expect(key.Value("Dword").Dword()).To(Equal(uint32(42)))
+			Expect(key.Value("Multi").Strings()).To(Equal([]string{"one", "two"}))
+			Expect(key.Value("Binary").Data).To(HaveLen(26))
+			Expect(key.Value("Binary").Data[25]).To(Equal(byte(0x19)))
+			Expect(key.Value("Qword").Type).To(Equal(winereg.TypeQword))
+		})
+
+		It("should reject files without the header", func() {
+			_, err := winereg.Parse(strings.NewReader("REGEDIT4\n"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject invalid values", func() {
+			_, err := winereg.Parse(strings.NewReader(winereg.Header + "\n\n[Key] 0\n\"Bad\"=dword:zz\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Keys", func() {
+		It("should insert new keys in Wine's order", func() {
+			reg.CreateKey(`Software\Wine\DllOverrides`)
+			reg.CreateKey(`Software\Microsoft`)
+
+			var names []string
+			for _, k := range reg.Keys {
+				names = append(names, k.Name)
+			}
+			Expect(names).To(Equal([]string{
+				`Software\Microsoft`,
+				`Software\Microsoft\Windows NT\CurrentVersion`,
+				`Software\Wine\DllOverrides`,
+				`Software\Wine\Test`,
+			}))
+		})
+
+		It("should return existing keys from CreateKey", func() {
+			Expect(reg.CreateKey(`SOFTWARE\Wine\Test`)).To(BeIdenticalTo(reg.Keys[1]))
+		})
+
+		It("should delete keys with their subkeys", func() {
+			Expect(reg.DeleteKey(`Software\Microsoft`)).To(BeTrue())
+			Expect(reg.Keys).To(HaveLen(1))
+			Expect(reg.DeleteKey(`Software\Missing`)).To(BeFalse())
+		})
+
+		It("should list direct subkeys", func() {
+			reg.CreateKey(`Software\Wine\Test\Nested`)
+			subkeys := reg.Subkeys(`Software\Wine`)
+			Expect(subkeys).To(HaveLen(1))
+			Expect(subkeys[0].Name).To(Equal(`Software\Wine\Test`))
+		})
+	})
+
+	Describe("Values", func() {
+		It("should set values and update the key timestamp", func() {
+			key := reg.Key(`Software\Wine\Test`)
+			key.SetString("Version", "win10")
+			key.SetDword("Dword", 7)
+
+			Expect(key.Value("Version").String()).To(Equal("win10"))
+			Expect(key.Value("Dword").Dword()).To(Equal(uint32(7)))
+			Expect(key.Modified).To(BeTemporally("~", time.Now(), 2*time.Second))
+		})
+
+		It("should delete values", func() {
+			key := reg.Key(`Software\Wine\Test`)
+			Expect(key.DeleteValue("dword")).To(BeTrue())
+			Expect(key.Value("Dword")).To(BeNil())
+			Expect(key.DeleteValue("Dword")).To(BeFalse())
+		})
+	})
+})
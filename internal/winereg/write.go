@@ -0,0 +1,276 @@
+package winereg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// NewFile creates an empty registry file with keys relative to relative.
+func NewFile(relative string, arch string) *File {
+	return &File{Relative: relative, Arch: arch}
+}
+
+// WriteFile writes the registry file atomically, keeping the existing file's
+// permissions.
+func (f *File) WriteFile(path string) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmpPath := path + ".tmp"
+	out, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create registry file: %w", err)
+	}
+
+	if err := f.Write(out); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write registry file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// Write writes the registry file in Wine's format.
+func (f *File) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, Header)
+	if f.Relative != "" {
+		fmt.Fprintf(bw, ";; All keys relative to %s\n", f.Relative)
+	}
+	if f.Arch != "" {
+		fmt.Fprintf(bw, "\n#arch=%s\n", f.Arch)
+	}
+
+	for _, k := range f.Keys {
+		name := k.rawName
+		if name == "" || mustUnescape(name) != k.Name {
+			name = escape(k.Name, "[]")
+		}
+		fmt.Fprintf(bw, "\n[%s] %d\n", name, k.Modified.Unix())
+		if k.time != "" {
+			fmt.Fprintf(bw, "#time=%s\n", k.time)
+		}
+		for _, opt := range k.Options {
+			fmt.Fprintln(bw, opt)
+		}
+		for _, v := range k.Values {
+			fmt.Fprintln(bw, v.line())
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write registry file: %w", err)
+	}
+	return nil
+}
+
+// line formats the value as it appears in a registry file.
+func (v *Value) line() string {
+	name := "@"
+	if v.Name != "" {
+		raw := v.rawName
+		if raw == "" || mustUnescape(raw) != v.Name {
+			raw = escape(v.Name, `""`)
+		}
+		name = `"` + raw + `"`
+	}
+	if v.raw != "" {
+		return name + "=" + v.raw
+	}
+	return name + "=" + v.encode(len(name)+1)
+}
+
+// encode formats the value data the way Wine does. column is the number of
+// characters already written on the line, used to wrap long hex values.
+func (v *Value) encode(column int) string {
+	switch v.Type {
+	case TypeSZ, TypeExpandSZ, TypeMultiSZ:
+		if len(v.Data) >= 2 && len(v.Data)%2 == 0 && v.Data[len(v.Data)-2] == 0 && v.Data[len(v.Data)-1] == 0 {
+			s := `"` + escape(decodeUTF16(v.Data[:len(v.Data)-2]), `""`) + `"`
+			if v.Type == TypeSZ {
+				return s
+			}
+			return fmt.Sprintf("str(%x):%s", uint32(v.Type), s)
+		}
+	case TypeDword:
+		if len(v.Data) == 4 {
+			return fmt.Sprintf("dword:%08x", v.Dword())
+		}
+	}
+
+	var sb strings.Builder
+	if v.Type == TypeBinary {
+		sb.WriteString("hex:")
+	} else {
+		fmt.Fprintf(&sb, "hex(%x):", uint32(v.Type))
+	}
+
+	count := column + sb.Len()
+	for i, b := range v.Data {
+		fmt.Fprintf(&sb, "%02x", b)
+		count += 2
+		if i < len(v.Data)-1 {
+			sb.WriteByte(',')
+			count++
+			if count > 76 {
+				sb.WriteString("\\\n  ")
+				count = 2
+			}
+		}
+	}
+	return sb.String()
+}
+
+// controlEscapes maps control characters to their C escape letters, as Wine
+// writes them. Other control characters are written in octal.
+var controlEscapes = map[uint16]byte{
+	0x07: 'a', 0x08: 'b', 0x09: 't', 0x0a: 'n', 0x0b: 'v', 0x0c: 'f', 0x0d: 'r', 0x1b: 'e',
+}
+
+// escape escapes a string for a registry file the way Wine does. delims are
+// the delimiter characters that must be backslash-escaped ("[]" for key
+// names, `""` for value names and strings).
+func escape(s string, delims string) string {
+	units := utf16.Encode([]rune(s))
+
+	var sb strings.Builder
+	for i, u := range units {
+		next := uint16(0xFFFF)
+		if i+1 < len(units) {
+			next = units[i+1]
+		}
+
+		switch {
+		case u > 127:
+			// Use the fixed-width form if a hex digit follows, so it isn't
+			// read as part of the escape.
+			if isHexDigit(next) {
+				fmt.Fprintf(&sb, `\x%04x`, u)
+			} else {
+				fmt.Fprintf(&sb, `\x%x`, u)
+			}
+		case u < 32:
+			if c, ok := controlEscapes[u]; ok {
+				sb.WriteByte('\\')
+				sb.WriteByte(c)
+			} else if next >= '0' && next <= '7' {
+				fmt.Fprintf(&sb, `\%03o`, u)
+			} else {
+				fmt.Fprintf(&sb, `\%o`, u)
+			}
+		case u == '\\' || strings.ContainsRune(delims, rune(u)):
+			sb.WriteByte('\\')
+			sb.WriteByte(byte(u))
+		default:
+			sb.WriteByte(byte(u))
+		}
+	}
+	return sb.String()
+}
+
+// unescape decodes the escapes Wine uses in registry strings.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var units []uint16
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r != '\\' {
+			units = append(units, utf16.Encode([]rune{r})...)
+			continue
+		}
+
+		i++
+		if i >= len(runes) {
+			return "", fmt.Errorf("trailing backslash in %q", s)
+		}
+
+		switch c := runes[i]; c {
+		case 'a':
+			units = append(units, '\a')
+		case 'b':
+			units = append(units, '\b')
+		case 'e':
+			units = append(units, 0x1b)
+		case 'f':
+			units = append(units, '\f')
+		case 'n':
+			units = append(units, '\n')
+		case 'r':
+			units = append(units, '\r')
+		case 't':
+			units = append(units, '\t')
+		case 'v':
+			units = append(units, '\v')
+		case 'x':
+			var u uint16
+			n := 0
+			for n < 4 && i+1 < len(runes) && runes[i+1] < 128 && isHexDigit(uint16(runes[i+1])) {
+				i++
+				u = u<<4 | hexValue(runes[i])
+				n++
+			}
+			if n == 0 {
+				return "", fmt.Errorf("invalid \\x escape in %q", s)
+			}
+			units = append(units, u)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			u := uint16(c - '0')
+			for n := 1; n < 3 && i+1 < len(runes) && runes[i+1] >= '0' && runes[i+1] <= '7'; n++ {
+				i++
+				u = u<<3 | uint16(runes[i]-'0')
+			}
+			units = append(units, u)
+		default:
+			units = append(units, utf16.Encode([]rune{c})...)
+		}
+	}
+
+	return string(utf16.Decode(units)), nil
+}
+
+// mustUnescape unescapes s, returning "" on error.
+func mustUnescape(s string) string {
+	u, err := unescape(s)
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
+// isHexDigit reports whether u is an ASCII hex digit.
+func isHexDigit(u uint16) bool {
+	return (u >= '0' && u <= '9') || (u >= 'a' && u <= 'f') || (u >= 'A' && u <= 'F')
+}
+
+// hexValue returns the value of an ASCII hex digit.
+func hexValue(r rune) uint16 {
+	switch {
+	case r >= '0' && r <= '9':
+		return uint16(r - '0')
+	case r >= 'a' && r <= 'f':
+		return uint16(r - 'a' + 10)
+	default:
+		return uint16(r - 'A' + 10)
+	}
+}
@@ -0,0 +1,121 @@
+package winereg_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/jslay88/zladxhd-installer/internal/winereg"
+)
+
+var _ = Describe("Write", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "winereg-test-*")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	// roundTrip writes reg and parses the output again.
+	roundTrip := func(reg *winereg.File) (string, *winereg.File) {
+		var buf bytes.Buffer
+		Expect(reg.Write(&buf)).To(Succeed())
+		parsed, err := winereg.Parse(strings.NewReader(buf.String()))
+		Expect(err).NotTo(HaveOccurred())
+		return buf.String(), parsed
+	}
+
+	It("should write an unmodified file byte for byte", func() {
+		reg, err := winereg.Parse(strings.NewReader(sampleRegistry))
+		Expect(err).NotTo(HaveOccurred())
+
+		out, _ := roundTrip(reg)
+		Expect(out).To(Equal(sampleRegistry))
+	})
+
+	It("should only rewrite changed values", func() {
+		reg, err := winereg.Parse(strings.NewReader(sampleRegistry))
+		Expect(err).NotTo(HaveOccurred())
+		reg.Key(`Software\Wine\Test`).SetDword("Dword", 255)
+
+		out, _ := roundTrip(reg)
+		Expect(out).To(ContainSubstring(`"Dword"=dword:000000ff`))
+		Expect(out).To(ContainSubstring(`"Unicode"="caf\xe9"`))
+		Expect(out).To(ContainSubstring(`[Software\\Microsoft\\Windows NT\\CurrentVersion] 1700000000`))
+	})
+
+	It("should escape strings the way Wine does", func() {
+		reg := winereg.NewFile(`\\User\\S-1-5-21-0-0-0-1000`, "win64")
+		key := reg.CreateKey(`Software\Wine\Escapes`)
+		key.SetString(`quote"name`, "back\\slash")
+		key.SetString("Control", "a\x01b\x017\n")
+		key.SetString("Unicode", "é1ü")
+		key.SetString("", "default")
+
+		out, parsed := roundTrip(reg)
+		Expect(out).To(ContainSubstring(`[Software\\Wine\\Escapes] `))
+		Expect(out).To(ContainSubstring(`"quote\"name"="back\\slash"`))
+		Expect(out).To(ContainSubstring(`"Control"="a\1b\0017\n"`))
+		Expect(out).To(ContainSubstring(`"Unicode"="\x00e91\xfc"`))
+		Expect(out).To(ContainSubstring(`@="default"`))
+
+		parsedKey := parsed.Key(`Software\Wine\Escapes`)
+		Expect(parsedKey.Value(`quote"name`).String()).To(Equal("back\\slash"))
+		Expect(parsedKey.Value("Control").String()).To(Equal("a\x01b\x017\n"))
+		Expect(parsedKey.Value("Unicode").String()).To(Equal("é1ü"))
+	})
+
+	It("should wrap long hex values", func() {
+		data := make([]byte, 64)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		reg := winereg.NewFile(`\\Machine`, "")
+		reg.CreateKey(`Software\Wine`).SetBinary("Blob", winereg.TypeBinary, data)
+
+		out, parsed := roundTrip(reg)
+		for _, line := range strings.Split(out, "\n") {
+			Expect(len(line)).To(BeNumerically("<=", 80))
+		}
+		Expect(out).To(ContainSubstring(",\\\n  "))
+		Expect(parsed.Key(`Software\Wine`).Value("Blob").Data).To(Equal(data))
+	})
+
+	It("should write typed strings", func() {
+		reg := winereg.NewFile(`\\Machine`, "")
+		key := reg.CreateKey(`Environment`)
+		key.SetExpandString("TEMP", `%USERPROFILE%\Temp`)
+
+		out, parsed := roundTrip(reg)
+		Expect(out).To(ContainSubstring(`"TEMP"=str(2):"%USERPROFILE%\\Temp"`))
+		Expect(parsed.Key("Environment").Value("TEMP").Type).To(Equal(winereg.TypeExpandSZ))
+	})
+
+	It("should replace files and keep their permissions", func() {
+		path := filepath.Join(tmpDir, "user.reg")
+		Expect(os.WriteFile(path, []byte(sampleRegistry), 0600)).To(Succeed())
+
+		reg, err := winereg.ParseFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		reg.CreateKey(`Software\Wine`).SetString("Version", "win10")
+		Expect(reg.WriteFile(path)).To(Succeed())
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		Expect(filepath.Join(tmpDir, "user.reg.tmp")).NotTo(BeAnExistingFile())
+
+		reread, err := winereg.ParseFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reread.Key(`Software\Wine`).Value("Version").String()).To(Equal("win10"))
+	})
+})